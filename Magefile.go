@@ -4,9 +4,30 @@
 package main
 
 import (
+	"os/exec"
+	"strings"
+	"time"
+
 	// mage:import
 	build "github.com/grafana/grafana-plugin-sdk-go/build"
 )
 
+// versionPackage holds the build details reported by the /version resource.
+const versionPackage = "github.com/achan/grafana-jira-datasource/pkg/plugin"
+
+func init() {
+	// Stamp the git commit and build date into the backend, see pkg/plugin/version.go.
+	_ = build.SetBeforeBuildCallback(func(cfg build.Config) (build.Config, error) {
+		if cfg.CustomVars == nil {
+			cfg.CustomVars = map[string]string{}
+		}
+		if out, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
+			cfg.CustomVars[versionPackage+".gitCommit"] = strings.TrimSpace(string(out))
+		}
+		cfg.CustomVars[versionPackage+".buildDate"] = time.Now().UTC().Format(time.RFC3339)
+		return cfg, nil
+	})
+}
+
 // Default configures the default target.
 var Default = build.BuildAll
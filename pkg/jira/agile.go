@@ -0,0 +1,54 @@
+package jira
+
+import (
+	"context"
+	"strconv"
+)
+
+// Sprint is a sprint as returned by the Jira Software agile API. Dates are
+// ISO 8601 timestamps and empty until the sprint was started or completed.
+type Sprint struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	StartDate     string `json:"startDate,omitempty"`
+	EndDate       string `json:"endDate,omitempty"`
+	CompleteDate  string `json:"completeDate,omitempty"`
+	OriginBoardID int    `json:"originBoardId,omitempty"`
+}
+
+// GetSprint returns the sprint with the given id.
+func (c *Client) GetSprint(ctx context.Context, id int) (*Sprint, error) {
+	var sprint Sprint
+	if err := c.getJSON(ctx, "/rest/agile/1.0/sprint/"+strconv.Itoa(id), nil, &sprint); err != nil {
+		return nil, err
+	}
+	return &sprint, nil
+}
+
+// BoardColumn is a column of a board with the statuses mapped to it.
+type BoardColumn struct {
+	Name     string        `json:"name"`
+	Statuses []BoardStatus `json:"statuses"`
+}
+
+// BoardStatus is a status mapped to a board column, known only by its id.
+type BoardStatus struct {
+	ID string `json:"id"`
+}
+
+type boardConfiguration struct {
+	ColumnConfig struct {
+		Columns []BoardColumn `json:"columns"`
+	} `json:"columnConfig"`
+}
+
+// GetBoardColumns returns the columns of the board with the given id from left
+// to right.
+func (c *Client) GetBoardColumns(ctx context.Context, id int) ([]BoardColumn, error) {
+	var config boardConfiguration
+	if err := c.getJSON(ctx, "/rest/agile/1.0/board/"+strconv.Itoa(id)+"/configuration", nil, &config); err != nil {
+		return nil, err
+	}
+	return config.ColumnConfig.Columns, nil
+}
@@ -0,0 +1,113 @@
+package jira
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive failures that opens the circuit.
+	DefaultFailureThreshold = 5
+	// DefaultCooldown is how long an open circuit fails fast before probing Jira again.
+	DefaultCooldown = 45 * time.Second
+)
+
+// Circuit breaker states.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// CircuitOpenError is returned without contacting Jira while the circuit is open.
+type CircuitOpenError struct {
+	RetryIn time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.RetryIn <= 0 {
+		return "Jira temporarily marked unavailable (circuit half-open, probing)"
+	}
+	return fmt.Sprintf("Jira temporarily marked unavailable (circuit open, retrying in %s)", e.RetryIn.Round(time.Second))
+}
+
+// CircuitBreaker stops requests to a Jira instance that keeps failing, so that
+// refreshing dashboards don't add to an outage. After threshold consecutive
+// failures the circuit opens and requests fail fast for the cooldown. Then a
+// single probe request is let through (half-open): its success closes the
+// circuit again, its failure reopens it.
+//
+// A CircuitBreaker is meant to be shared by all clients of a datasource instance.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openUntil time.Time
+}
+
+// NewCircuitBreaker creates a closed circuit breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, state: CircuitClosed}
+}
+
+// allow returns a *CircuitOpenError if a request must not be sent.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case CircuitOpen:
+		if wait := time.Until(b.openUntil); wait > 0 {
+			return &CircuitOpenError{RetryIn: wait}
+		}
+		b.state = CircuitHalfOpen
+		return nil
+	case CircuitHalfOpen:
+		// A probe is already on its way.
+		return &CircuitOpenError{}
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a request.
+func (b *CircuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// CircuitState is a snapshot of a circuit breaker.
+type CircuitState struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	// RetryInSeconds is how long an open circuit keeps failing fast.
+	RetryInSeconds int `json:"retryInSeconds,omitempty"`
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := CircuitState{State: b.state, ConsecutiveFailures: b.failures}
+	if b.state == CircuitOpen {
+		if wait := time.Until(b.openUntil); wait > 0 {
+			state.RetryInSeconds = int(wait.Round(time.Second) / time.Second)
+		}
+	}
+	return state
+}
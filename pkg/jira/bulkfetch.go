@@ -0,0 +1,76 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxBulkFetchKeys is the number of issues Jira returns per bulk fetch request.
+const maxBulkFetchKeys = 100
+
+type bulkFetchRequest struct {
+	IssueIdsOrKeys []string `json:"issueIdsOrKeys"`
+	Fields         []string `json:"fields"`
+	Expand         []string `json:"expand,omitempty"`
+}
+
+type bulkFetchResponse struct {
+	Issues      []Issue      `json:"issues"`
+	IssueErrors []IssueError `json:"issueErrors"`
+}
+
+// IssueError explains why a single issue of a bulk fetch was not returned, e.g.
+// because it doesn't exist or the user can't see it.
+type IssueError struct {
+	ID           string `json:"id"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// FetchIssues fetches the issues with the given keys, 100 per request. Issues
+// Jira doesn't return are reported as IssueErrors instead of failing the fetch.
+func (c *Client) FetchIssues(ctx context.Context, keys []string, opts SearchOptions) ([]Issue, []IssueError, error) {
+	ctx, span := tracer().Start(ctx, "jira.bulkfetch", trace.WithAttributes(attribute.Int("keys", len(keys))))
+	defer span.End()
+
+	issues := []Issue{}
+	var issueErrors []IssueError
+	for start := 0; start < len(keys); start += maxBulkFetchKeys {
+		end := start + maxBulkFetchKeys
+		if end > len(keys) {
+			end = len(keys)
+		}
+		reqBody := bulkFetchRequest{
+			IssueIdsOrKeys: keys[start:end],
+			Fields:         opts.fields(),
+			Expand:         opts.expandList(),
+		}
+
+		resp, err := c.doRequest(ctx, "POST", "/rest/api/3/issue/bulkfetch", nil, reqBody)
+		if err != nil {
+			SpanError(span, err)
+			return nil, nil, err
+		}
+		var result bulkFetchResponse
+		if resp.StatusCode != http.StatusOK {
+			err = &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&result)
+		}
+		resp.Body.Close()
+		if err != nil {
+			SpanError(span, err)
+			return nil, nil, err
+		}
+
+		opts.pruneChangelogs(result.Issues)
+		issues = append(issues, result.Issues...)
+		issueErrors = append(issueErrors, result.IssueErrors...)
+	}
+
+	span.SetAttributes(attribute.Int("issues", len(issues)), attribute.Int("issue_errors", len(issueErrors)))
+	return issues, issueErrors, nil
+}
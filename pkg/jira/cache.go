@@ -0,0 +1,152 @@
+package jira
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// maxCacheEntries bounds the memory a cache can use.
+	maxCacheEntries = 500
+	// etagRetention is how many TTLs an entry with an ETag is kept for revalidation.
+	etagRetention = 10
+)
+
+// Cache stores Jira responses per request signature. Entries are served directly
+// while younger than the TTL. After that, entries that came with an ETag are
+// revalidated with If-None-Match, and a 304 reuses the stored payload; entries
+// without an ETag are simply fetched again.
+//
+// A Cache is meant to be shared by all clients of a datasource instance.
+type Cache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	body     []byte
+	etag     string
+	storedAt time.Time
+}
+
+// NewCache creates a cache whose entries are fresh for ttl.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{ttl: ttl, entries: map[string]*cacheEntry{}}
+}
+
+func (c *Cache) get(key string) (entry *cacheEntry, fresh bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return entry, time.Since(entry.storedAt) < c.ttl
+}
+
+func (c *Cache) set(key string, entry *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCacheEntries {
+		c.evictLocked()
+	}
+	c.entries[key] = entry
+}
+
+// touch marks an entry as fresh again after a successful revalidation.
+func (c *Cache) touch(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.entries[key]; ok {
+		entry.storedAt = time.Now()
+	}
+}
+
+// evictLocked drops entries that can't be served or revalidated anymore, and the
+// oldest entry if that didn't free any space.
+func (c *Cache) evictLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, entry := range c.entries {
+		age := time.Since(entry.storedAt)
+		if (entry.etag == "" && age >= c.ttl) || age >= etagRetention*c.ttl {
+			delete(c.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.storedAt.Before(oldest) {
+			oldestKey, oldest = key, entry.storedAt
+		}
+	}
+	if len(c.entries) >= maxCacheEntries && oldestKey != "" {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// Len returns the number of cached responses.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Clear drops all cached responses.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[string]*cacheEntry{}
+}
+
+// CacheStats counts how requests of a client were answered.
+type CacheStats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	NotModified int64 `json:"notModified"`
+}
+
+// Sub returns the stats accumulated since before.
+func (s CacheStats) Sub(before CacheStats) CacheStats {
+	return CacheStats{
+		Hits:        s.Hits - before.Hits,
+		Misses:      s.Misses - before.Misses,
+		NotModified: s.NotModified - before.NotModified,
+	}
+}
+
+type cacheCounters struct {
+	hits, misses, notModified atomic.Int64
+}
+
+func (c *cacheCounters) snapshot() CacheStats {
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), NotModified: c.notModified.Load()}
+}
+
+// cacheKey identifies a request by everything that influences its response.
+func cacheKey(method, reqURL string, body []byte, authHeader string) string {
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(method), []byte(reqURL), body, []byte(authHeader)} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedResponse replays a stored payload as a 200 response.
+func cachedResponse(entry *cacheEntry) *http.Response {
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(entry.body)),
+		ContentLength: int64(len(entry.body)),
+	}
+}
@@ -0,0 +1,106 @@
+package jira
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// changelogPageSize is the number of histories requested per changelog page,
+// the most Jira returns.
+const changelogPageSize = 100
+
+// Truncated reports whether Jira left histories out of the changelog, as
+// searches do beyond the first 40 to 100 histories of an issue.
+func (c *Changelog) Truncated() bool {
+	returned := c.MaxResults
+	if len(c.Histories) > returned {
+		returned = len(c.Histories)
+	}
+	return c.Total > c.StartAt+returned
+}
+
+type changelogPage struct {
+	StartAt    int       `json:"startAt"`
+	MaxResults int       `json:"maxResults"`
+	Total      int       `json:"total"`
+	IsLast     bool      `json:"isLast"`
+	Values     []History `json:"values"`
+}
+
+// BackfillStats reports how many truncated changelogs were completed and how
+// many requests that took.
+type BackfillStats struct {
+	Issues   int `json:"issues"`
+	Requests int `json:"requests"`
+}
+
+// BackfillChangelogs replaces the truncated changelogs of the issues with their
+// full changelogs from the changelog endpoint of every issue, pruned like the
+// search with opts. An error ends the back-fill; the issues completed so far
+// keep their full changelogs and are counted in the stats. Like searches, the
+// back-fill stops with a *PaginationError when the search deadline of ctx is
+// reached between issues, see WithSearchDeadline.
+func (c *Client) BackfillChangelogs(ctx context.Context, issues []Issue, opts SearchOptions) (BackfillStats, error) {
+	ctx, span := tracer().Start(ctx, "jira.changelog.backfill")
+	defer span.End()
+
+	var stats BackfillStats
+	defer func() {
+		span.SetAttributes(attribute.Int("issues", stats.Issues), attribute.Int("requests", stats.Requests))
+	}()
+	for i := range issues {
+		if issues[i].Changelog == nil || !issues[i].Changelog.Truncated() {
+			continue
+		}
+		if stats.Requests > 0 && SearchDeadlineReached(ctx) {
+			err := &PaginationError{Deadline: true}
+			SpanError(span, err)
+			return stats, err
+		}
+		histories, requests, err := c.issueChangelog(ctx, issues[i].Key)
+		stats.Requests += requests
+		if err != nil {
+			SpanError(span, err)
+			return stats, err
+		}
+		issues[i].Changelog = &Changelog{MaxResults: len(histories), Total: len(histories), Histories: histories}
+		opts.pruneChangelogs(issues[i : i+1])
+		stats.Issues++
+	}
+	return stats, nil
+}
+
+// issueChangelog fetches every history of the issue, page by page up to the
+// page limit of searches, and returns them with the number of requests made.
+func (c *Client) issueChangelog(ctx context.Context, key string) ([]History, int, error) {
+	ctx, span := tracer().Start(ctx, "jira.changelog", trace.WithAttributes(attribute.String("issue", key)))
+	defer span.End()
+
+	maxPages := c.maxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+	var histories []History
+	requests := 0
+	for requests < maxPages {
+		params := url.Values{}
+		params.Set("startAt", strconv.Itoa(len(histories)))
+		params.Set("maxResults", strconv.Itoa(changelogPageSize))
+
+		var page changelogPage
+		requests++
+		if err := c.getJSON(ctx, "/rest/api/3/issue/"+url.PathEscape(key)+"/changelog", params, &page); err != nil {
+			SpanError(span, err)
+			return nil, requests, err
+		}
+		histories = append(histories, page.Values...)
+		if page.IsLast || len(page.Values) == 0 || len(histories) >= page.Total {
+			break
+		}
+	}
+	return histories, requests, nil
+}
@@ -1,34 +1,131 @@
 package jira
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrHTMLResponse is returned for successful responses with an HTML page instead
+// of JSON, typically the login page of an SSO proxy in front of Jira.
+var ErrHTMLResponse = errors.New("Jira returned HTML instead of JSON, the URL may point at a login page or the proxy requires interactive SSO")
+
+// isHTML reports whether the Content-Type header is that of an HTML page.
+func isHTML(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
 type Client struct {
 	httpClient *http.Client
 	baseURL    string
 	authHeader string
+	userAgent  string
+	language   string
+	headers    map[string]string
+	cache      *Cache
+	cacheStats cacheCounters
+	breaker    *CircuitBreaker
+	maxPages   int
+	rateLimit  rateLimitRecorder
+	redirects  redirectRecorder
+}
+
+// DefaultMaxPages is the number of pages after which a search is aborted.
+const DefaultMaxPages = 200
+
+// PaginationError aborts a search that doesn't come to an end, either because
+// Jira handed out a page token twice or because the page limit was reached. The
+// issues collected until then are returned along with it.
+type PaginationError struct {
+	Pages int
+	// RepeatedToken is set when Jira returned a page token that was already used.
+	RepeatedToken string
+	// Deadline is set when the search stopped to be done before its deadline,
+	// see WithSearchDeadline.
+	Deadline bool
+}
+
+func (e *PaginationError) Error() string {
+	if e.Deadline {
+		return "search stopped early to answer before the query times out"
+	}
+	if e.RepeatedToken != "" {
+		return fmt.Sprintf("search aborted after %d pages: Jira returned the page token %q a second time", e.Pages, e.RepeatedToken)
+	}
+	return fmt.Sprintf("search aborted after reaching the limit of %d pages", e.Pages)
 }
 
-func NewClient(baseURL, username, token string) *Client {
+// NewClient creates a client for the Jira instance at baseURL. userAgent is sent
+// with every request so that Jira admins can attribute the plugin's traffic.
+func NewClient(baseURL, username, token, userAgent string) *Client {
 	baseURL = strings.TrimRight(baseURL, "/")
 	
 	auth := username + ":" + token
 	encodedAuth := base64.StdEncoding.EncodeToString([]byte(auth))
 	
-	return &Client{
-		httpClient: &http.Client{},
+	c := &Client{
 		baseURL:    baseURL,
 		authHeader: "Basic " + encodedAuth,
+		userAgent:  userAgent,
 	}
+	c.SetHTTPClient(&http.Client{})
+	return c
 }
 
-func (c *Client) doRequest(method, path string, params url.Values, body interface{}) (*http.Response, error) {
+// SetCustomHeaders configures extra headers sent with every request.
+func (c *Client) SetCustomHeaders(headers map[string]string) {
+	c.headers = headers
+}
+
+// SetLanguage sets the Accept-Language header of every request, e.g. "en" for
+// English status names on a localized instance. Custom headers override it.
+func (c *Client) SetLanguage(language string) {
+	c.language = language
+}
+
+// SetHTTPClient makes the client send its requests through httpClient, e.g. to
+// share connections between clients. The client uses a copy of it with its own
+// redirect policy, see checkRedirect.
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	client := *httpClient
+	client.CheckRedirect = c.checkRedirect
+	c.httpClient = &client
+}
+
+// SetMaxPages limits how many pages a search fetches, 0 uses DefaultMaxPages.
+func (c *Client) SetMaxPages(maxPages int) {
+	c.maxPages = maxPages
+}
+
+// SetCache makes the client serve repeated requests from cache.
+func (c *Client) SetCache(cache *Cache) {
+	c.cache = cache
+}
+
+// SetCircuitBreaker makes the client fail fast while the breaker is open.
+func (c *Client) SetCircuitBreaker(breaker *CircuitBreaker) {
+	c.breaker = breaker
+}
+
+// CacheStats returns how the requests of this client were answered so far.
+func (c *Client) CacheStats() CacheStats {
+	return c.cacheStats.snapshot()
+}
+
+func (c *Client) doRequest(ctx context.Context, method, path string, params url.Values, body interface{}) (*http.Response, error) {
 	reqURL := c.baseURL + path
 	if len(params) > 0 {
 		reqURL += "?" + params.Encode()
@@ -36,28 +133,114 @@ func (c *Client) doRequest(method, path string, params url.Values, body interfac
 
 	var req *http.Request
 	var err error
+	var jsonBody []byte
 
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, err
 		}
-		req, err = http.NewRequest(method, reqURL, strings.NewReader(string(jsonBody)))
+		req, err = http.NewRequestWithContext(ctx, method, reqURL, strings.NewReader(string(jsonBody)))
 		if err != nil {
 			return nil, err
 		}
 		req.Header.Set("Content-Type", "application/json")
 	} else {
-		req, err = http.NewRequest(method, reqURL, nil)
+		req, err = http.NewRequestWithContext(ctx, method, reqURL, nil)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+	for name, value := range c.headers {
+		req.Header.Set(name, value)
+	}
 	req.Header.Set("Authorization", c.authHeader)
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	// All requests the client makes are reads, so every one of them can be cached.
+	var key string
+	var cached *cacheEntry
+	if c.cache != nil {
+		key = cacheKey(method, reqURL, jsonBody, c.authHeader)
+		var fresh bool
+		cached, fresh = c.cache.get(key)
+		if fresh {
+			c.cacheStats.hits.Add(1)
+			cacheRequests.WithLabelValues("hit").Inc()
+			return cachedResponse(cached), nil
+		}
+		if cached != nil && cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
 
-	return c.httpClient.Do(req)
+	if c.breaker != nil {
+		if err := c.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		observeRequest(path, 0, start)
+	} else {
+		observeRequest(path, resp.StatusCode, start)
+	}
+	var redirectErr *RedirectError
+	refused := errors.As(err, &redirectErr)
+	if c.breaker != nil {
+		// Client errors and refused redirects are the request's fault, only
+		// outages count as failures.
+		c.breaker.record(refused || (err == nil && resp.StatusCode < http.StatusInternalServerError))
+	}
+	if refused {
+		return nil, redirectErr
+	}
+	if err != nil {
+		return nil, wrapNetworkError(ctx, reqURL, err)
+	}
+	c.rateLimit.record(resp.Header)
+
+	if c.cache != nil && cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		c.cacheStats.notModified.Add(1)
+		cacheRequests.WithLabelValues("not_modified").Inc()
+		c.cache.touch(key)
+		return cachedResponse(cached), nil
+	}
+
+	if resp.StatusCode == http.StatusOK && isHTML(resp.Header.Get("Content-Type")) {
+		resp.Body.Close()
+		return nil, ErrHTMLResponse
+	}
+
+	if err := wrapResponseBody(resp, path); err != nil {
+		return nil, err
+	}
+
+	if c.cache != nil {
+		c.cacheStats.misses.Add(1)
+		cacheRequests.WithLabelValues("miss").Inc()
+		if resp.StatusCode == http.StatusOK {
+			payload, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			c.cache.set(key, &cacheEntry{body: payload, etag: resp.Header.Get("ETag"), storedAt: time.Now()})
+			resp.Body = io.NopCloser(bytes.NewReader(payload))
+		}
+	}
+	return resp, nil
 }
 
 type JQLSearchRequest struct {
@@ -74,12 +257,15 @@ type SearchResults struct {
 	Total         int     `json:"total"`
 	Issues        []Issue `json:"issues"`
 	NextPageToken string  `json:"nextPageToken,omitempty"`
+	// WarningMessages explain e.g. fields in the request that don't exist.
+	WarningMessages []string `json:"warningMessages,omitempty"`
 }
 
 type Issue struct {
-	Key       string                 `json:"key"`
-	Fields    map[string]interface{} `json:"fields"`
-	Changelog *Changelog             `json:"changelog"`
+	Key            string                 `json:"key"`
+	Fields         map[string]interface{} `json:"fields"`
+	RenderedFields map[string]interface{} `json:"renderedFields,omitempty"`
+	Changelog      *Changelog             `json:"changelog"`
 }
 
 type Changelog struct {
@@ -90,9 +276,11 @@ type Changelog struct {
 }
 
 type History struct {
-	ID      string   `json:"id"`
-	Created string   `json:"created"`
-	Items   []Item   `json:"items"`
+	ID      string `json:"id"`
+	Created string `json:"created"`
+	Items   []Item `json:"items"`
+	// Author made the change, nil for anonymous and some automated changes.
+	Author *User `json:"author,omitempty"`
 }
 
 type Item struct {
@@ -104,49 +292,268 @@ type Item struct {
 	ToString   string `json:"toString"`
 }
 
-func (c *Client) SearchChangelogs(jql string) ([]Issue, error) {
-	allIssues := []Issue{}
-	maxResults := 50 // Default batch size
-	nextPageToken := ""
+// DefaultFields are the issue fields every search requests.
+var DefaultFields = []string{"key", "summary", "issuetype", "status", "project", "created"}
 
-	for {
-		params := url.Values{}
+// SearchOptions extends what a search returns beyond DefaultFields and the changelog.
+type SearchOptions struct {
+	// Fields are requested in addition to DefaultFields.
+	Fields []string
+	// Expand lists additional expansions, e.g. "renderedFields".
+	Expand []string
+	// SkipChangelog leaves out the changelog expansion for searches that only need fields.
+	SkipChangelog bool
+	// MaxIssues stops paging once this many issues were fetched, 0 fetches everything.
+	MaxIssues int
+	// ChangelogFields keeps only the changelog items of these fields, e.g. "status",
+	// and drops the histories left without items. nil keeps the whole changelog.
+	ChangelogFields []string
+}
 
-		reqBody := JQLSearchRequest{
-			JQL:           jql,
-			MaxResults:    maxResults,
-			Fields:        []string{"key", "summary", "issuetype", "status", "project"},
-			Expand:        "changelog",
-			NextPageToken: nextPageToken,
+// pruneChangelogs applies ChangelogFields to the issues of a page right after
+// decoding. Kept items and histories are copied so that the dropped ones, often
+// most of a changelog (rank changes, description edits), can be collected.
+func (o SearchOptions) pruneChangelogs(issues []Issue) {
+	if o.ChangelogFields == nil {
+		return
+	}
+	for i := range issues {
+		changelog := issues[i].Changelog
+		if changelog == nil {
+			continue
 		}
-		resp, err := c.doRequest("POST", "/rest/api/3/search/jql", params, reqBody)
-		if err != nil {
-			return nil, err
+		var histories []History
+		for _, history := range changelog.Histories {
+			var items []Item
+			for _, item := range history.Items {
+				if contains(o.ChangelogFields, item.Field) {
+					items = append(items, item)
+				}
+			}
+			if len(items) > 0 {
+				history.Items = items
+				histories = append(histories, history)
+			}
 		}
-		defer resp.Body.Close()
+		changelog.Histories = histories
+	}
+}
 
-		if resp.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("Jira API returned status: %s", resp.Status)
+func (o SearchOptions) fields() []string {
+	fields := append([]string{}, DefaultFields...)
+	for _, f := range o.Fields {
+		if !contains(fields, f) {
+			fields = append(fields, f)
 		}
+	}
+	return fields
+}
 
-		var result SearchResults
-		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return nil, err
+func (o SearchOptions) expand() string {
+	return strings.Join(o.expandList(), ",")
+}
+
+func (o SearchOptions) expandList() []string {
+	var expand []string
+	if !o.SkipChangelog {
+		expand = append(expand, "changelog")
+	}
+	for _, e := range o.Expand {
+		if !contains(expand, e) {
+			expand = append(expand, e)
+		}
+	}
+	return expand
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SearchChangelogs fetches all issues matching jql page by page, along with the
+// distinct warning messages of the pages. Every page is traced as a child span
+// of the search span. A search that doesn't end returns the issues fetched so
+// far with a *PaginationError, as does a search that runs out of time before its
+// search deadline.
+func (c *Client) SearchChangelogs(ctx context.Context, jql string, opts SearchOptions) ([]Issue, []string, error) {
+	ctx, span := tracer().Start(ctx, "jira.search", trace.WithAttributes(attribute.String("jql.hash", JQLHash(jql))))
+	defer span.End()
+
+	allIssues := []Issue{}
+	maxResults := 50 // Default batch size
+	nextPageToken := ""
+	pages := 0
+	defer func() { searchPages.Observe(float64(pages)) }()
+
+	maxPages := c.maxPages
+	if maxPages <= 0 {
+		maxPages = DefaultMaxPages
+	}
+	seenTokens := map[string]bool{}
+	var warnings []string
+
+	for {
+		pages++
+		pageStart := time.Now()
+		result, err := c.searchPage(ctx, jql, opts, nextPageToken, maxResults, pages)
+		pageTime := time.Since(pageStart)
+		if err != nil {
+			SpanError(span, err)
+			return nil, nil, err
 		}
 
 		allIssues = append(allIssues, result.Issues...)
+		for _, w := range result.WarningMessages {
+			if !contains(warnings, w) {
+				warnings = append(warnings, w)
+			}
+		}
 
+		if opts.MaxIssues > 0 && len(allIssues) >= opts.MaxIssues {
+			allIssues = allIssues[:opts.MaxIssues]
+			break
+		}
 		if result.NextPageToken == "" {
 			break
 		}
+		if seenTokens[result.NextPageToken] {
+			err := &PaginationError{Pages: pages, RepeatedToken: result.NextPageToken}
+			SpanError(span, err)
+			return allIssues, warnings, err
+		}
+		if pages >= maxPages {
+			err := &PaginationError{Pages: pages}
+			SpanError(span, err)
+			return allIssues, warnings, err
+		}
+		if !pageFits(ctx, pageTime) {
+			err := &PaginationError{Pages: pages, Deadline: true}
+			SpanError(span, err)
+			return allIssues, warnings, err
+		}
+		seenTokens[result.NextPageToken] = true
 		nextPageToken = result.NextPageToken
 	}
 
-	return allIssues, nil
+	span.SetAttributes(attribute.Int("pages", pages), attribute.Int("issues", len(allIssues)))
+	return allIssues, warnings, nil
+}
+
+// SearchPage fetches the single page of up to pageSize issues matching jql that
+// starts at pageToken, the first page for "". Callers that page themselves, e.g.
+// a table loading more rows on demand, continue with the NextPageToken of the
+// result, which is empty after the last page.
+func (c *Client) SearchPage(ctx context.Context, jql string, opts SearchOptions, pageToken string, pageSize int) (*SearchResults, error) {
+	ctx, span := tracer().Start(ctx, "jira.search", trace.WithAttributes(attribute.String("jql.hash", JQLHash(jql))))
+	defer span.End()
+
+	result, err := c.searchPage(ctx, jql, opts, pageToken, pageSize, 1)
+	if err != nil {
+		SpanError(span, err)
+		return nil, err
+	}
+	searchPages.Observe(1)
+	return result, nil
+}
+
+// searchPage fetches a single page of a search, page being its number within
+// the search for tracing.
+func (c *Client) searchPage(ctx context.Context, jql string, opts SearchOptions, pageToken string, pageSize int, page int) (*SearchResults, error) {
+	ctx, span := tracer().Start(ctx, "jira.search.page", trace.WithAttributes(attribute.Int("page", page)))
+	defer span.End()
+
+	reqBody := JQLSearchRequest{
+		JQL:           jql,
+		MaxResults:    pageSize,
+		Fields:        opts.fields(),
+		Expand:        opts.expand(),
+		NextPageToken: pageToken,
+	}
+
+	resp, err := c.doRequest(ctx, "POST", "/rest/api/3/search/jql", nil, reqBody)
+	if err != nil {
+		SpanError(span, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("Jira API returned status: %s", resp.Status)
+		SpanError(span, err)
+		return nil, err
+	}
+
+	var result SearchResults
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		SpanError(span, err)
+		return nil, err
+	}
+	opts.pruneChangelogs(result.Issues)
+	span.SetAttributes(attribute.Int("issues", len(result.Issues)))
+	return &result, nil
+}
+
+// StatusError is returned when Jira answers with an unexpected HTTP status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Jira API returned status: %s", e.Status)
+}
+
+// getJSON performs a GET request and decodes the JSON response into v.
+func (c *Client) getJSON(ctx context.Context, path string, params url.Values, v interface{}) error {
+	resp, err := c.doRequest(ctx, "GET", path, params, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+type approximateCountRequest struct {
+	JQL string `json:"jql"`
+}
+
+type approximateCountResponse struct {
+	Count int `json:"count"`
+}
+
+// CountIssues returns the approximate number of issues matching jql without
+// fetching them.
+func (c *Client) CountIssues(ctx context.Context, jql string) (int, error) {
+	resp, err := c.doRequest(ctx, "POST", "/rest/api/3/search/approximate-count", nil, approximateCountRequest{JQL: jql})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Jira API returned status: %s", resp.Status)
+	}
+
+	var result approximateCountResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+	return result.Count, nil
 }
 
-func (c *Client) Myself() error {
-	resp, err := c.doRequest("GET", "/rest/api/3/myself", nil, nil)
+func (c *Client) Myself(ctx context.Context) error {
+	resp, err := c.doRequest(ctx, "GET", "/rest/api/3/myself", nil, nil)
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,20 @@
 package jira
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -10,7 +22,7 @@ func TestNewClient(t *testing.T) {
 	username := "user@example.com"
 	token := "my-secret-token"
 
-	client := NewClient(baseURL, username, token)
+	client := NewClient(baseURL, username, token, "grafana-jira-datasource/1.2.3 (grafana 11.0.0)")
 
 	if client.baseURL != "https://jira.example.com" {
 		t.Errorf("expected baseURL 'https://jira.example.com', got '%s'", client.baseURL)
@@ -21,3 +33,683 @@ func TestNewClient(t *testing.T) {
 		t.Errorf("expected authHeader '%s', got '%s'", expectedAuth, client.authHeader)
 	}
 }
+
+func TestUserAgentHeader(t *testing.T) {
+	userAgent := "grafana-jira-datasource/1.2.3 (grafana 11.0.0)"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("User-Agent"); got != userAgent {
+			t.Errorf("expected User-Agent %q, got %q", userAgent, got)
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	if err := NewClient(server.URL, "user", "token", userAgent).Myself(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLanguageHeader(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Accept-Language"))
+		fmt.Fprint(w, `{"issues":[],"isLast":true}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "token", "")
+	if err := client.Myself(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	client.SetLanguage("en")
+	if err := client.Myself(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	client.SetCustomHeaders(map[string]string{"Accept-Language": "de"})
+	if err := client.Myself(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != ",en,en,de" {
+		t.Errorf("expected no header, en for every request and the custom header, got %q", got)
+	}
+}
+
+func TestSearchUsersFallsBackToV2(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/3/user/assignable/search":
+			http.NotFound(w, r)
+		case "/rest/api/2/user/assignable/search":
+			if r.URL.Query().Get("username") != "ali" {
+				t.Errorf("expected the search string as username, got %q", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `[{"name":"alice","key":"JIRAUSER1","displayName":"Alice"}]`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	users, err := NewClient(server.URL, "user", "token", "").SearchUsers(context.Background(), "ali", "PLAT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("unexpected users %+v", users)
+	}
+}
+
+func TestGetProjectsPages(t *testing.T) {
+	var startAts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/project/search" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if got := query["status"]; len(got) != 2 || got[0] != "live" || got[1] != "archived" {
+			t.Errorf("expected live and archived projects, got %v", got)
+		}
+		startAts = append(startAts, query.Get("startAt"))
+		switch query.Get("startAt") {
+		case "0":
+			fmt.Fprint(w, `{"isLast":false,"values":[{"id":"1","key":"A","name":"Alpha"},{"id":"2","key":"B","name":"Beta"}]}`)
+		case "2":
+			fmt.Fprint(w, `{"isLast":true,"values":[{"id":"3","key":"C","name":"Gamma","archived":true}]}`)
+		default:
+			t.Errorf("unexpected page at %s", query.Get("startAt"))
+		}
+	}))
+	defer server.Close()
+
+	projects, err := NewClient(server.URL, "user", "token", "").GetProjects(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(startAts, ",") != "0,2" {
+		t.Errorf("expected pages at 0 and 2, got %v", startAts)
+	}
+	if len(projects) != 3 || projects[2].Key != "C" || !projects[2].Archived {
+		t.Errorf("expected the projects of both pages, got %+v", projects)
+	}
+}
+
+func TestFetchIssuesStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, _, err := NewClient(server.URL, "user", "token", "").FetchIssues(context.Background(), []string{"A-1"}, SearchOptions{})
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a status error, got %v", err)
+	}
+}
+
+func TestGzipResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected Accept-Encoding gzip, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, `{"issues":[{"key":"A-1","fields":{}}]}`)
+		gz.Close()
+	}))
+	defer server.Close()
+
+	issues, _, err := NewClient(server.URL, "user", "token", "").SearchChangelogs(context.Background(), "project = A", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Key != "A-1" {
+		t.Errorf("unexpected issues %+v", issues)
+	}
+}
+
+func TestCacheRevalidatesWithETag(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}}]}`)
+	}))
+	defer server.Close()
+
+	// An expired entry is revalidated and the stored payload reused on 304.
+	client := NewClient(server.URL, "user", "token", "")
+	client.SetCache(NewCache(time.Nanosecond))
+	for i := 0; i < 2; i++ {
+		issues, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(issues) != 1 || issues[0].Key != "A-1" {
+			t.Errorf("unexpected issues %+v", issues)
+		}
+	}
+	if stats := client.CacheStats(); stats != (CacheStats{Misses: 1, NotModified: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// A fresh entry is served without a request.
+	requests = 0
+	client.SetCache(NewCache(time.Minute))
+	before := client.CacheStats()
+	for i := 0; i < 2; i++ {
+		if _, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("expected 1 request, got %d", requests)
+	}
+	if stats := client.CacheStats().Sub(before); stats != (CacheStats{Hits: 1, Misses: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	requests := 0
+	healthy := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if !healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+	client := NewClient(server.URL, "user", "token", "")
+	client.SetCircuitBreaker(breaker)
+
+	for i := 0; i < 3; i++ {
+		client.Myself(context.Background())
+	}
+	if requests != 2 {
+		t.Errorf("expected the circuit to open after 2 requests, got %d requests", requests)
+	}
+	var openErr *CircuitOpenError
+	if err := client.Myself(context.Background()); !errors.As(err, &openErr) {
+		t.Fatalf("expected a CircuitOpenError, got %v", err)
+	}
+	if breaker.State().State != CircuitOpen {
+		t.Errorf("expected an open circuit, got %+v", breaker.State())
+	}
+
+	// After the cooldown a probe is sent and its success closes the circuit.
+	healthy = true
+	time.Sleep(30 * time.Millisecond)
+	if err := client.Myself(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if state := breaker.State(); state != (CircuitState{State: CircuitClosed}) {
+		t.Errorf("expected a closed circuit, got %+v", state)
+	}
+}
+
+func TestEndpointLabel(t *testing.T) {
+	tests := map[string]string{
+		"/rest/api/3/search/jql":                "/rest/api/3/search/jql",
+		"/rest/api/3/project/search":            "/rest/api/3/project/search",
+		"/rest/api/3/project/PLAT/components":   "/rest/api/3/project/{key}/components",
+		"/rest/api/3/issue/PLAT-1":              "/rest/api/3/issue/{key}",
+		"/rest/api/3/issue/bulkfetch":           "/rest/api/3/issue/bulkfetch",
+		"/rest/agile/1.0/sprint/42":             "/rest/agile/1.0/sprint/{id}",
+		"/rest/agile/1.0/board/7/configuration": "/rest/agile/1.0/board/{id}/configuration",
+		"/rest/agile/1.0/board":                 "/rest/agile/1.0/board",
+	}
+	for path, want := range tests {
+		if got := endpointLabel(path); got != want {
+			t.Errorf("endpointLabel(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSearchAbortsOnRepeatedPageToken(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 10 {
+			t.Fatal("search did not stop")
+		}
+		fmt.Fprintf(w, `{"issues":[{"key":"A-%d","fields":{}}],"nextPageToken":"same"}`, requests)
+	}))
+	defer server.Close()
+
+	issues, _, err := NewClient(server.URL, "user", "token", "").SearchChangelogs(context.Background(), "project = A", SearchOptions{})
+	var paginationErr *PaginationError
+	if !errors.As(err, &paginationErr) || paginationErr.RepeatedToken != "same" || paginationErr.Pages != 2 {
+		t.Fatalf("expected a repeated token error after 2 pages, got %v", err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("expected the 2 issues fetched so far, got %d", len(issues))
+	}
+}
+
+func TestSearchStopsAtMaxPages(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"issues":[{"key":"A-%d","fields":{}}],"nextPageToken":"page-%d"}`, requests, requests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "token", "")
+	client.SetMaxPages(3)
+	issues, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{})
+	var paginationErr *PaginationError
+	if !errors.As(err, &paginationErr) || paginationErr.RepeatedToken != "" || paginationErr.Pages != 3 {
+		t.Fatalf("expected a page limit error after 3 pages, got %v", err)
+	}
+	if len(issues) != 3 || requests != 3 {
+		t.Errorf("expected 3 issues from 3 requests, got %d issues from %d requests", len(issues), requests)
+	}
+}
+
+func TestSearchStopsBeforeDeadline(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		time.Sleep(40 * time.Millisecond)
+		fmt.Fprintf(w, `{"issues":[{"key":"A-%d","fields":{}}],"nextPageToken":"page-%d"}`, requests, requests)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "token", "")
+	ctx := WithSearchDeadline(context.Background(), time.Now().Add(100*time.Millisecond))
+	issues, _, err := client.SearchChangelogs(ctx, "project = A", SearchOptions{})
+	var paginationErr *PaginationError
+	if !errors.As(err, &paginationErr) || !paginationErr.Deadline {
+		t.Fatalf("expected the search to stop before the deadline, got %v", err)
+	}
+	// A third page wouldn't be done in time.
+	if len(issues) != 2 || requests != 2 {
+		t.Errorf("expected 2 issues from 2 requests, got %d issues from %d requests", len(issues), requests)
+	}
+
+	// A deadline that has passed still fetches the first page.
+	requests = 0
+	ctx = WithSearchDeadline(context.Background(), time.Now().Add(-time.Second))
+	if issues, _, err := client.SearchChangelogs(ctx, "project = A", SearchOptions{}); len(issues) != 1 || !errors.As(err, &paginationErr) {
+		t.Errorf("expected the first page and a deadline error, got %d issues and %v", len(issues), err)
+	}
+}
+
+func TestRateLimitLow(t *testing.T) {
+	tests := []struct {
+		limit RateLimit
+		low   bool
+	}{
+		{RateLimit{"X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "50"}, false},
+		{RateLimit{"X-RateLimit-Limit": "100", "X-RateLimit-Remaining": "9"}, true},
+		{RateLimit{"X-RateLimit-Remaining": "1"}, false},
+		{RateLimit{"X-RateLimit-NearLimit": "true"}, true},
+	}
+	for _, tt := range tests {
+		if low := tt.limit.Low(); low != tt.low {
+			t.Errorf("expected Low() of %v to be %v, got %v", tt.limit, tt.low, low)
+		}
+	}
+}
+
+func TestSearchReturnsWarnings(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}}],"nextPageToken":"next","warningMessages":["The field 'storypoints' does not exist."]}`)
+			return
+		}
+		fmt.Fprint(w, `{"issues":[{"key":"A-2","fields":{}}],"warningMessages":["The field 'storypoints' does not exist.","Ordering by rank is ignored."]}`)
+	}))
+	defer server.Close()
+
+	issues, warnings, err := NewClient(server.URL, "user", "token", "").SearchChangelogs(context.Background(), "project = A", SearchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 {
+		t.Errorf("expected 2 issues, got %d", len(issues))
+	}
+	if len(warnings) != 2 || warnings[1] != "Ordering by rank is ignored." {
+		t.Errorf("expected the 2 distinct warnings, got %v", warnings)
+	}
+}
+
+func TestSearchPrunesChangelogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{},"changelog":{"histories":[
+			{"created":"2024-01-02T10:00:00.000+0000","items":[{"field":"Rank","toString":"Ranked higher"}]},
+			{"created":"2024-01-03T10:00:00.000+0000","items":[{"field":"description"},{"field":"status","toString":"Done"}]}
+		]}}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "token", "")
+	issues, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{ChangelogFields: []string{"status"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	histories := issues[0].Changelog.Histories
+	if len(histories) != 1 || len(histories[0].Items) != 1 || histories[0].Items[0].ToString != "Done" {
+		t.Errorf("expected only the status change to be kept, got %+v", histories)
+	}
+
+	issues, _, _ = client.SearchChangelogs(context.Background(), "project = A", SearchOptions{})
+	if got := len(issues[0].Changelog.Histories); got != 2 {
+		t.Errorf("expected the whole changelog without ChangelogFields, got %d histories", got)
+	}
+}
+
+// changelogFixture is a search page of issues whose changelogs are mostly rank
+// changes and description edits, like those of long-lived issues.
+func changelogFixture(issues, histories int) []byte {
+	var page bytes.Buffer
+	page.WriteString(`{"issues":[`)
+	for i := 0; i < issues; i++ {
+		if i > 0 {
+			page.WriteString(",")
+		}
+		fmt.Fprintf(&page, `{"key":"A-%d","fields":{},"changelog":{"histories":[`, i)
+		for h := 0; h < histories; h++ {
+			if h > 0 {
+				page.WriteString(",")
+			}
+			item := `{"field":"Rank","fieldtype":"custom","from":"","fromString":"","to":"","toString":"Ranked higher"},{"field":"description","fieldtype":"jira","from":null,"fromString":"An old description of the issue","to":null,"toString":"A new description of the issue"}`
+			if h%20 == 0 {
+				item = `{"field":"status","fieldtype":"jira","from":"1","fromString":"To Do","to":"3","toString":"In Progress"}`
+			}
+			fmt.Fprintf(&page, `{"id":"%d","created":"2024-01-02T10:00:00.000+0000","author":{"accountId":"abc","displayName":"Someone"},"items":[%s]}`, h, item)
+		}
+		page.WriteString(`]}}`)
+	}
+	page.WriteString(`]}`)
+	return page.Bytes()
+}
+
+// BenchmarkChangelogPruning reports the heap retained by the decoded issues of
+// a page with the whole changelog and with only the status changes.
+func BenchmarkChangelogPruning(b *testing.B) {
+	page := changelogFixture(50, 1000)
+	for _, bc := range []struct {
+		name string
+		opts SearchOptions
+	}{
+		{"all", SearchOptions{}},
+		{"status", SearchOptions{ChangelogFields: []string{"status"}}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				var result SearchResults
+				if err := json.Unmarshal(page, &result); err != nil {
+					b.Fatal(err)
+				}
+				bc.opts.pruneChangelogs(result.Issues)
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				retained += after.HeapAlloc - before.HeapAlloc
+				runtime.KeepAlive(result)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
+
+func TestHTMLResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<!DOCTYPE html><html><body><form action="/login"></form></body></html>`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "user", "token", "")
+	client.SetCache(NewCache(time.Minute))
+	if err := client.Myself(context.Background()); !errors.Is(err, ErrHTMLResponse) {
+		t.Errorf("expected ErrHTMLResponse from the health check, got %v", err)
+	}
+	if _, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{}); !errors.Is(err, ErrHTMLResponse) {
+		t.Errorf("expected ErrHTMLResponse from a search, got %v", err)
+	}
+	if stats := client.CacheStats(); stats.Misses != 0 {
+		t.Errorf("expected login pages not to be cached, got %+v", stats)
+	}
+}
+
+func TestNetworkErrors(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		timeout  time.Duration
+		category string
+	}{
+		{"dns", "http://jira.invalid", 0, NetworkDNS},
+		{"refused", closedURL, 0, NetworkRefused},
+		{"tls", tlsServer.URL, 0, NetworkTLS},
+		{"timeout", slow.URL, 50 * time.Millisecond, NetworkTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(tt.url, "user", "token", "")
+			client.SetHTTPClient(&http.Client{Timeout: tt.timeout})
+			err := client.Myself(context.Background())
+			var netErr *NetworkError
+			if !errors.As(err, &netErr) {
+				t.Fatalf("expected a NetworkError, got %T: %v", err, err)
+			}
+			if netErr.Category != tt.category {
+				t.Errorf("expected category %q, got %q (%v)", tt.category, netErr.Category, netErr.Err)
+			}
+			if want := "Cannot reach Jira at " + netErr.Host + ": " + tt.category; !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("expected the message to start with %q, got %q", want, err.Error())
+			}
+			if netErr.Err == nil {
+				t.Error("expected the underlying error to be kept")
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client := NewClient(slow.URL, "user", "token", "")
+	if _, _, err := client.SearchChangelogs(ctx, "project = A", SearchOptions{}); !errors.Is(err, context.Canceled) || errors.As(err, new(*NetworkError)) {
+		t.Errorf("expected cancelled requests to keep their error, got %v", err)
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	var newHostRequests int
+	newHost := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		newHostRequests++
+		fmt.Fprint(w, `{}`)
+	}))
+	defer newHost.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/moved/"):
+			http.Redirect(w, r, "/jira/"+strings.TrimPrefix(r.URL.Path, "/moved/"), http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, "/kept/"):
+			http.Redirect(w, r, "/jira/"+strings.TrimPrefix(r.URL.Path, "/kept/"), http.StatusPermanentRedirect)
+		case strings.HasPrefix(r.URL.Path, "/temporary/"):
+			http.Redirect(w, r, "/jira/"+strings.TrimPrefix(r.URL.Path, "/temporary/"), http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/elsewhere/"):
+			http.Redirect(w, r, newHost.URL+"/"+strings.TrimPrefix(r.URL.Path, "/elsewhere/"), http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, "/loop/"):
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		case r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), "project = A") {
+				t.Errorf("expected the search to keep its body, got %s", body)
+			}
+			fmt.Fprint(w, `{"issues":[{"key":"A-1"}],"isLast":true}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+
+	// A permanent redirect on the same host is followed and recorded.
+	client := NewClient(server.URL+"/moved", "user", "token", "")
+	if err := client.Myself(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := client.MovedTo(); got != server.URL+"/jira" {
+		t.Errorf("expected the client to have moved to %s/jira, got %q", server.URL, got)
+	}
+
+	// Temporary redirects are followed without a trace.
+	client = NewClient(server.URL+"/temporary", "user", "token", "")
+	if err := client.Myself(context.Background()); err != nil || client.MovedTo() != "" {
+		t.Errorf("expected a temporary redirect to be followed silently, got %v and %q", err, client.MovedTo())
+	}
+
+	// Searches are POSTs, a 301 would turn them into a GET without the JQL.
+	client = NewClient(server.URL+"/moved", "user", "token", "")
+	var redirectErr *RedirectError
+	if _, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{}); !errors.As(err, &redirectErr) || !strings.Contains(err.Error(), "without its body") {
+		t.Errorf("expected the redirect of the search to be refused, got %v", err)
+	}
+	client = NewClient(server.URL+"/kept", "user", "token", "")
+	if issues, _, err := client.SearchChangelogs(context.Background(), "project = A", SearchOptions{}); err != nil || len(issues) != 1 {
+		t.Errorf("expected a 308 to keep the search, got %d issues and %v", len(issues), err)
+	}
+
+	// The credentials never go to another host.
+	client = NewClient(server.URL+"/elsewhere", "user", "token", "")
+	if err := client.Myself(context.Background()); !errors.As(err, &redirectErr) || !strings.HasPrefix(redirectErr.URL, newHost.URL) {
+		t.Errorf("expected the redirect to %s to be refused, got %v", newHost.URL, err)
+	}
+	if newHostRequests != 0 {
+		t.Errorf("expected no request to the other host, got %d", newHostRequests)
+	}
+	if client.MovedTo() != newHost.URL {
+		t.Errorf("expected the move to %s to be recorded, got %q", newHost.URL, client.MovedTo())
+	}
+
+	client = NewClient(server.URL+"/loop", "user", "token", "")
+	if err := client.Myself(context.Background()); !errors.As(err, &redirectErr) || !strings.Contains(err.Error(), "redirected 3 times") {
+		t.Errorf("expected the redirect loop to stop, got %v", err)
+	}
+}
+
+func TestBackfillChangelogs(t *testing.T) {
+	histories := []string{
+		`{"id":"1","created":"2024-01-01T10:00:00.000+0000","items":[{"field":"status","fromString":"To Do","toString":"In Progress"}]}`,
+		`{"id":"2","created":"2024-01-02T10:00:00.000+0000","items":[{"field":"Rank","toString":"Ranked higher"}]}`,
+		`{"id":"3","created":"2024-01-03T10:00:00.000+0000","items":[{"field":"status","fromString":"In Progress","toString":"Review"}]}`,
+		`{"id":"4","created":"2024-01-04T10:00:00.000+0000","items":[{"field":"status","fromString":"Review","toString":"Done"}]}`,
+		`{"id":"5","created":"2024-01-05T10:00:00.000+0000","items":[{"field":"labels","toString":"backend"}]}`,
+	}
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/rest/api/3/issue/A-1/changelog" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			return
+		}
+		// Two histories per page, whatever the client asks for.
+		var startAt int
+		fmt.Sscan(r.URL.Query().Get("startAt"), &startAt)
+		end := min(startAt+2, len(histories))
+		fmt.Fprintf(w, `{"startAt":%d,"maxResults":2,"total":%d,"isLast":%t,"values":[%s]}`, startAt, len(histories), end == len(histories), strings.Join(histories[startAt:end], ","))
+	}))
+	defer server.Close()
+
+	issues := []Issue{
+		{Key: "A-1", Changelog: &Changelog{MaxResults: 2, Total: 5, Histories: []History{{ID: "4"}, {ID: "5"}}}},
+		{Key: "A-2", Changelog: &Changelog{MaxResults: 1, Total: 1, Histories: []History{{ID: "9"}}}},
+		{Key: "A-3"},
+	}
+	if !issues[0].Changelog.Truncated() || issues[1].Changelog.Truncated() {
+		t.Fatal("expected only the changelog of A-1 to be truncated")
+	}
+
+	client := NewClient(server.URL, "user", "token", "")
+	stats, err := client.BackfillChangelogs(context.Background(), issues, SearchOptions{ChangelogFields: []string{"status"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Issues != 1 || stats.Requests != 3 || len(paths) != 3 {
+		t.Errorf("expected one issue back-filled with 3 requests, got %+v after %d requests", stats, len(paths))
+	}
+	var ids []string
+	for _, history := range issues[0].Changelog.Histories {
+		ids = append(ids, history.ID)
+	}
+	if strings.Join(ids, ",") != "1,3,4" || issues[0].Changelog.Truncated() {
+		t.Errorf("expected the status changes of the full changelog, got %v", ids)
+	}
+	if len(issues[1].Changelog.Histories) != 1 {
+		t.Error("expected the complete changelog to be kept")
+	}
+
+	// A failing back-fill keeps the truncated changelog.
+	server.Close()
+	issues = []Issue{{Key: "A-1", Changelog: &Changelog{MaxResults: 2, Total: 5, Histories: []History{{ID: "4"}, {ID: "5"}}}}}
+	if stats, err := client.BackfillChangelogs(context.Background(), issues, SearchOptions{}); err == nil || stats.Issues != 0 || stats.Requests != 1 || len(issues[0].Changelog.Histories) != 2 {
+		t.Errorf("expected the failed request to be counted and the changelog kept, got %+v and %v", stats, err)
+	}
+}
+
+func TestBackfillChangelogsDeadline(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"startAt":0,"maxResults":100,"total":2,"isLast":true,"values":[{"id":"1"},{"id":"2"}]}`)
+	}))
+	defer server.Close()
+
+	truncated := func(key string) Issue {
+		return Issue{Key: key, Changelog: &Changelog{MaxResults: 1, Total: 2, Histories: []History{{ID: "2"}}}}
+	}
+	issues := []Issue{truncated("A-1"), truncated("A-2"), truncated("A-3")}
+
+	// The deadline passed already: the first issue is back-filled, the rest
+	// keep their truncated changelogs.
+	ctx := WithSearchDeadline(context.Background(), time.Now().Add(-time.Second))
+	client := NewClient(server.URL, "user", "token", "")
+	stats, err := client.BackfillChangelogs(ctx, issues, SearchOptions{})
+	var paginationErr *PaginationError
+	if !errors.As(err, &paginationErr) || !paginationErr.Deadline {
+		t.Fatalf("expected the deadline to stop the back-fill, got %v", err)
+	}
+	if stats.Issues != 1 || stats.Requests != 1 || requests != 1 {
+		t.Errorf("expected one issue back-filled with one request, got %+v after %d requests", stats, requests)
+	}
+	if len(issues[0].Changelog.Histories) != 2 || !issues[1].Changelog.Truncated() || !issues[2].Changelog.Truncated() {
+		t.Error("expected only the first changelog to be back-filled")
+	}
+}
@@ -0,0 +1,30 @@
+package jira
+
+import (
+	"context"
+	"time"
+)
+
+type searchDeadlineKey struct{}
+
+// WithSearchDeadline returns a context in which searches stop fetching further
+// pages when the next one wouldn't be done by deadline, judging by how long the
+// last page took. Unlike a context deadline, it doesn't cancel the page in
+// flight, so the search ends with the issues so far and a *PaginationError
+// instead of failing.
+func WithSearchDeadline(ctx context.Context, deadline time.Time) context.Context {
+	return context.WithValue(ctx, searchDeadlineKey{}, deadline)
+}
+
+// SearchDeadlineReached reports whether the search deadline of ctx, if any, has
+// passed.
+func SearchDeadlineReached(ctx context.Context) bool {
+	return !pageFits(ctx, 0)
+}
+
+// pageFits reports whether a page that takes estimate is done before the search
+// deadline of ctx, always true without one.
+func pageFits(ctx context.Context, estimate time.Duration) bool {
+	deadline, ok := ctx.Value(searchDeadlineKey{}).(time.Time)
+	return !ok || time.Now().Add(estimate).Before(deadline)
+}
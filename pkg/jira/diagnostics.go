@@ -0,0 +1,102 @@
+package jira
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// ServerInfo describes the Jira instance.
+type ServerInfo struct {
+	BaseURL        string `json:"baseUrl"`
+	Version        string `json:"version"`
+	DeploymentType string `json:"deploymentType"`
+	BuildNumber    int    `json:"buildNumber"`
+}
+
+// ServerInfo returns the version and deployment type of the Jira instance.
+func (c *Client) ServerInfo(ctx context.Context) (*ServerInfo, error) {
+	var info ServerInfo
+	if err := c.getJSON(ctx, "/rest/api/3/serverInfo", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+type boardPage struct {
+	Total int `json:"total"`
+}
+
+// CountBoards returns the number of boards visible to the user. It fails when the
+// Jira Software (agile) API isn't available.
+func (c *Client) CountBoards(ctx context.Context) (int, error) {
+	params := url.Values{}
+	params.Set("maxResults", "1")
+
+	var page boardPage
+	if err := c.getJSON(ctx, "/rest/agile/1.0/board", params, &page); err != nil {
+		return 0, err
+	}
+	return page.Total, nil
+}
+
+// rateLimitHeaders are the headers Jira describes its rate limit with.
+var rateLimitHeaders = []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-RateLimit-NearLimit", "Retry-After"}
+
+// RateLimit holds the rate limit headers of the latest response that had any,
+// keyed by header name.
+type RateLimit map[string]string
+
+// LowRateLimitRatio is the share of the rate limit budget below which the
+// remaining budget counts as low.
+const LowRateLimitRatio = 0.1
+
+// Low reports whether Jira says the limit is near or less than
+// LowRateLimitRatio of the budget remains.
+func (r RateLimit) Low() bool {
+	if strings.EqualFold(r["X-RateLimit-NearLimit"], "true") {
+		return true
+	}
+	limit, err := strconv.ParseFloat(r["X-RateLimit-Limit"], 64)
+	if err != nil || limit <= 0 {
+		return false
+	}
+	remaining, err := strconv.ParseFloat(r["X-RateLimit-Remaining"], 64)
+	return err == nil && remaining < limit*LowRateLimitRatio
+}
+
+type rateLimitRecorder struct {
+	mu     sync.Mutex
+	latest RateLimit
+}
+
+func (r *rateLimitRecorder) record(header http.Header) {
+	latest := RateLimit{}
+	for _, name := range rateLimitHeaders {
+		if value := header.Get(name); value != "" {
+			latest[name] = value
+		}
+	}
+	if len(latest) == 0 {
+		return
+	}
+	if latest.Low() {
+		log.DefaultLogger.Warn("Jira rate limit budget is running low", "limit", latest["X-RateLimit-Limit"], "remaining", latest["X-RateLimit-Remaining"], "reset", latest["X-RateLimit-Reset"])
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.latest = latest
+}
+
+// RateLimit returns the rate limit headers Jira sent last, nil if it never sent any.
+func (c *Client) RateLimit() RateLimit {
+	c.rateLimit.mu.Lock()
+	defer c.rateLimit.mu.Unlock()
+	return c.rateLimit.latest
+}
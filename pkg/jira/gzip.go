@@ -0,0 +1,79 @@
+package jira
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// maxResponseBytes caps how much of a (decompressed) response body is read, to
+// protect the plugin against pathological responses.
+const maxResponseBytes = 256 << 20
+
+var errResponseTooLarge = errors.New("Jira response exceeds the maximum size of 256 MiB")
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// responseBody decompresses (when needed) and size-limits a response body, and
+// logs the transferred and decoded sizes when it is closed.
+type responseBody struct {
+	raw     io.ReadCloser
+	wire    *countingReader
+	decoded *countingReader
+	path    string
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	n, err := b.decoded.Read(p)
+	if b.decoded.n > maxResponseBytes {
+		return n, errResponseTooLarge
+	}
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	log.DefaultLogger.Debug("Jira response size", "path", b.path, "wireBytes", b.wire.n, "decodedBytes", b.decoded.n)
+	return b.raw.Close()
+}
+
+// wrapResponseBody replaces resp.Body with a reader that undoes gzip content
+// encoding. Go only decompresses transparently when it added Accept-Encoding
+// itself, which it doesn't once the header is set explicitly.
+func wrapResponseBody(resp *http.Response, path string) error {
+	wire := &countingReader{r: resp.Body}
+	var decoded io.Reader = wire
+
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(wire)
+		if err != nil {
+			resp.Body.Close()
+			return err
+		}
+		decoded = gz
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+	}
+
+	resp.Body = &responseBody{
+		raw:     resp.Body,
+		wire:    wire,
+		decoded: &countingReader{r: decoded},
+		path:    path,
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package jira
+
+import "strings"
+
+// jqlEscaper escapes the characters that would end or break a quoted JQL string.
+var jqlEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// QuoteJQL returns value as a single-quoted JQL string, e.g. for status names or
+// timestamps injected into a query.
+func QuoteJQL(value string) string {
+	return "'" + jqlEscaper.Replace(value) + "'"
+}
+
+// QuoteJQLList quotes every value and joins them for use in an IN (...) clause.
+func QuoteJQLList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = QuoteJQL(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,27 @@
+package jira
+
+import "testing"
+
+func TestQuoteJQL(t *testing.T) {
+	tests := map[string]string{
+		"In Progress":      `'In Progress'`,
+		"Won't Do":         `'Won\'t Do'`,
+		`Back\slash`:       `'Back\\slash'`,
+		"Done, Closed":     `'Done, Closed'`,
+		"Erledigt ✓":       `'Erledigt ✓'`,
+		`' OR project = X`: `'\' OR project = X'`,
+		"2024-01-01 00:00": `'2024-01-01 00:00'`,
+	}
+	for value, want := range tests {
+		if got := QuoteJQL(value); got != want {
+			t.Errorf("QuoteJQL(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestQuoteJQLList(t *testing.T) {
+	got := QuoteJQLList([]string{"PLAT-1", "Won't Do"})
+	if want := `'PLAT-1', 'Won\'t Do'`; got != want {
+		t.Errorf("QuoteJQLList = %s, want %s", got, want)
+	}
+}
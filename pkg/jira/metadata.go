@@ -0,0 +1,186 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type labelPage struct {
+	IsLast     bool     `json:"isLast"`
+	StartAt    int      `json:"startAt"`
+	MaxResults int      `json:"maxResults"`
+	Values     []string `json:"values"`
+}
+
+// GetLabels returns every label in use on the instance.
+func (c *Client) GetLabels(ctx context.Context) ([]string, error) {
+	var labels []string
+	startAt := 0
+
+	for {
+		params := url.Values{}
+		params.Set("startAt", strconv.Itoa(startAt))
+		params.Set("maxResults", "1000")
+
+		var page labelPage
+		if err := c.getJSON(ctx, "/rest/api/3/label", params, &page); err != nil {
+			return nil, err
+		}
+		labels = append(labels, page.Values...)
+
+		if page.IsLast || len(page.Values) == 0 {
+			break
+		}
+		startAt += len(page.Values)
+	}
+
+	return labels, nil
+}
+
+// NamedValue is the common shape of project metadata like components and versions.
+type NamedValue struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetStatuses returns every status of the instance with its id and name.
+func (c *Client) GetStatuses(ctx context.Context) ([]NamedValue, error) {
+	var statuses []NamedValue
+	err := c.getJSON(ctx, "/rest/api/3/status", nil, &statuses)
+	return statuses, err
+}
+
+// GetProjectComponents returns the components of a project.
+func (c *Client) GetProjectComponents(ctx context.Context, projectKey string) ([]NamedValue, error) {
+	var components []NamedValue
+	err := c.getJSON(ctx, "/rest/api/3/project/"+url.PathEscape(projectKey)+"/components", nil, &components)
+	return components, err
+}
+
+// GetProjectVersions returns the versions of a project.
+func (c *Client) GetProjectVersions(ctx context.Context, projectKey string) ([]NamedValue, error) {
+	var versions []NamedValue
+	err := c.getJSON(ctx, "/rest/api/3/project/"+url.PathEscape(projectKey)+"/versions", nil, &versions)
+	return versions, err
+}
+
+// Project is a Jira project as returned by the project search.
+type Project struct {
+	ID              string           `json:"id"`
+	Key             string           `json:"key"`
+	Name            string           `json:"name"`
+	Archived        bool             `json:"archived"`
+	ProjectCategory *ProjectCategory `json:"projectCategory,omitempty"`
+	Lead            *User            `json:"lead,omitempty"`
+}
+
+type ProjectCategory struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// User is a Jira user reference. Cloud identifies users by AccountID, Server and
+// Data Center by Name/Key.
+type User struct {
+	AccountID   string `json:"accountId,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Key         string `json:"key,omitempty"`
+	DisplayName string `json:"displayName"`
+}
+
+type projectPage struct {
+	IsLast     bool      `json:"isLast"`
+	StartAt    int       `json:"startAt"`
+	MaxResults int       `json:"maxResults"`
+	Values     []Project `json:"values"`
+}
+
+// GetProjects returns all projects visible to the user, including their lead.
+// Archived projects are only included when includeArchived is set.
+func (c *Client) GetProjects(ctx context.Context, includeArchived bool) ([]Project, error) {
+	var projects []Project
+	startAt := 0
+
+	for {
+		params := url.Values{}
+		params.Set("startAt", strconv.Itoa(startAt))
+		params.Set("maxResults", "100")
+		params.Set("expand", "lead")
+		params.Add("status", "live")
+		if includeArchived {
+			params.Add("status", "archived")
+		}
+
+		var page projectPage
+		if err := c.getJSON(ctx, "/rest/api/3/project/search", params, &page); err != nil {
+			return nil, err
+		}
+		projects = append(projects, page.Values...)
+
+		if page.IsLast || len(page.Values) == 0 {
+			break
+		}
+		startAt += len(page.Values)
+	}
+
+	return projects, nil
+}
+
+type statusPage struct {
+	IsLast     bool         `json:"isLast"`
+	StartAt    int          `json:"startAt"`
+	MaxResults int          `json:"maxResults"`
+	Values     []NamedValue `json:"values"`
+}
+
+// GetProjectStatuses returns the statuses usable in the project with the given
+// id, which in team-managed projects can share their names with statuses of
+// other projects.
+func (c *Client) GetProjectStatuses(ctx context.Context, projectID string) ([]NamedValue, error) {
+	var statuses []NamedValue
+	startAt := 0
+
+	for {
+		params := url.Values{}
+		params.Set("projectId", projectID)
+		params.Set("startAt", strconv.Itoa(startAt))
+		params.Set("maxResults", "200")
+
+		var page statusPage
+		if err := c.getJSON(ctx, "/rest/api/3/statuses/search", params, &page); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, page.Values...)
+
+		if page.IsLast || len(page.Values) == 0 {
+			break
+		}
+		startAt += len(page.Values)
+	}
+
+	return statuses, nil
+}
+
+// SearchUsers returns the users assignable to issues in projectKey whose name
+// matches query. Jira Server and Data Center don't have the v3 API, so a 404
+// falls back to the v2 endpoint, which takes the search string as username.
+func (c *Client) SearchUsers(ctx context.Context, query, projectKey string) ([]User, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("project", projectKey)
+	params.Set("maxResults", "100")
+
+	var users []User
+	err := c.getJSON(ctx, "/rest/api/3/user/assignable/search", params, &users)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		params.Del("query")
+		params.Set("username", query)
+		users = nil
+		err = c.getJSON(ctx, "/rest/api/2/user/assignable/search", params, &users)
+	}
+	return users, err
+}
@@ -0,0 +1,73 @@
+package jira
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricsNamespace prefixes the plugin's Prometheus metrics.
+const metricsNamespace = "grafana_jira_datasource"
+
+// The collectors are registered with the default registry, which the plugin SDK
+// exposes through Grafana's plugin metrics endpoint. Labels never carry issue
+// keys, project keys or JQL so that their cardinality stays bounded.
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "jira_requests_total",
+		Help:      "Requests sent to Jira by endpoint and response status.",
+	}, []string{"endpoint", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "jira_request_duration_seconds",
+		Help:      "Latency of requests sent to Jira by endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	searchPages = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "jira_search_pages",
+		Help:      "Pages fetched per search.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+	})
+
+	cacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "cache_requests_total",
+		Help:      "Requests answered by the response cache, by result (hit, miss, not_modified).",
+	}, []string{"result"})
+)
+
+// observeRequest records a request to Jira. status is 0 when no response was received.
+func observeRequest(path string, status int, start time.Time) {
+	endpoint := endpointLabel(path)
+	statusLabel := "error"
+	if status != 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	requestsTotal.WithLabelValues(endpoint, statusLabel).Inc()
+	requestDuration.WithLabelValues(endpoint).Observe(time.Since(start).Seconds())
+}
+
+// endpointLabel replaces the path segments naming a project, issue, sprint or
+// board with a placeholder, e.g. "/rest/api/3/project/{key}/components" or
+// "/rest/agile/1.0/sprint/{id}".
+func endpointLabel(path string) string {
+	segments := strings.Split(path, "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i-1] {
+		case "project", "issue":
+			if segments[i] != "search" && segments[i] != "bulkfetch" {
+				segments[i] = "{key}"
+			}
+		case "sprint", "board":
+			segments[i] = "{id}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,86 @@
+package jira
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+// Categories of network errors.
+const (
+	NetworkDNS     = "DNS lookup failed"
+	NetworkRefused = "connection refused"
+	NetworkTimeout = "connection timed out"
+	NetworkTLS     = "TLS handshake failed"
+	NetworkOther   = "connection failed"
+)
+
+// NetworkError is a request that didn't get any response from Jira. Its message
+// starts with what went wrong in plain words, followed by the underlying error,
+// which Err keeps for logs.
+type NetworkError struct {
+	Host     string
+	Category string
+	Err      error
+}
+
+func (e *NetworkError) Error() string {
+	return fmt.Sprintf("Cannot reach Jira at %s: %s (%v)", e.Host, e.Category, e.Err)
+}
+
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the request timed out.
+func (e *NetworkError) Timeout() bool {
+	return e.Category == NetworkTimeout
+}
+
+// networkCategory classifies the error of a request that got no response.
+func networkCategory(err error) string {
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return NetworkDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return NetworkRefused
+	case errors.As(err, &recordErr), errors.As(err, &verifyErr), errors.As(err, &authorityErr),
+		errors.As(err, &hostnameErr), errors.As(err, &invalidErr):
+		return NetworkTLS
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return NetworkTimeout
+	default:
+		return NetworkOther
+	}
+}
+
+// wrapNetworkError turns the error of a request to reqURL that got no response
+// into a NetworkError. Cancelled requests are returned as they are.
+func wrapNetworkError(ctx context.Context, reqURL string, err error) error {
+	if ctx.Err() != nil {
+		return err
+	}
+	host := reqURL
+	if u, parseErr := url.Parse(reqURL); parseErr == nil && u.Host != "" {
+		host = u.Host
+	}
+	cause := err
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		// The url.Error repeats the method and URL, the host is enough.
+		cause = urlErr.Err
+	}
+	return &NetworkError{Host: host, Category: networkCategory(err), Err: cause}
+}
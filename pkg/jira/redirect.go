@@ -0,0 +1,86 @@
+package jira
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// maxRedirects is how many redirects a request follows, enough for a moved
+// instance or an added trailing slash without hiding a misconfigured URL.
+const maxRedirects = 2
+
+// RedirectError is a redirect the client refused to follow.
+type RedirectError struct {
+	URL    string
+	Reason string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("Jira redirected to %s, which was not followed because %s; update the datasource URL", e.URL, e.Reason)
+}
+
+type redirectRecorder struct {
+	mu      sync.Mutex
+	movedTo string
+}
+
+func (r *redirectRecorder) record(movedTo string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.movedTo = movedTo
+}
+
+// MovedTo returns where Jira permanently redirected the client to last, as the
+// base URL to configure instead when it can tell, empty if it never did.
+func (c *Client) MovedTo() string {
+	c.redirects.mu.Lock()
+	defer c.redirects.mu.Unlock()
+	return c.redirects.movedTo
+}
+
+// checkRedirect is the redirect policy of the client. It follows at most
+// maxRedirects redirects and never one that would send the credentials to
+// another host or over plain HTTP, or drop the body of the request by turning
+// it into a GET. Permanent redirects are recorded for the health check, since
+// following them silently leaves the datasource pointing at the old URL.
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	first := via[0]
+	if req.Response != nil && (req.Response.StatusCode == http.StatusMovedPermanently || req.Response.StatusCode == http.StatusPermanentRedirect) {
+		movedTo := c.movedBaseURL(first.URL, req.URL)
+		log.DefaultLogger.Warn("Jira permanently redirected, the datasource URL should be updated", "url", c.baseURL, "movedTo", movedTo)
+		c.redirects.record(movedTo)
+	}
+
+	credentials := first.Header.Get("Authorization") != ""
+	switch {
+	case len(via) > maxRedirects:
+		return &RedirectError{URL: req.URL.String(), Reason: fmt.Sprintf("it was redirected %d times already", len(via))}
+	case credentials && !strings.EqualFold(req.URL.Host, first.URL.Host):
+		return &RedirectError{URL: req.URL.String(), Reason: "it is another host and the request carries credentials"}
+	case credentials && first.URL.Scheme == "https" && req.URL.Scheme != "https":
+		return &RedirectError{URL: req.URL.String(), Reason: "it is not HTTPS and the request carries credentials"}
+	case req.Method != first.Method:
+		return &RedirectError{URL: req.URL.String(), Reason: fmt.Sprintf("it turns the %s request into a %s without its body", first.Method, req.Method)}
+	}
+	return nil
+}
+
+// movedBaseURL returns the base URL that from moved to: target without the API
+// path of the request, or its origin if the path changed too.
+func (c *Client) movedBaseURL(from, target *url.URL) string {
+	origin := target.Scheme + "://" + target.Host
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return origin
+	}
+	apiPath := strings.TrimPrefix(from.Path, base.Path)
+	if apiPath == "" || !strings.HasSuffix(target.Path, apiPath) {
+		return origin
+	}
+	return origin + strings.TrimSuffix(target.Path, apiPath)
+}
@@ -0,0 +1,33 @@
+package jira
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// JQLHash identifies a JQL query in traces without recording the query itself.
+func JQLHash(jql string) string {
+	sum := sha256.Sum256([]byte(jql))
+	return hex.EncodeToString(sum[:8])
+}
+
+// SpanError marks the span as failed. Request URLs are stripped from the message
+// since they can contain search parameters.
+func SpanError(span trace.Span, err error) {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+func tracer() trace.Tracer {
+	return tracing.DefaultTracer()
+}
@@ -3,18 +3,117 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 )
 
 type PluginSettings struct {
-	URL      string                `json:"url"`
-	Username string                `json:"username"`
-	Secrets  *SecretPluginSettings `json:"-"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	// StoryPointsField is the custom field id holding story points, e.g. "customfield_10016".
+	StoryPointsField string `json:"storyPointsField"`
+	// CacheTTLSeconds is how long Jira responses are reused, 0 uses the default and
+	// -1 disables caching.
+	CacheTTLSeconds int `json:"cacheTTLSeconds"`
+	// DefaultTimezone is used for dashboards in the browser time zone, e.g. "Europe/Berlin".
+	DefaultTimezone string `json:"defaultTimezone"`
+	// Holidays are dates ("2024-12-25") that don't count as business days.
+	Holidays []string `json:"holidays"`
+	// WorkingHours limits business days to e.g. "09:00-17:00" for ages in working hours.
+	WorkingHours string `json:"workingHours"`
+	// DefectTypes are the issue types the defect ratio counts as defects, ["Bug"] if empty.
+	DefectTypes []string `json:"defectTypes"`
+	// AnonymizeUsers replaces user names in frames with a hash salted with the
+	// anonymizeSalt from secureJsonData.
+	AnonymizeUsers bool `json:"anonymizeUsers"`
+	// MaxSearchPages aborts searches after this many pages, 0 uses the client default.
+	MaxSearchPages int `json:"maxSearchPages"`
+	// MaxTimeRangeDays rejects queries over longer time ranges before they reach
+	// Jira, 0 allows any range.
+	MaxTimeRangeDays int `json:"maxTimeRangeDays"`
+	// SplitSearchThreshold splits searches whose approximate result count exceeds
+	// it into searches over consecutive parts of the time range, 0 disables it.
+	SplitSearchThreshold int `json:"splitSearchThreshold"`
+	// MaxSearchSplits caps the parts of a split search, 0 uses the default.
+	MaxSearchSplits int `json:"maxSearchSplits"`
+	// MaxTextLength trims summaries and other free text in frames to this many
+	// characters, 0 uses the default and -1 disables trimming.
+	MaxTextLength int `json:"maxTextLength"`
+	// MetadataSnapshots keeps metadata like statuses and projects in Grafana's data
+	// directory across plugin restarts.
+	MetadataSnapshots bool `json:"metadataSnapshots"`
+	// ChangelogDepth is the changelogDepth of queries that don't set one,
+	// "truncated" if empty.
+	ChangelogDepth string `json:"changelogDepth"`
+	// Language is sent as Accept-Language with every request, so that localized
+	// Jira instances answer with the status names dashboards are written with.
+	// Empty uses "en".
+	Language string `json:"language"`
+	// CustomHeaders are sent with every request to Jira, e.g. for an auth proxy in
+	// front of it. They are configured like in Grafana's core datasources: the
+	// names as jsonData httpHeaderName1..n and the values as secureJsonData
+	// httpHeaderValue1..n.
+	CustomHeaders map[string]string     `json:"-"`
+	Secrets       *SecretPluginSettings `json:"-"`
+	// LegacyKeys are the keys of an early plugin version the settings were
+	// loaded from, see migrateLegacyKeys.
+	LegacyKeys []string `json:"-"`
 }
 
+// defaultCacheTTL is used when the datasource doesn't configure cacheTTLSeconds.
+const defaultCacheTTL = 30 * time.Second
+
+// CacheTTL returns how long Jira responses are reused, 0 when caching is disabled.
+func (s *PluginSettings) CacheTTL() time.Duration {
+	switch {
+	case s.CacheTTLSeconds < 0:
+		return 0
+	case s.CacheTTLSeconds == 0:
+		return defaultCacheTTL
+	default:
+		return time.Duration(s.CacheTTLSeconds) * time.Second
+	}
+}
+
+// defaultMaxTextLength is used when the datasource doesn't configure maxTextLength.
+const defaultMaxTextLength = 200
+
+// TextLength returns the number of characters free text is trimmed to, 0 when
+// trimming is disabled.
+func (s *PluginSettings) TextLength() int {
+	switch {
+	case s.MaxTextLength < 0:
+		return 0
+	case s.MaxTextLength == 0:
+		return defaultMaxTextLength
+	default:
+		return s.MaxTextLength
+	}
+}
+
+// defaultLanguage is used when the datasource doesn't configure language.
+const defaultLanguage = "en"
+
+// AcceptLanguage returns the Accept-Language header of requests to Jira.
+func (s *PluginSettings) AcceptLanguage() string {
+	if s.Language == "" {
+		return defaultLanguage
+	}
+	return s.Language
+}
+
+// reservedHeaders can't be overridden by custom headers since the client sets them itself.
+var reservedHeaders = []string{"Authorization", "Content-Type"}
+
 type SecretPluginSettings struct {
 	Token string `json:"token"`
+	// AnonymizeSalt keys the hash of anonymized user names.
+	AnonymizeSalt string `json:"anonymizeSalt"`
 }
 
 func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSettings, error) {
@@ -26,11 +125,96 @@ func LoadPluginSettings(source backend.DataSourceInstanceSettings) (*PluginSetti
 
 	settings.Secrets = loadSecretPluginSettings(source.DecryptedSecureJSONData)
 
+	settings.LegacyKeys, err = migrateLegacyKeys(&settings, source.JSONData, source.DecryptedSecureJSONData)
+	if err != nil {
+		return nil, err
+	}
+	warnLegacyKeys(source, settings.LegacyKeys)
+
+	settings.CustomHeaders, err = loadCustomHeaders(source.JSONData, source.DecryptedSecureJSONData)
+	if err != nil {
+		return nil, err
+	}
+
 	return &settings, nil
 }
 
+// Early versions of the plugin, built on Grafana's plugin template, stored the
+// Jira URL as "path" in jsonData and the API token as "apiKey" in
+// secureJsonData. Datasources that weren't saved since still have them.
+const (
+	legacyURLKey   = "path"
+	legacyTokenKey = "apiKey"
+)
+
+// migrateLegacyKeys fills in the settings that are only stored under their
+// legacy keys and returns those keys. The current keys win when both are set,
+// as after saving the datasource with a current version, which keeps the
+// legacy keys in jsonData.
+func migrateLegacyKeys(settings *PluginSettings, jsonData []byte, secureJSONData map[string]string) ([]string, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, fmt.Errorf("could not unmarshal PluginSettings json: %w", err)
+	}
+
+	var legacy []string
+	if path, _ := raw[legacyURLKey].(string); settings.URL == "" && path != "" {
+		settings.URL = path
+		legacy = append(legacy, legacyURLKey)
+	}
+	if apiKey := secureJSONData[legacyTokenKey]; settings.Secrets.Token == "" && apiKey != "" {
+		settings.Secrets.Token = apiKey
+		legacy = append(legacy, legacyTokenKey)
+	}
+	return legacy, nil
+}
+
+// legacyWarnings holds the uids of the datasources warned about legacy keys.
+var legacyWarnings sync.Map
+
+// warnLegacyKeys logs once per datasource that it uses legacy keys and should be
+// saved again. The health check reports them every time, see
+// PluginSettings.LegacyKeys.
+func warnLegacyKeys(source backend.DataSourceInstanceSettings, legacy []string) {
+	if len(legacy) == 0 {
+		return
+	}
+	if _, warned := legacyWarnings.LoadOrStore(source.UID, true); warned {
+		return
+	}
+	log.DefaultLogger.Warn("The datasource settings use keys of an early plugin version, save the datasource again to migrate them",
+		"datasource", source.Name, "uid", source.UID, "keys", strings.Join(legacy, ","))
+}
+
+func loadCustomHeaders(jsonData []byte, secureJSONData map[string]string) (map[string]string, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(jsonData, &raw); err != nil {
+		return nil, fmt.Errorf("could not unmarshal PluginSettings json: %w", err)
+	}
+
+	headers := map[string]string{}
+	for i := 1; ; i++ {
+		name, ok := raw[fmt.Sprintf("httpHeaderName%d", i)].(string)
+		if !ok {
+			break
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		for _, reserved := range reservedHeaders {
+			if http.CanonicalHeaderKey(name) == reserved {
+				return nil, fmt.Errorf("custom header %q is not allowed, it is set by the plugin", name)
+			}
+		}
+		headers[name] = secureJSONData[fmt.Sprintf("httpHeaderValue%d", i)]
+	}
+	return headers, nil
+}
+
 func loadSecretPluginSettings(source map[string]string) *SecretPluginSettings {
 	return &SecretPluginSettings{
-		Token: source["token"],
+		Token:         source["token"],
+		AnonymizeSalt: source["anonymizeSalt"],
 	}
 }
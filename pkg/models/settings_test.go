@@ -0,0 +1,76 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestLoadPluginSettingsCustomHeaders(t *testing.T) {
+	settings, err := LoadPluginSettings(backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"url":"https://jira","httpHeaderName1":"X-Forwarded-Client","httpHeaderName2":"X-Team"}`),
+		DecryptedSecureJSONData: map[string]string{"token": "t", "httpHeaderValue1": "secret", "httpHeaderValue2": "core"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.CustomHeaders["X-Forwarded-Client"] != "secret" || settings.CustomHeaders["X-Team"] != "core" {
+		t.Errorf("unexpected custom headers %v", settings.CustomHeaders)
+	}
+
+	for _, name := range []string{"Authorization", "content-type"} {
+		_, err := LoadPluginSettings(backend.DataSourceInstanceSettings{
+			JSONData: []byte(`{"httpHeaderName1":"` + name + `"}`),
+		})
+		if err == nil {
+			t.Errorf("expected %s to be rejected as a custom header", name)
+		}
+	}
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	for jsonData, want := range map[string]string{`{}`: "en", `{"language":"de-DE"}`: "de-DE"} {
+		settings, err := LoadPluginSettings(backend.DataSourceInstanceSettings{JSONData: []byte(jsonData)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := settings.AcceptLanguage(); got != want {
+			t.Errorf("%s: expected %q, got %q", jsonData, want, got)
+		}
+	}
+}
+
+func TestLoadPluginSettingsLegacyKeys(t *testing.T) {
+	tests := []struct {
+		name      string
+		jsonData  string
+		secure    map[string]string
+		wantURL   string
+		wantToken string
+		legacy    bool
+	}{
+		{"legacy", `{"path":"https://old.example.com","username":"user"}`, map[string]string{"apiKey": "old-token"}, "https://old.example.com", "old-token", true},
+		{"legacy url only", `{"path":"https://old.example.com"}`, map[string]string{"token": "token"}, "https://old.example.com", "token", true},
+		// Saving with a current version keeps the legacy keys next to the current ones.
+		{"saved again", `{"path":"https://old.example.com","url":"https://jira.example.com"}`, map[string]string{"apiKey": "old-token", "token": "token"}, "https://jira.example.com", "token", false},
+		{"current", `{"url":"https://jira.example.com"}`, map[string]string{"token": "token"}, "https://jira.example.com", "token", false},
+	}
+	for i, tt := range tests {
+		source := backend.DataSourceInstanceSettings{UID: fmt.Sprintf("legacy-%d", i), JSONData: []byte(tt.jsonData), DecryptedSecureJSONData: tt.secure}
+		settings, err := LoadPluginSettings(source)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if settings.URL != tt.wantURL || settings.Secrets.Token != tt.wantToken {
+			t.Errorf("%s: expected %s with %s, got %s with %s", tt.name, tt.wantURL, tt.wantToken, settings.URL, settings.Secrets.Token)
+		}
+		if (len(settings.LegacyKeys) > 0) != tt.legacy {
+			t.Errorf("%s: expected legacy keys %v, got %v", tt.name, tt.legacy, settings.LegacyKeys)
+		}
+		// Only datasources with legacy keys are warned about.
+		if _, warned := legacyWarnings.Load(source.UID); warned != tt.legacy {
+			t.Errorf("%s: expected a warning %v, got %v", tt.name, tt.legacy, warned)
+		}
+	}
+}
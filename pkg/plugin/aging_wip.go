@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// getAgingWipData lists the issues that are between the start and end statuses
+// at the end of the time range (or now, if that is earlier), with their current
+// status and how long ago they were started. The age is measured in the query's
+// AgeUnit, like cycle time.
+func (d *Datasource) getAgingWipData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	asOf := timeRange.To
+	if now := time.Now(); now.Before(asOf) {
+		asOf = now
+	}
+	startStatuses := parseList(qm.StartStatus)
+	endStatuses := parseList(qm.EndStatus)
+
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Project", nil, []*string{}),
+		data.NewField("Status", nil, []*string{}),
+		data.NewField("Started", nil, []time.Time{}),
+		data.NewField("Age", nil, []float64{}),
+	)
+
+	for _, issue := range issues {
+		var started *time.Time
+		for _, p := range wipPeriods(issue, startStatuses, endStatuses) {
+			if inProgressAt([]wipPeriod{p}, asOf) {
+				enter := p.enter
+				started = &enter
+			}
+		}
+		if started == nil {
+			continue
+		}
+
+		status := ""
+		for _, change := range statusChanges(issue) {
+			if !change.at.After(asOf) {
+				status = change.to
+			}
+		}
+		frame.AppendRow(
+			issue.Key,
+			optionalString(issueTypeName(issue)),
+			optionalString(projectKey(issue)),
+			optionalString(status),
+			*started,
+			qm.age(*started, asOf),
+		)
+	}
+
+	unit := qm.AgeUnit
+	if unit == "" {
+		unit = ageCalendarDays
+	}
+	setCustomMeta(frame, "ageUnit", unit)
+
+	if err := sortFrame(frame, qm, "Age", sortDesc, "IssueKey"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// defaultAnnotationStatus is the end status annotations mark when none is configured.
+const defaultAnnotationStatus = "Done"
+
+// getAnnotationsData emits an annotation for every transition into one of the end
+// statuses (default "Done") within the time range, titled with the issue key and
+// tagged with its project, issue type and labels. With annotationRegions, each
+// annotation spans from the latest preceding transition into a start status to the
+// end transition; ends without such a transition stay point annotations. The title
+// links to the issue in Jira.
+func (d *Datasource) getAnnotationsData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange, jiraURL string) backend.DataResponse {
+	var response backend.DataResponse
+
+	endStatuses := parseList(qm.EndStatus)
+	if len(endStatuses) == 0 {
+		endStatuses = []string{defaultAnnotationStatus}
+	}
+	startStatuses := parseList(qm.StartStatus)
+
+	title := data.NewField("title", nil, []string{})
+	title.Config = &data.FieldConfig{
+		Links: []data.DataLink{{
+			Title:       "Open in Jira",
+			URL:         issueURL(jiraURL, "${__value.raw}"),
+			TargetBlank: true,
+		}},
+	}
+	frame := data.NewFrame("response",
+		data.NewField("time", nil, []time.Time{}),
+		title,
+		data.NewField("text", nil, []string{}),
+		data.NewField("tags", nil, []string{}),
+	)
+	if qm.AnnotationRegions {
+		frame.Fields = append(frame.Fields, data.NewField("timeEnd", nil, []time.Time{}))
+	}
+
+	for _, issue := range issues {
+		summary, _ := issue.Fields["summary"].(string)
+		summary = truncateText(summary, qm.textLength)
+		tags := strings.Join(issueTags(issue), ",")
+
+		var started *time.Time
+		for _, change := range statusChanges(issue) {
+			if listHas(startStatuses, change.to) {
+				at := change.at
+				started = &at
+				continue
+			}
+			if !listHas(endStatuses, change.to) {
+				continue
+			}
+			if change.at.Before(timeRange.From) || change.at.After(timeRange.To) {
+				continue
+			}
+
+			if !qm.AnnotationRegions {
+				frame.AppendRow(change.at, issue.Key, summary, tags)
+				continue
+			}
+			start := change.at
+			if started != nil {
+				start = *started
+			}
+			frame.AppendRow(start, issue.Key, summary, tags, change.at)
+			started = nil
+		}
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
+
+// issueTags returns the project key, issue type and labels of the issue.
+func issueTags(issue jira.Issue) []string {
+	var tags []string
+	for _, tag := range []string{projectKey(issue), issueTypeName(issue)} {
+		if tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return append(tags, issueLabels(issue)...)
+}
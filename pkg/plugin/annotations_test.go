@@ -0,0 +1,41 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestAnnotationsData(t *testing.T) {
+	issue := newTestIssue("T-1", "Bug",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Done"},
+	)
+	issue.Fields["summary"] = "Login fails"
+	issue.Fields["labels"] = []interface{}{"incident"}
+	// Reached Done before the range, not annotated.
+	old := newTestIssue("T-2", "Story", [3]string{"2023-12-01T10:00:00.000+0000", "To Do", "Done"})
+
+	res := (&Datasource{}).getAnnotationsData([]jira.Issue{issue, old}, queryModel{}, testTimeRange(), "https://jira.example.com/")
+	frame := res.Frames[0]
+	if frame.Rows() != 1 {
+		t.Fatalf("expected 1 annotation, got %d", frame.Rows())
+	}
+	if at := frame.At(0, 0).(time.Time); at.Day() != 3 {
+		t.Errorf("expected the annotation at the Done transition, got %v", at)
+	}
+	if title, tags := frame.At(1, 0), frame.At(3, 0); title != "T-1" || tags != "TEST,Bug,incident" {
+		t.Errorf("unexpected title %v and tags %v", title, tags)
+	}
+	if link := frame.Fields[1].Config.Links[0].URL; link != "https://jira.example.com/browse/${__value.raw}" {
+		t.Errorf("unexpected link %q", link)
+	}
+
+	res = (&Datasource{}).getAnnotationsData([]jira.Issue{issue}, queryModel{StartStatus: "In Progress", AnnotationRegions: true}, testTimeRange(), "")
+	frame = res.Frames[0]
+	start, end := frame.At(0, 0).(time.Time), frame.At(4, 0).(time.Time)
+	if start.Day() != 2 || end.Day() != 3 {
+		t.Errorf("expected a region from the 2nd to the 3rd, got %v to %v", start, end)
+	}
+}
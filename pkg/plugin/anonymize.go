@@ -0,0 +1,36 @@
+package plugin
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// userFields are the changelog fields whose values name users.
+var userFields = []string{"assignee", "reporter", "creator"}
+
+// userAnonymizer replaces user names with a label derived from a keyed hash of
+// the name, e.g. "User-7f3a09c1". The same name always gets the same label for
+// the same salt, so that grouping by user keeps working.
+type userAnonymizer struct {
+	salt []byte
+}
+
+// label returns the anonymous label of name, "" for no user.
+func (a *userAnonymizer) label(name string) string {
+	if name == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(name))
+	return "User-" + hex.EncodeToString(mac.Sum(nil))[:8]
+}
+
+// userName returns the name of a user as it may appear in frames: anonymized
+// when the datasource has anonymizeUsers enabled.
+func (qm queryModel) userName(name string) string {
+	if qm.anonymizer == nil {
+		return name
+	}
+	return qm.anonymizer.label(name)
+}
@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestAnonymizeChangelog(t *testing.T) {
+	ds := &Datasource{}
+	issue := newTestIssue("T-1", "Story", [3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"})
+	issue.Changelog.Histories = append(issue.Changelog.Histories, jira.History{
+		Created: "2024-01-02T11:00:00.000+0000",
+		Items:   []jira.Item{{Field: "assignee", FromString: "Alice Example", ToString: "Bob Example"}},
+	})
+	qm := queryModel{anonymizer: &userAnonymizer{salt: []byte("secret")}}
+
+	frame := ds.getChangelogRawData([]jira.Issue{issue}, qm).Frames[0]
+	from, _ := frame.FieldByName("fromValue")
+	to, _ := frame.FieldByName("toValue")
+	if v, _ := from.ConcreteAt(0); v != "To Do" {
+		t.Errorf("expected status values to be kept, got %v", v)
+	}
+	alice, _ := from.ConcreteAt(1)
+	if alice != qm.userName("Alice Example") || !strings.HasPrefix(alice.(string), "User-") {
+		t.Errorf("expected a stable anonymous label, got %v", alice)
+	}
+	if bob, _ := to.ConcreteAt(1); bob == alice || strings.Contains(bob.(string), "Bob") {
+		t.Errorf("expected a distinct anonymous label, got %v", bob)
+	}
+
+	other := queryModel{anonymizer: &userAnonymizer{salt: []byte("other")}}
+	if other.userName("Alice Example") == alice {
+		t.Error("expected the label to depend on the salt")
+	}
+	if (queryModel{}).userName("Alice Example") != "Alice Example" {
+		t.Error("expected names to be kept without anonymizer")
+	}
+}
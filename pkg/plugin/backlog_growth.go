@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// getBacklogGrowthData emits, per bucket, how many issues were created and
+// resolved (entered an end status) and the running backlog size: cumulative
+// created minus cumulative resolved. The backlog starts at zero, or at the number
+// of issues that were still open at the range start when qm.SeedFromCount is set.
+func (d *Datasource) getBacklogGrowthData(ctx context.Context, client *jira.Client, issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	size, err := bucketSize(qm, timeRange)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	buckets := bucketStarts(timeRange, size, qm.loc())
+	created := make([]int64, len(buckets))
+	resolved := make([]int64, len(buckets))
+
+	endStatuses := parseList(qm.EndStatus)
+
+	for _, issue := range issues {
+		createdRaw, _ := issue.Fields["created"].(string)
+		if createdTime, err := parseJiraTime(createdRaw); err == nil {
+			if i, ok := bucketIndex(timeRange, buckets, createdTime); ok {
+				created[i]++
+			}
+		}
+
+		// Count each issue once, at its latest transition into an end status.
+		var resolvedAt time.Time
+		for _, change := range statusChanges(issue) {
+			if listHas(endStatuses, change.to) && !change.at.Before(timeRange.From) && !change.at.After(timeRange.To) {
+				resolvedAt = change.at
+			}
+		}
+		if i, ok := bucketIndex(timeRange, buckets, resolvedAt); ok {
+			resolved[i]++
+		}
+	}
+
+	var seed int64
+	var seedNotice *data.Notice
+	if qm.SeedFromCount && qm.JQLQuery != "" {
+		from := jira.QuoteJQL(timeRange.From.In(qm.loc()).Format(jqlTimeLayout))
+		// The resolution date approximates "entered an end status" here, since the
+		// count endpoint can't look at changelogs.
+		clause := fmt.Sprintf("created < %s AND (resolved is EMPTY OR resolved >= %s)", from, from)
+		for _, created := range createdClauses(qm) {
+			clause += " AND " + created
+		}
+		seedJQL, notice := addFilter(qm.JQLQuery, clause, "to count the open backlog at the range start")
+		seedNotice = &notice
+		count, err := client.CountIssues(ctx, seedJQL)
+		if err != nil {
+			return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("jira count failed: %v", err.Error()))
+		}
+		seed = int64(count)
+	}
+
+	backlog := make([]int64, len(buckets))
+	running := seed
+	for i := range buckets {
+		running += created[i] - resolved[i]
+		backlog[i] = running
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Time", nil, buckets),
+		data.NewField("Created", nil, created),
+		data.NewField("Resolved", nil, resolved),
+		data.NewField("Backlog", nil, backlog),
+	)
+	frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesWide})
+	setBucketInterval(frame, size)
+	if seedNotice != nil {
+		frame.AppendNotices(*seedNotice)
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestBacklogGrowthSeedFromCount(t *testing.T) {
+	var countedJQL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/search/approximate-count" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		var body struct {
+			JQL string `json:"jql"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		countedJQL = body.JQL
+		fmt.Fprint(w, `{"count":5}`)
+	}))
+	defer server.Close()
+	client := jira.NewClient(server.URL, "user", "token", "")
+
+	created := func(issue jira.Issue, at string) jira.Issue {
+		issue.Fields["created"] = at
+		return issue
+	}
+	issues := []jira.Issue{
+		created(newTestIssue("A-1", "Story", [3]string{"2024-01-10T10:00:00.000+0000", "To Do", "Done"}), "2024-01-02T10:00:00.000+0000"),
+		created(newTestIssue("A-2", "Story"), "2024-01-16T10:00:00.000+0000"),
+		// Open at the range start, and so part of the seed.
+		created(newTestIssue("A-3", "Story", [3]string{"2024-01-23T10:00:00.000+0000", "To Do", "Done"}), "2023-12-01T10:00:00.000+0000"),
+	}
+	qm := queryModel{JQLQuery: "project = A", EndStatus: "Done", Interval: "1w", SeedFromCount: true}
+
+	res := (&Datasource{}).getBacklogGrowthData(context.Background(), client, issues, qm, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if !strings.Contains(countedJQL, "created < '2024-01-01 00:00' AND (resolved is EMPTY OR resolved >= '2024-01-01 00:00')") {
+		t.Errorf("expected the issues open at the range start to be counted, got %s", countedJQL)
+	}
+
+	frame := res.Frames[0]
+	want := map[string][]int64{
+		"Created":  {1, 0, 1, 0, 0},
+		"Resolved": {0, 1, 0, 1, 0},
+		"Backlog":  {6, 5, 6, 5, 5},
+	}
+	for name, values := range want {
+		field, _ := frame.FieldByName(name)
+		if field == nil || field.Len() != len(values) {
+			t.Fatalf("expected %d weekly %s buckets, got %v", len(values), name, field)
+		}
+		for i, v := range values {
+			if got := field.At(i).(int64); got != v {
+				t.Errorf("%s bucket %d: expected %d, got %d", name, i, v, got)
+			}
+		}
+	}
+
+	// The count runs with the query's context.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := (&Datasource{}).getBacklogGrowthData(ctx, client, issues, qm, testTimeRange()); res.Error == nil {
+		t.Error("expected the count to fail with a canceled context")
+	}
+}
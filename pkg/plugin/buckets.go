@@ -0,0 +1,156 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const defaultBucketSize = 24 * time.Hour
+
+// minBucketSize is the smallest interval accepted.
+const minBucketSize = time.Minute
+
+// maxBuckets is the most buckets a time series may have, so that a small
+// interval over a long time range can't exhaust the memory of the plugin.
+const maxBuckets = 10000
+
+// parseInterval parses a bucket size such as "12h", "1d" or "2w". Besides the
+// units understood by time.ParseDuration, "d" (days) and "w" (weeks) are accepted.
+// Intervals shorter than minBucketSize are rejected.
+func parseInterval(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count <= 0 {
+				return 0, fmt.Errorf("invalid interval: %s", value)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid interval: %s", value)
+	}
+	if d < minBucketSize {
+		return 0, fmt.Errorf("interval %s is shorter than %s", value, minBucketSize)
+	}
+	return d, nil
+}
+
+// autoBucketSizes are the bucket sizes picked when the query doesn't set an
+// interval, from fine to coarse. Beyond the last one, whole weeks are used.
+var autoBucketSizes = []time.Duration{
+	time.Hour, 2 * time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour,
+	24 * time.Hour, 7 * 24 * time.Hour,
+}
+
+// bucketSize returns the bucket size for time series metrics: the query's own
+// interval option if set, rejected if it yields more than maxBuckets buckets over
+// the time range. Otherwise the smallest calendar unit that is at least the
+// interval Grafana suggests and doesn't yield more than maxDataPoints or
+// maxBuckets buckets, or one day when Grafana sent neither.
+func bucketSize(qm queryModel, timeRange backend.TimeRange) (time.Duration, error) {
+	if qm.Interval != "" {
+		size, err := parseInterval(qm.Interval)
+		if err != nil {
+			return 0, err
+		}
+		if n := timeRange.Duration() / size; n > maxBuckets {
+			return 0, fmt.Errorf("interval %s yields %d buckets over the time range, more than %d", qm.Interval, n, maxBuckets)
+		}
+		return size, nil
+	}
+	if qm.maxDataPoints <= 0 && qm.queryInterval <= 0 {
+		return defaultBucketSize, nil
+	}
+
+	minSize := max(qm.queryInterval, timeRange.Duration()/maxBuckets)
+	if qm.maxDataPoints > 0 {
+		minSize = max(minSize, timeRange.Duration()/time.Duration(qm.maxDataPoints))
+	}
+	for _, size := range autoBucketSizes {
+		if size >= minSize {
+			return size, nil
+		}
+	}
+	week := 7 * 24 * time.Hour
+	return (minSize + week - 1) / week * week, nil
+}
+
+// formatInterval renders a bucket size the way the interval option is written,
+// e.g. "6h", "1d" or "2w".
+func formatInterval(size time.Duration) string {
+	day := 24 * time.Hour
+	switch {
+	case size%(7*day) == 0:
+		return fmt.Sprintf("%dw", size/(7*day))
+	case size%day == 0:
+		return fmt.Sprintf("%dd", size/day)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(size.String(), "0s"), "0m")
+}
+
+// setBucketInterval documents the bucket size of a time series frame: in the
+// "interval" key of the custom meta, and as the interval of the time field,
+// which Grafana panels use to tell gaps from regular points.
+func setBucketInterval(frame *data.Frame, size time.Duration) {
+	setCustomMeta(frame, "interval", formatInterval(size))
+	for _, field := range frame.Fields {
+		if field.Type() == data.FieldTypeTime {
+			if field.Config == nil {
+				field.Config = &data.FieldConfig{}
+			}
+			field.Config.Interval = float64(size.Milliseconds())
+		}
+	}
+}
+
+// bucketStarts returns the start of every bucket of the given size covering the
+// time range. Buckets are aligned to the calendar in loc: buckets of whole days
+// start at midnight, buckets of whole weeks on Monday, and shorter buckets at
+// multiples of their size since midnight. The first bucket therefore can start
+// before timeRange.From.
+func bucketStarts(timeRange backend.TimeRange, size time.Duration, loc *time.Location) []time.Time {
+	from := timeRange.From.In(loc)
+	midnight := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+
+	var starts []time.Time
+	if size%(24*time.Hour) == 0 {
+		days := int(size / (24 * time.Hour))
+		start := midnight
+		if days%7 == 0 {
+			// Go counts weekdays from Sunday.
+			start = midnight.AddDate(0, 0, -(int(from.Weekday())+6)%7)
+		}
+		// AddDate keeps buckets on midnight across DST changes.
+		for t := start; t.Before(timeRange.To); t = t.AddDate(0, 0, days) {
+			starts = append(starts, t)
+		}
+		return starts
+	}
+
+	start := midnight.Add(from.Sub(midnight) / size * size)
+	for t := start; t.Before(timeRange.To); t = t.Add(size) {
+		starts = append(starts, t)
+	}
+	return starts
+}
+
+// bucketIndex returns the index of the bucket t falls into, or false when t is
+// outside the time range.
+func bucketIndex(timeRange backend.TimeRange, buckets []time.Time, t time.Time) (int, bool) {
+	if len(buckets) == 0 || t.Before(timeRange.From) || t.After(timeRange.To) {
+		return 0, false
+	}
+	// The first bucket starting after t is the one after t's bucket.
+	i := sort.Search(len(buckets), func(i int) bool { return buckets[i].After(t) })
+	return max(i-1, 0), true
+}
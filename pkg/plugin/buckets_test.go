@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketsInTimezone(t *testing.T) {
+	brisbane := time.FixedZone("AEST", 10*60*60)
+	timeRange := testTimeRange()
+
+	// Daily buckets start at midnight in Brisbane, 14:00 UTC the day before.
+	buckets := bucketStarts(timeRange, 24*time.Hour, brisbane)
+	if want := time.Date(2023, 12, 31, 14, 0, 0, 0, time.UTC); !buckets[0].Equal(want) {
+		t.Errorf("expected the first bucket at %v, got %v", want, buckets[0])
+	}
+
+	// 15:00 UTC on Jan 1st is already Jan 2nd in Brisbane.
+	event := time.Date(2024, 1, 1, 15, 0, 0, 0, time.UTC)
+	if i, ok := bucketIndex(timeRange, buckets, event); !ok || i != 1 {
+		t.Errorf("expected bucket 1 in Brisbane, got %d", i)
+	}
+	utcBuckets := bucketStarts(timeRange, 24*time.Hour, time.UTC)
+	if i, ok := bucketIndex(timeRange, utcBuckets, event); !ok || i != 0 {
+		t.Errorf("expected bucket 0 in UTC, got %d", i)
+	}
+}
+
+func TestWeeklyBucketsStartOnMonday(t *testing.T) {
+	// 2024-01-01 is a Monday in UTC but still Sunday in New York.
+	newYork := time.FixedZone("EST", -5*60*60)
+	buckets := bucketStarts(testTimeRange(), 7*24*time.Hour, newYork)
+	if want := time.Date(2023, 12, 25, 5, 0, 0, 0, time.UTC); !buckets[0].Equal(want) {
+		t.Errorf("expected the first bucket at %v, got %v", want, buckets[0])
+	}
+	if got := buckets[0].In(newYork).Weekday(); got != time.Monday {
+		t.Errorf("expected Monday, got %v", got)
+	}
+}
+
+func TestQueryLocation(t *testing.T) {
+	loc, err := queryLocation(queryModel{Timezone: "Australia/Sydney"}, nil)
+	if err != nil || loc.String() != "Australia/Sydney" {
+		t.Errorf("expected Australia/Sydney, got %v (%v)", loc, err)
+	}
+	if loc, _ := queryLocation(queryModel{Timezone: "browser"}, nil); loc != time.UTC {
+		t.Errorf("expected UTC for the browser time zone, got %v", loc)
+	}
+	if _, err := queryLocation(queryModel{Timezone: "Mars/Olympus"}, nil); err == nil {
+		t.Errorf("expected an error for an unknown time zone")
+	}
+}
+
+func TestAutoBucketSize(t *testing.T) {
+	month := testTimeRange() // 31 days
+	tests := []struct {
+		maxDataPoints int64
+		interval      time.Duration
+		want          string
+	}{
+		{0, 0, "1d"},
+		{1000, 0, "1h"},
+		{100, 0, "12h"},
+		{20, 0, "1w"},
+		{2, 0, "3w"},
+		{1000, 5 * time.Minute, "1h"},
+		{1000, 2 * time.Hour, "2h"},
+	}
+	for _, tt := range tests {
+		size, err := bucketSize(queryModel{maxDataPoints: tt.maxDataPoints, queryInterval: tt.interval}, month)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatInterval(size); got != tt.want {
+			t.Errorf("maxDataPoints %d, interval %v: expected %s, got %s", tt.maxDataPoints, tt.interval, tt.want, got)
+		}
+	}
+
+	if size, _ := bucketSize(queryModel{Interval: "6h", maxDataPoints: 2}, month); size != 6*time.Hour {
+		t.Errorf("expected the query's interval to win, got %v", size)
+	}
+}
+
+func TestBucketLimits(t *testing.T) {
+	month := testTimeRange() // 31 days
+	for _, interval := range []string{"1ns", "30s", "0.5m"} {
+		if _, err := bucketSize(queryModel{Interval: interval}, month); err == nil {
+			t.Errorf("%s: expected intervals shorter than a minute to be rejected", interval)
+		}
+	}
+	if size, err := bucketSize(queryModel{Interval: "1m"}, month); err == nil {
+		t.Errorf("expected 44640 one minute buckets to be rejected, got %v", size)
+	}
+	if size, err := bucketSize(queryModel{Interval: "5m"}, month); err != nil || size != 5*time.Minute {
+		t.Errorf("expected 8928 five minute buckets, got %v (%v)", size, err)
+	}
+
+	// Grafana's suggestions are raised to stay within the cap.
+	twoYears := month
+	twoYears.To = twoYears.From.AddDate(2, 0, 0)
+	size, err := bucketSize(queryModel{maxDataPoints: 1000000, queryInterval: time.Minute}, twoYears)
+	if err != nil || twoYears.Duration()/size > maxBuckets {
+		t.Errorf("expected at most %d automatic buckets, got %v (%v)", maxBuckets, size, err)
+	}
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// Units of the burndown metric.
+const (
+	burndownStoryPoints = "storyPoints"
+	burndownIssueCount  = "issueCount"
+)
+
+// defaultBurndownEndStatuses are the statuses that burn an issue down when the
+// query has no end status.
+var defaultBurndownEndStatuses = []string{"Done"}
+
+// statusAt returns the status of the issue at t, replaying its status changes
+// backwards from the current status.
+func statusAt(issue jira.Issue, changes []statusChange, t time.Time) string {
+	status := issueStatus(issue)
+	if len(changes) > 0 {
+		status = changes[0].from
+	}
+	for _, c := range changes {
+		if c.at.After(t) {
+			break
+		}
+		status = c.to
+	}
+	return status
+}
+
+// getBurndownData reconstructs the burndown of the sprint given as qm.Sprint,
+// see burndownFrame.
+func (d *Datasource) getBurndownData(ctx context.Context, client *jira.Client, issues []jira.Issue, qm queryModel) backend.DataResponse {
+	unit := qm.BurndownUnit
+	if unit == "" {
+		unit = burndownStoryPoints
+	}
+	switch unit {
+	case burndownStoryPoints:
+		if qm.storyPointsField == "" {
+			return backend.ErrDataResponse(backend.StatusBadRequest, "a burndown in story points requires the story points field in the datasource settings")
+		}
+	case burndownIssueCount:
+	default:
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unknown burndown unit: %s", qm.BurndownUnit))
+	}
+
+	sprint, members, err := fetchSprint(ctx, client, issues, qm)
+	if err != nil {
+		return queryErrorResponse(err)
+	}
+	return burndownFrame(issues, members, sprint, qm, unit, time.Now())
+}
+
+// burndownFrame emits the work remaining in the sprint at its start, at every
+// midnight while it runs and at its end (completion, or planned end while it is
+// active): the story points, or with unit issueCount the number, of the issues
+// that were in the sprint at that time and not in an end status. Issues that
+// were done before the sprint started, removed from it or added to it are
+// therefore only counted while they were in it and open. Ideal is the straight
+// line from the remaining work at the start to zero at the end. Remaining is
+// null after now.
+func burndownFrame(issues, members []jira.Issue, sprint *jira.Sprint, qm queryModel, unit string, now time.Time) backend.DataResponse {
+	var response backend.DataResponse
+
+	start, err := time.Parse(time.RFC3339, sprint.StartDate)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("invalid sprint start date: %s", sprint.StartDate))
+	}
+	end, err := time.Parse(time.RFC3339, sprint.CompleteDate)
+	if err != nil {
+		end, err = time.Parse(time.RFC3339, sprint.EndDate)
+		if err != nil {
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("sprint %s has no end date", sprint.Name))
+		}
+	}
+	start, end = start.In(qm.loc()), end.In(qm.loc())
+
+	times := []time.Time{start}
+	midnight := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, qm.loc())
+	for t := midnight.AddDate(0, 0, 1); t.Before(end); t = t.AddDate(0, 0, 1) {
+		times = append(times, t)
+	}
+	if end.After(start) {
+		times = append(times, end)
+	}
+
+	endStatuses := parseList(qm.EndStatus)
+	if len(endStatuses) == 0 {
+		endStatuses = defaultBurndownEndStatuses
+	}
+
+	all, isMember := sprintIssues(issues, members)
+	remaining := make([]float64, len(times))
+	for _, issue := range all {
+		weight := 1.0
+		if unit == burndownStoryPoints {
+			weight, _ = issue.Fields[qm.storyPointsField].(float64)
+		}
+		createdRaw, _ := issue.Fields["created"].(string)
+		created, createdErr := parseJiraTime(createdRaw)
+		membership := newSprintMembership(issue, sprint.ID, isMember[issue.Key])
+		changes := statusChanges(issue)
+
+		for i, t := range times {
+			if createdErr == nil && created.After(t) {
+				continue
+			}
+			if membership.at(t) && !listHas(endStatuses, statusAt(issue, changes, t)) {
+				remaining[i] += weight
+			}
+		}
+	}
+
+	remainingValues := make([]*float64, len(times))
+	ideal := make([]float64, len(times))
+	for i, t := range times {
+		if !t.After(now) {
+			remainingValues[i] = &remaining[i]
+		}
+		if span := end.Sub(start); span > 0 {
+			ideal[i] = remaining[0] * (1 - float64(t.Sub(start))/float64(span))
+		}
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Time", nil, times),
+		data.NewField("Remaining", nil, remainingValues),
+		data.NewField("Ideal", nil, ideal),
+	)
+	frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesWide})
+	setCustomMeta(frame, "sprint", sprint.Name)
+	setCustomMeta(frame, "unit", unit)
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// withStatusChanges appends status transitions given as (created, from, to).
+func withStatusChanges(issue jira.Issue, transitions ...[3]string) jira.Issue {
+	for _, t := range transitions {
+		issue.Changelog.Histories = append(issue.Changelog.Histories, jira.History{
+			Created: t[0],
+			Items:   []jira.Item{{Field: "status", FromString: t[1], ToString: t[2]}},
+		})
+	}
+	return issue
+}
+
+func TestBurndown(t *testing.T) {
+	sprint := &jira.Sprint{ID: 7, Name: "Sprint 7", StartDate: "2024-01-08T09:00:00.000Z", EndDate: "2024-01-12T17:00:00.000Z"}
+	created := "2024-01-01T10:00:00.000+0000"
+	planned := [3]string{"2024-01-05T10:00:00.000+0000", "", "7"}
+	members := []jira.Issue{
+		// Done on the 10th.
+		withStatusChanges(newTestSprintIssue("T-1", created, 3, planned),
+			[3]string{"2024-01-10T10:00:00.000+0000", "To Do", "Done"}),
+		// Done before the sprint started.
+		withStatusChanges(newTestSprintIssue("T-2", created, 8, planned),
+			[3]string{"2024-01-04T10:00:00.000+0000", "To Do", "Done"}),
+		// Added on the 9th.
+		newTestSprintIssue("T-3", created, 2, [3]string{"2024-01-09T10:00:00.000+0000", "", "7"}),
+	}
+	issues := []jira.Issue{
+		// Removed on the 11th.
+		newTestSprintIssue("T-4", created, 5, planned, [3]string{"2024-01-11T10:00:00.000+0000", "7", ""}),
+	}
+	for i := range members {
+		members[i].Fields["status"] = map[string]interface{}{"name": "To Do"}
+	}
+
+	qm := queryModel{storyPointsField: "customfield_10016"}
+	now := time.Date(2024, 1, 12, 12, 0, 0, 0, time.UTC)
+	res := burndownFrame(issues, members, sprint, qm, burndownStoryPoints, now)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	// The start, the 9th to the 12th at midnight and the end.
+	if frame.Rows() != 6 {
+		t.Fatalf("expected 6 points, got %d", frame.Rows())
+	}
+	for i, want := range []float64{8, 8, 10, 7, 2} {
+		if got := frame.Fields[1].At(i).(*float64); got == nil || *got != want {
+			t.Errorf("point %d: expected %v remaining, got %v", i, want, got)
+		}
+	}
+	if got := frame.Fields[1].At(5).(*float64); got != nil {
+		t.Errorf("expected nothing remaining after now, got %v", *got)
+	}
+	if first, last := frame.Fields[2].At(0), frame.Fields[2].At(5); first != 8.0 || last != 0.0 {
+		t.Errorf("expected the ideal line to go from 8 to 0, got %v to %v", first, last)
+	}
+
+	res = burndownFrame(issues, members, sprint, qm, burndownIssueCount, now)
+	if got := res.Frames[0].Fields[1].At(0).(*float64); *got != 2 {
+		t.Errorf("expected 2 open issues at the start, got %v", *got)
+	}
+}
@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+// Units ages and cycle times can be measured in, see queryModel.AgeUnit.
+const (
+	ageCalendarDays = "calendarDays"
+	ageBusinessDays = "businessDays"
+	ageWorkingHours = "workingHours"
+)
+
+func validateAgeUnit(unit string) error {
+	switch unit {
+	case "", ageCalendarDays, ageBusinessDays, ageWorkingHours:
+		return nil
+	}
+	return fmt.Errorf("unknown ageUnit %q, expected %s, %s or %s", unit, ageCalendarDays, ageBusinessDays, ageWorkingHours)
+}
+
+// usesBusinessCalendar reports whether the AgeUnit of the query is measured with
+// the business calendar of the datasource.
+func (qm queryModel) usesBusinessCalendar() bool {
+	return qm.AgeUnit == ageBusinessDays || qm.AgeUnit == ageWorkingHours
+}
+
+// businessCalendar tells working time apart from weekends, the holidays of the
+// datasource settings and, if configured, the time outside working hours.
+type businessCalendar struct {
+	loc      *time.Location
+	holidays map[string]bool
+	// dayStart and dayEnd are the working hours as offsets from midnight.
+	dayStart time.Duration
+	dayEnd   time.Duration
+}
+
+// newBusinessCalendar builds the calendar of the datasource in loc. Without
+// working hours, business days count in full.
+func newBusinessCalendar(config *models.PluginSettings, loc *time.Location) (*businessCalendar, error) {
+	cal := &businessCalendar{loc: loc, holidays: map[string]bool{}, dayEnd: 24 * time.Hour}
+	if config == nil {
+		return cal, nil
+	}
+
+	for _, holiday := range config.Holidays {
+		day, err := time.Parse("2006-01-02", strings.TrimSpace(holiday))
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q, expected YYYY-MM-DD", holiday)
+		}
+		cal.holidays[day.Format("2006-01-02")] = true
+	}
+
+	if config.WorkingHours != "" {
+		from, to, ok := strings.Cut(config.WorkingHours, "-")
+		start, errStart := time.Parse("15:04", strings.TrimSpace(from))
+		end, errEnd := time.Parse("15:04", strings.TrimSpace(to))
+		if !ok || errStart != nil || errEnd != nil || !start.Before(end) {
+			return nil, fmt.Errorf("invalid workingHours %q, expected e.g. 09:00-17:00", config.WorkingHours)
+		}
+		cal.dayStart = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+		cal.dayEnd = time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute
+	}
+	return cal, nil
+}
+
+// isBusinessDay reports whether the day starting at midnight day is neither on a
+// weekend nor a holiday.
+func (c *businessCalendar) isBusinessDay(day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	return !c.holidays[day.Format("2006-01-02")]
+}
+
+// days calls fn with the midnight of every day from the day of start to the day
+// of end in the calendar's time zone.
+func (c *businessCalendar) days(start, end time.Time, fn func(day time.Time)) {
+	start, end = start.In(c.loc), end.In(c.loc)
+	for day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, c.loc); !day.After(end); day = day.AddDate(0, 0, 1) {
+		fn(day)
+	}
+}
+
+// businessDays counts the business days from the day of start to the day of end,
+// both included, like cycleDays does for calendar days.
+func (c *businessCalendar) businessDays(start, end time.Time) float64 {
+	count := 0.0
+	c.days(start, end, func(day time.Time) {
+		if c.isBusinessDay(day) {
+			count++
+		}
+	})
+	return count
+}
+
+// workingHours sums the hours between start and end that fall into the working
+// hours of business days.
+func (c *businessCalendar) workingHours(start, end time.Time) float64 {
+	var total time.Duration
+	c.days(start, end, func(day time.Time) {
+		if !c.isBusinessDay(day) {
+			return
+		}
+		from, to := day.Add(c.dayStart), day.Add(c.dayEnd)
+		if start.After(from) {
+			from = start
+		}
+		if end.Before(to) {
+			to = end
+		}
+		if to.After(from) {
+			total += to.Sub(from)
+		}
+	})
+	return total.Hours()
+}
+
+// age measures the time from start to end in the AgeUnit of the query.
+func (qm queryModel) age(start, end time.Time) float64 {
+	cal := qm.calendar
+	if cal == nil {
+		cal, _ = newBusinessCalendar(nil, qm.loc())
+	}
+	switch qm.AgeUnit {
+	case ageBusinessDays:
+		return cal.businessDays(start, end)
+	case ageWorkingHours:
+		return cal.workingHours(start, end)
+	default:
+		return cycleDays(start, end)
+	}
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestBusinessCalendar(t *testing.T) {
+	cal, err := newBusinessCalendar(&models.PluginSettings{Holidays: []string{"2024-01-01"}, WorkingHours: "09:00-17:00"}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Friday 2023-12-29 15:00 to Tuesday 2024-01-02 10:00, over a weekend and a holiday.
+	start := time.Date(2023, 12, 29, 15, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	if days := cal.businessDays(start, end); days != 2 {
+		t.Errorf("expected 2 business days, got %v", days)
+	}
+	if hours := cal.workingHours(start, end); hours != 3 {
+		t.Errorf("expected 3 working hours, got %v", hours)
+	}
+
+	for _, config := range []models.PluginSettings{{Holidays: []string{"25.12.2024"}}, {WorkingHours: "17:00-09:00"}} {
+		if _, err := newBusinessCalendar(&config, time.UTC); err == nil {
+			t.Errorf("expected an error for %+v", config)
+		}
+	}
+}
+
+func TestInvalidCalendarSettings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"issues":[]}`)
+	}))
+	defer server.Close()
+	client := jira.NewClient(server.URL, "user", "token", "")
+	config := &models.PluginSettings{Holidays: []string{"25.12.2024"}, Secrets: &models.SecretPluginSettings{}}
+
+	// Only queries measured in business days or working hours need the calendar.
+	for unit, fails := range map[string]bool{"": false, ageCalendarDays: false, ageBusinessDays: true, ageWorkingHours: true} {
+		query := backend.DataQuery{
+			JSON:      []byte(fmt.Sprintf(`{"metric":"cycletime","jqlQuery":"project = A","startStatus":"In Progress","endStatus":"Done","ageUnit":%q}`, unit)),
+			TimeRange: testTimeRange(),
+		}
+		res := (&Datasource{}).query(context.Background(), client, config, query)
+		if (res.Error != nil) != fails || fails && res.Status != backend.StatusBadRequest {
+			t.Errorf("ageUnit %q: expected failure %v, got status %d (%v)", unit, fails, res.Status, res.Error)
+		}
+	}
+}
+
+func TestAgingWipData(t *testing.T) {
+	issues := []jira.Issue{
+		// Started on Friday and still in review at the end of the range.
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-26T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-29T10:00:00.000+0000", "In Progress", "Review"},
+		),
+		newTestIssue("T-2", "Story",
+			[3]string{"2024-01-26T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-27T10:00:00.000+0000", "In Progress", "Done"},
+		),
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", AgeUnit: ageBusinessDays}
+
+	res := (&Datasource{}).getAgingWipData(issues, qm, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 1 || frame.At(0, 0) != "T-1" {
+		t.Fatalf("expected only T-1 to be in progress, got %d rows", frame.Rows())
+	}
+	if status := frame.At(3, 0).(*string); *status != "Review" {
+		t.Errorf("expected the current status Review, got %s", *status)
+	}
+	// Friday 26th to Thursday 1st, without the weekend.
+	if age := frame.At(5, 0); age != 5.0 {
+		t.Errorf("expected an age of 5 business days, got %v", age)
+	}
+}
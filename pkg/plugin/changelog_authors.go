@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// Values of the authorMatch option.
+const (
+	authorMatchExact    = "exact"
+	authorMatchContains = "contains"
+)
+
+// validateAuthorMatch rejects unknown authorMatch values.
+func validateAuthorMatch(match string) error {
+	switch match {
+	case "", authorMatchExact, authorMatchContains:
+		return nil
+	default:
+		return fmt.Errorf("unknown authorMatch: %s", match)
+	}
+}
+
+// authorFilters returns the authors of the authorFilter option, nil when it
+// doesn't filter, e.g. for the $__all value of a multi-value variable.
+func authorFilters(qm queryModel) []string {
+	filters := parseList(qm.AuthorFilter)
+	if containsString(filters, allValues) {
+		return nil
+	}
+	return filters
+}
+
+// authorMatches reports whether the author of a change is one of filters: by
+// account id (or key and name on Server and Data Center) or display name, equal
+// ignoring case or, with authorMatch "contains", containing it. When the
+// datasource anonymizes users, only the anonymous label of the author matches,
+// so that the filter can't reveal who made changes.
+func (qm queryModel) authorMatches(author *jira.User, filters []string) bool {
+	if author == nil {
+		return false
+	}
+	names := []string{author.AccountID, author.Key, author.Name, author.DisplayName}
+	if qm.anonymizer != nil {
+		names = []string{qm.userName(author.DisplayName)}
+	}
+	for _, filter := range filters {
+		for _, name := range names {
+			if name == "" {
+				continue
+			}
+			if strings.EqualFold(name, filter) ||
+				qm.AuthorMatch == authorMatchContains && strings.Contains(strings.ToLower(name), strings.ToLower(filter)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterChangelogAuthors returns copies of the issues with only the changes made
+// by the authors of the authorFilter option, e.g. for audits of what a team
+// changed. Issues are returned as they are without a filter.
+func filterChangelogAuthors(issues []jira.Issue, qm queryModel) []jira.Issue {
+	filters := authorFilters(qm)
+	if len(filters) == 0 {
+		return issues
+	}
+	filtered := make([]jira.Issue, len(issues))
+	for i, issue := range issues {
+		filtered[i] = issue
+		if issue.Changelog == nil {
+			continue
+		}
+		changelog := *issue.Changelog
+		changelog.Histories = nil
+		for _, history := range issue.Changelog.Histories {
+			if qm.authorMatches(history.Author, filters) {
+				changelog.Histories = append(changelog.Histories, history)
+			}
+		}
+		filtered[i].Changelog = &changelog
+	}
+	return filtered
+}
+
+// authorName returns the display name of the author of a change as it may
+// appear in frames, nil for changes without one.
+func (qm queryModel) authorName(author *jira.User) *string {
+	if author == nil {
+		return nil
+	}
+	return optionalString(qm.userName(author.DisplayName))
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestChangelogAuthorFilter(t *testing.T) {
+	issue := newTestIssue("T-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Review"},
+		[3]string{"2024-01-04T10:00:00.000+0000", "Review", "Done"},
+	)
+	histories := issue.Changelog.Histories
+	histories[0].Author = &jira.User{AccountID: "acc-ana", DisplayName: "Ana Lima"}
+	histories[1].Author = &jira.User{AccountID: "acc-bo", DisplayName: "Bo Chen"}
+	// histories[2] was made by automation without an author.
+
+	ds := &Datasource{}
+	rows := func(qm queryModel) []string {
+		t.Helper()
+		qm.Metric = "changelogRaw"
+		res := ds.buildFrames(context.Background(), nil, &models.PluginSettings{}, qm, testTimeRange(), []jira.Issue{issue})
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		frame := res.Frames[0]
+		author, _ := frame.FieldByName("Author")
+		if (author != nil) != (len(authorFilters(qm)) > 0) {
+			t.Errorf("%q: expected the Author column only with a filter", qm.AuthorFilter)
+		}
+		var got []string
+		for i := 0; i < frame.Rows(); i++ {
+			to, _ := frame.Fields[5].ConcreteAt(i)
+			row := to.(string)
+			if author != nil {
+				name, _ := author.ConcreteAt(i)
+				row += " by " + name.(string)
+			}
+			got = append(got, row)
+		}
+		return got
+	}
+
+	tests := []struct {
+		qm   queryModel
+		want []string
+	}{
+		{queryModel{}, []string{"In Progress", "Review", "Done"}},
+		{queryModel{AuthorFilter: "acc-ana"}, []string{"In Progress by Ana Lima"}},
+		{queryModel{AuthorFilter: "bo chen"}, []string{"Review by Bo Chen"}},
+		{queryModel{AuthorFilter: "Chen"}, nil},
+		{queryModel{AuthorFilter: "Chen", AuthorMatch: authorMatchContains}, []string{"Review by Bo Chen"}},
+		// A multi-value variable for a team audit, and its "All" value.
+		{queryModel{AuthorFilter: "{acc-ana,acc-bo}"}, []string{"In Progress by Ana Lima", "Review by Bo Chen"}},
+		{queryModel{AuthorFilter: allValues}, []string{"In Progress", "Review", "Done"}},
+	}
+	for _, tt := range tests {
+		got := rows(tt.qm)
+		if len(got) != len(tt.want) {
+			t.Errorf("%q (%s): expected %v, got %v", tt.qm.AuthorFilter, tt.qm.AuthorMatch, tt.want, got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%q (%s): expected %v, got %v", tt.qm.AuthorFilter, tt.qm.AuthorMatch, tt.want, got)
+				break
+			}
+		}
+	}
+
+	// Filtering doesn't change the fetched issues, other queries may share them.
+	if len(issue.Changelog.Histories) != 3 {
+		t.Errorf("expected the issue to keep its changes, got %d", len(issue.Changelog.Histories))
+	}
+
+	// Anonymized authors only match by their label.
+	anonymizer := &userAnonymizer{salt: []byte("salt")}
+	label := anonymizer.label("Ana Lima")
+	qm := queryModel{anonymizer: anonymizer}
+	if qm.authorMatches(histories[0].Author, []string{"Ana Lima"}) || !qm.authorMatches(histories[0].Author, []string{label}) {
+		t.Errorf("expected only the label %s to match an anonymized author", label)
+	}
+
+	if err := (queryModel{AuthorMatch: "regex"}).validate(); err == nil {
+		t.Error("expected an unknown authorMatch to be rejected")
+	}
+}
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+// Values of the changelogDepth option.
+const (
+	// changelogTruncated uses the changelogs as the search returns them, which
+	// leaves out histories beyond the first 40 to 100 of an issue.
+	changelogTruncated = "truncated"
+	// changelogFull back-fills truncated changelogs from the changelog endpoint
+	// of every issue, at the cost of a request per page of 100 histories.
+	changelogFull = "full"
+)
+
+// validateChangelogDepth rejects unknown changelogDepth values of the option name.
+func validateChangelogDepth(name, depth string) error {
+	switch depth {
+	case "", changelogTruncated, changelogFull:
+		return nil
+	default:
+		return fmt.Errorf("unknown %s: %s", name, depth)
+	}
+}
+
+// resolveChangelogDepth returns the changelogDepth of the query, or else of the
+// datasource, "truncated" if neither sets one.
+func resolveChangelogDepth(qm queryModel, config *models.PluginSettings) (string, error) {
+	if qm.ChangelogDepth != "" {
+		return qm.ChangelogDepth, nil
+	}
+	if err := validateChangelogDepth("changelogDepth of the datasource", config.ChangelogDepth); err != nil {
+		return "", err
+	}
+	if config.ChangelogDepth != "" {
+		return config.ChangelogDepth, nil
+	}
+	return changelogTruncated, nil
+}
+
+// changelogBackfill is what the "changelogBackfill" key of the custom frame meta
+// reports: the changelog depth of the query, how many issues had a truncated
+// changelog, and with "full" how many of them were back-filled with how many
+// requests.
+type changelogBackfill struct {
+	Depth     string `json:"depth"`
+	Truncated int    `json:"truncated"`
+	jira.BackfillStats
+}
+
+// backfillChangelogs completes the truncated changelogs of the issues when the
+// query asks for the full changelogs. It returns nil for queries that don't
+// fetch changelogs. A failed back-fill returns its error along with what was
+// done, the issues keep the changelogs they have.
+func backfillChangelogs(ctx context.Context, client *jira.Client, qm queryModel, issues []jira.Issue) (*changelogBackfill, error) {
+	opts := searchOptions(qm)
+	if opts.SkipChangelog {
+		return nil, nil
+	}
+	backfill := &changelogBackfill{Depth: qm.changelogDepth}
+	for _, issue := range issues {
+		if issue.Changelog != nil && issue.Changelog.Truncated() {
+			backfill.Truncated++
+		}
+	}
+	if qm.changelogDepth != changelogFull || backfill.Truncated == 0 {
+		return backfill, nil
+	}
+	var err error
+	backfill.BackfillStats, err = client.BackfillChangelogs(ctx, issues, opts)
+	return backfill, err
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestChangelogDepth(t *testing.T) {
+	var changelogRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/3/issue/A-1/changelog":
+			changelogRequests++
+			fmt.Fprint(w, `{"startAt":0,"maxResults":100,"total":2,"isLast":true,"values":[
+				{"id":"1","created":"2024-01-02T10:00:00.000+0000","items":[{"field":"status","fromString":"To Do","toString":"In Progress"}]},
+				{"id":"2","created":"2024-01-05T10:00:00.000+0000","items":[{"field":"status","fromString":"In Progress","toString":"Done"}]}
+			]}`)
+		default:
+			// The search left out the start of the work on A-1.
+			fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{"summary":"Long","status":{"name":"Done"},"issuetype":{"name":"Story"},"project":{"key":"A"},"created":"2024-01-01T10:00:00.000+0000"},
+				"changelog":{"startAt":0,"maxResults":1,"total":2,"histories":[
+					{"id":"2","created":"2024-01-05T10:00:00.000+0000","items":[{"field":"status","fromString":"In Progress","toString":"Done"}]}
+				]}}],"isLast":true}`)
+		}
+	}))
+	defer server.Close()
+	client := jira.NewClient(server.URL, "user", "token", "")
+
+	for _, tc := range []struct {
+		name     string
+		depth    string
+		settings string
+		want     changelogBackfill
+		rows     int
+	}{
+		{name: "default", want: changelogBackfill{Depth: "truncated", Truncated: 1}},
+		{name: "query", depth: "full", want: changelogBackfill{Depth: "full", Truncated: 1, BackfillStats: jira.BackfillStats{Issues: 1, Requests: 1}}, rows: 1},
+		{name: "datasource", settings: "full", want: changelogBackfill{Depth: "full", Truncated: 1, BackfillStats: jira.BackfillStats{Issues: 1, Requests: 1}}, rows: 1},
+		{name: "query overrides datasource", depth: "truncated", settings: "full", want: changelogBackfill{Depth: "truncated", Truncated: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			changelogRequests = 0
+			query := backend.DataQuery{
+				RefID:     "A",
+				JSON:      []byte(fmt.Sprintf(`{"metric":"cycletime","jqlQuery":"project = A","startStatus":"In Progress","endStatus":"Done","changelogDepth":%q}`, tc.depth)),
+				TimeRange: testTimeRange(),
+			}
+			config := &models.PluginSettings{ChangelogDepth: tc.settings, Secrets: &models.SecretPluginSettings{}}
+			res := (&Datasource{}).query(context.Background(), client, config, query)
+			if res.Error != nil {
+				t.Fatal(res.Error)
+			}
+			frame := res.Frames[0]
+			got, ok := frame.Meta.Custom.(map[string]interface{})["changelogBackfill"].(*changelogBackfill)
+			if !ok || *got != tc.want {
+				t.Errorf("expected the back-fill %+v in the meta, got %+v", tc.want, got)
+			}
+			if changelogRequests != tc.want.Requests {
+				t.Errorf("expected %d changelog requests, got %d", tc.want.Requests, changelogRequests)
+			}
+			if frame.Rows() != tc.rows {
+				t.Errorf("expected %d cycle times, got %d", tc.rows, frame.Rows())
+			}
+		})
+	}
+
+	query := backend.DataQuery{RefID: "A", JSON: []byte(`{"metric":"cycletime","changelogDepth":"deep"}`), TimeRange: testTimeRange()}
+	if res := (&Datasource{}).query(context.Background(), client, &models.PluginSettings{Secrets: &models.SecretPluginSettings{}}, query); res.Error == nil || res.Status != backend.StatusBadRequest {
+		t.Errorf("expected an unknown changelogDepth to be rejected, got %v", res.Error)
+	}
+}
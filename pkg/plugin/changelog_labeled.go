@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// formatLabeled splits changelogRaw into one frame per changed field.
+const formatLabeled = "labeled"
+
+// labeledChangelogFrame returns an empty frame of the changes of one field.
+func labeledChangelogFrame(name string, labels data.Labels) *data.Frame {
+	return data.NewFrame(name,
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("Created", nil, []time.Time{}),
+		data.NewField("FromValue", labels, []*string{}),
+		data.NewField("ToValue", labels, []*string{}),
+	)
+}
+
+// getChangelogLabeledData emits the change log in long format with one frame
+// per changed field, ordered by field name. Frames are named after the field and
+// their value columns carry it as a "field" label, so per-field panels and
+// legends need no transformations. maxRows applies to each frame.
+func (d *Datasource) getChangelogLabeledData(issues []jira.Issue, qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	frames := map[string]*data.Frame{}
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+		for _, history := range issue.Changelog.Histories {
+			createdTime, err := parseJiraTime(history.Created)
+			if err != nil {
+				continue
+			}
+			for _, item := range history.Items {
+				frame, ok := frames[item.Field]
+				if !ok {
+					frame = labeledChangelogFrame(item.Field, data.Labels{"field": item.Field})
+					frames[item.Field] = frame
+				}
+				if containsString(userFields, item.Field) {
+					item.FromString, item.ToString = qm.userName(item.FromString), qm.userName(item.ToString)
+				}
+				frame.AppendRow(issue.Key, createdTime, optionalString(item.FromString), optionalString(item.ToString))
+			}
+		}
+	}
+
+	fields := make([]string, 0, len(frames))
+	for field := range frames {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if len(fields) == 0 {
+		// Keeps the schema without any changes.
+		response.Frames = append(response.Frames, labeledChangelogFrame("response", nil))
+		return response
+	}
+
+	limit := rowLimit(qm)
+	for _, field := range fields {
+		frame := frames[field]
+		if err := sortFrame(frame, qm, "", "", "IssueKey"); err != nil {
+			return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+		}
+		addTruncationNotice(frame, truncateFrame(frame, limit), limit)
+		response.Frames = append(response.Frames, frame)
+	}
+	return response
+}
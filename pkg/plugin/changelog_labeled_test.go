@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestChangelogLabeledData(t *testing.T) {
+	issue := newTestIssue("T-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+	)
+	issue.Changelog.Histories[0].Items = append(issue.Changelog.Histories[0].Items,
+		jira.Item{Field: "priority", FromString: "Low", ToString: "High"})
+
+	res := (&Datasource{}).getChangelogLabeledData([]jira.Issue{issue}, queryModel{})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	nameFrames(&res, "A", "changelogRaw")
+	if len(res.Frames) != 2 {
+		t.Fatalf("expected a frame per field, got %d", len(res.Frames))
+	}
+
+	for i, want := range []struct {
+		field string
+		rows  int
+	}{{"priority", 1}, {"status", 2}} {
+		frame := res.Frames[i]
+		if frame.Name != "A changelogRaw "+want.field || frame.Rows() != want.rows {
+			t.Errorf("expected the %s frame with %d rows, got %s with %d", want.field, want.rows, frame.Name, frame.Rows())
+		}
+		to, _ := frame.FieldByName("ToValue")
+		if to == nil || to.Labels["field"] != want.field {
+			t.Errorf("expected ToValue labelled with %s in %s", want.field, frame.Name)
+		}
+	}
+
+	status := res.Frames[1]
+	if to, _ := status.Fields[3].ConcreteAt(1); to != "Done" {
+		t.Errorf("expected the second status change to Done, got %v", to)
+	}
+}
@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+// newClient creates the Jira client for a request from the datasource settings.
+// It uses the connections of the instance, so that they are closed along with it.
+func (d *Datasource) newClient(config *models.PluginSettings, pluginContext backend.PluginContext) *jira.Client {
+	client := jira.NewClient(config.URL, config.Username, config.Secrets.Token, userAgent(pluginContext))
+	client.SetLanguage(config.AcceptLanguage())
+	client.SetCustomHeaders(config.CustomHeaders)
+	client.SetMaxPages(config.MaxSearchPages)
+	if d.httpClient != nil {
+		client.SetHTTPClient(d.httpClient)
+	}
+	return client
+}
+
+// cachedClient is newClient backed by the response cache of the instance. The
+// health check must not use it, it has to reach Jira every time.
+func (d *Datasource) cachedClient(config *models.PluginSettings, pluginContext backend.PluginContext) *jira.Client {
+	client := d.newClient(config, pluginContext)
+	if d.cache != nil {
+		client.SetCache(d.cache)
+	}
+	if d.breaker != nil {
+		client.SetCircuitBreaker(d.breaker)
+	}
+	return client
+}
+
+// setCacheStatsMeta records on every frame of res how the Jira requests of its
+// query were answered, under the "cache" key of the custom frame meta.
+func setCacheStatsMeta(res *backend.DataResponse, stats jira.CacheStats) {
+	for _, frame := range res.Frames {
+		setCustomMeta(frame, "cache", stats)
+	}
+}
+
+// setRateLimitMeta records on every frame of res the rate limit headers Jira
+// sent last, under the "rateLimit" key of the custom frame meta.
+func setRateLimitMeta(res *backend.DataResponse, limit jira.RateLimit) {
+	for _, frame := range res.Frames {
+		setCustomMeta(frame, "rateLimit", limit)
+	}
+}
+
+// setCustomMeta sets key in the custom meta of frame, keeping the other keys.
+func setCustomMeta(frame *data.Frame, key string, value interface{}) {
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	custom, ok := frame.Meta.Custom.(map[string]interface{})
+	if !ok {
+		custom = map[string]interface{}{}
+	}
+	custom[key] = value
+	frame.Meta.Custom = custom
+}
+
+// userAgent identifies the plugin towards Jira, e.g.
+// "grafana-jira-datasource/1.2.0 (grafana 11.3.0)". The version comes from the
+// build info injected at build time, falling back to the one Grafana reports.
+func userAgent(pluginContext backend.PluginContext) string {
+	ua := fmt.Sprintf("grafana-jira-datasource/%s", pluginVersion(pluginContext))
+	if pluginContext.UserAgent != nil && pluginContext.UserAgent.GrafanaVersion() != "" {
+		ua += fmt.Sprintf(" (grafana %s)", pluginContext.UserAgent.GrafanaVersion())
+	}
+	return ua
+}
@@ -0,0 +1,76 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// cohortUnresolved is the ResolvedWeek of issues that weren't resolved by the
+// end of the time range.
+const cohortUnresolved = "unresolved"
+
+// isoWeek returns the ISO week of t in loc, e.g. "2024-W03", which sorts
+// chronologically.
+func isoWeek(t time.Time, loc *time.Location) string {
+	year, week := t.In(loc).ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// getCohortData counts the issues resolved in the time range per pair of the
+// week they were created and the week they were resolved, in the time zone of
+// the query, e.g. for a heat map of how long each cohort takes to drain. The
+// resolution comes from the resolutiondate field. With qm.IncludeUnresolved,
+// issues still unresolved at the end of the range are counted per created week
+// with the ResolvedWeek "unresolved"; like all issues, they are only fetched
+// when updated in the range.
+func (d *Datasource) getCohortData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	type pair struct{ created, resolved string }
+	counts := map[pair]int64{}
+	for _, issue := range issues {
+		createdRaw, _ := issue.Fields["created"].(string)
+		created, err := parseJiraTime(createdRaw)
+		if err != nil {
+			continue
+		}
+
+		resolved := timeField(issue, "resolutiondate")
+		switch {
+		case resolved != nil && resolved.Before(timeRange.From):
+			continue
+		case resolved != nil && !resolved.After(timeRange.To):
+			counts[pair{isoWeek(created, qm.loc()), isoWeek(*resolved, qm.loc())}]++
+		case qm.IncludeUnresolved && created.Before(timeRange.To):
+			counts[pair{isoWeek(created, qm.loc()), cohortUnresolved}]++
+		}
+	}
+
+	pairs := make([]pair, 0, len(counts))
+	for p := range counts {
+		pairs = append(pairs, p)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].created != pairs[j].created {
+			return pairs[i].created < pairs[j].created
+		}
+		return pairs[i].resolved < pairs[j].resolved
+	})
+
+	frame := data.NewFrame("response",
+		data.NewField("CreatedWeek", nil, []string{}),
+		data.NewField("ResolvedWeek", nil, []string{}),
+		data.NewField("Count", nil, []int64{}),
+	)
+	for _, p := range pairs {
+		frame.AppendRow(p.created, p.resolved, counts[p])
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
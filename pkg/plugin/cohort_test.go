@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestCohort(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIncident("T-1", "Story", "", "", "2023-12-27T10:00:00.000+0000", "2024-01-09T10:00:00.000+0000"),
+		newTestIncident("T-2", "Story", "", "", "2024-01-01T10:00:00.000+0000", "2024-01-09T12:00:00.000+0000"),
+		newTestIncident("T-3", "Story", "", "", "2024-01-02T10:00:00.000+0000", "2024-01-10T12:00:00.000+0000"),
+		// Unresolved, resolved before and after the range.
+		newTestIncident("T-4", "Story", "", "", "2024-01-03T10:00:00.000+0000", ""),
+		newTestIncident("T-5", "Story", "", "", "2023-12-01T10:00:00.000+0000", "2023-12-02T10:00:00.000+0000"),
+		newTestIncident("T-6", "Story", "", "", "2024-01-03T10:00:00.000+0000", "2024-03-01T10:00:00.000+0000"),
+	}
+
+	res := ds.getCohortData(issues, queryModel{}, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	want := [][3]interface{}{
+		{"2023-W52", "2024-W02", int64(1)},
+		{"2024-W01", "2024-W02", int64(2)},
+	}
+	if frame.Rows() != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), frame.Rows())
+	}
+	for i, w := range want {
+		if got := [3]interface{}{frame.Fields[0].At(i), frame.Fields[1].At(i), frame.Fields[2].At(i)}; got != w {
+			t.Errorf("row %d: expected %v, got %v", i, w, got)
+		}
+	}
+
+	res = ds.getCohortData(issues, queryModel{IncludeUnresolved: true}, testTimeRange())
+	frame = res.Frames[0]
+	if frame.Rows() != 3 || frame.Fields[1].At(2) != cohortUnresolved || frame.Fields[2].At(2) != int64(2) {
+		t.Errorf("expected 2 unresolved issues created in 2024-W01, got %d rows", frame.Rows())
+	}
+
+	// Late on Sunday in UTC is already Monday in Sydney.
+	sydney, _ := time.LoadLocation("Australia/Sydney")
+	issues = []jira.Issue{newTestIncident("T-7", "Story", "", "", "2024-01-07T20:00:00.000+0000", "2024-01-14T20:00:00.000+0000")}
+	res = ds.getCohortData(issues, queryModel{location: sydney}, testTimeRange())
+	if got := res.Frames[0].Fields[0].At(0); got != "2024-W02" {
+		t.Errorf("expected the created week in the query's time zone, got %v", got)
+	}
+}
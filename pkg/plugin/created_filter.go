@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// relativeDatePattern matches Grafana-style relative dates: "now", an offset
+// such as "now-30d" or "-30d", optionally rounded to a unit, e.g. "now-1M/M".
+var relativeDatePattern = regexp.MustCompile(`^(now)?(?:([+-]\d+)([smhdwMy]))?(?:/([smhdwMy]))?$`)
+
+// absoluteDateLayouts are the accepted formats of absolute dates, the first two
+// in the time zone of the query.
+var absoluteDateLayouts = []string{"2006-01-02", jqlTimeLayout, time.RFC3339}
+
+// startOfUnit returns the start of the unit of t, weeks starting on Monday.
+func startOfUnit(t time.Time, unit string) time.Time {
+	switch unit {
+	case "s":
+		return t.Truncate(time.Second)
+	case "m":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	case "h":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "d":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case "w":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case "M":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+// addUnits adds n of the unit to t, calendar units in the time zone of t.
+func addUnits(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "s":
+		return t.Add(time.Duration(n) * time.Second)
+	case "m":
+		return t.Add(time.Duration(n) * time.Minute)
+	case "h":
+		return t.Add(time.Duration(n) * time.Hour)
+	case "d":
+		return t.AddDate(0, 0, n)
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "M":
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(n, 0, 0)
+	}
+}
+
+// parseDateOption parses a date option of the query: a relative date like
+// Grafana's time picker, "now-30d", "-30d" or "now-1M/M", relative to now
+// rather than to the dashboard time range, or an absolute date such as
+// "2024-01-15" or "2024-01-15 09:00". Like Grafana, a rounded date that ends a
+// range rounds up: with end, "now-1M/M" is the end of last month.
+func parseDateOption(value string, now time.Time, loc *time.Location, end bool) (time.Time, error) {
+	for _, layout := range absoluteDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+
+	m := relativeDatePattern.FindStringSubmatch(value)
+	if m == nil || (m[1] == "" && m[2] == "") {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected e.g. \"now-30d\", \"-1M/M\" or \"2024-01-15\"", value)
+	}
+	t := now.In(loc)
+	if m[2] != "" {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		t = addUnits(t, n, m[3])
+	}
+	if unit := m[4]; unit != "" {
+		t = startOfUnit(t, unit)
+		if end {
+			t = addUnits(t, 1, unit)
+		}
+	}
+	return t, nil
+}
+
+// resolveCreatedFilters resolves the createdAfter and createdBefore options of
+// the query relative to now, in the time zone of the query.
+func (qm *queryModel) resolveCreatedFilters(now time.Time) error {
+	if qm.CreatedAfter != "" {
+		after, err := parseDateOption(qm.CreatedAfter, now, qm.loc(), false)
+		if err != nil {
+			return fmt.Errorf("createdAfter: %w", err)
+		}
+		qm.createdAfter = &after
+	}
+	if qm.CreatedBefore != "" {
+		before, err := parseDateOption(qm.CreatedBefore, now, qm.loc(), true)
+		if err != nil {
+			return fmt.Errorf("createdBefore: %w", err)
+		}
+		qm.createdBefore = &before
+	}
+	if qm.createdAfter != nil && qm.createdBefore != nil && !qm.createdAfter.Before(*qm.createdBefore) {
+		return fmt.Errorf("createdAfter (%s) must be before createdBefore (%s)", qm.createdAfter.Format(jqlTimeLayout), qm.createdBefore.Format(jqlTimeLayout))
+	}
+	return nil
+}
+
+// createdClauses returns the JQL clauses of the resolved createdAfter and
+// createdBefore options.
+func createdClauses(qm queryModel) []string {
+	var clauses []string
+	if qm.createdAfter != nil {
+		clauses = append(clauses, "created >= "+jira.QuoteJQL(qm.createdAfter.In(qm.loc()).Format(jqlTimeLayout)))
+	}
+	if qm.createdBefore != nil {
+		clauses = append(clauses, "created < "+jira.QuoteJQL(qm.createdBefore.In(qm.loc()).Format(jqlTimeLayout)))
+	}
+	return clauses
+}
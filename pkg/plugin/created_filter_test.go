@@ -0,0 +1,132 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestParseDateOption(t *testing.T) {
+	sydney, _ := time.LoadLocation("Australia/Sydney")
+	// A Wednesday.
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		end   bool
+		want  string
+	}{
+		{"now", false, "2024-03-13 15:30"},
+		{"now-30d", false, "2024-02-12 15:30"},
+		{"-30d", false, "2024-02-12 15:30"},
+		{"now-2h", false, "2024-03-13 13:30"},
+		{"now/d", false, "2024-03-13 00:00"},
+		{"now/w", false, "2024-03-11 00:00"},
+		{"now-1M/M", false, "2024-02-01 00:00"},
+		{"now-1M/M", true, "2024-03-01 00:00"},
+		{"-1y/y", false, "2023-01-01 00:00"},
+		{"2024-01-15", false, "2024-01-15 00:00"},
+		{"2024-01-15 09:00", false, "2024-01-15 09:00"},
+		{"2024-01-15T09:00:00Z", false, "2024-01-15 09:00"},
+	}
+	for _, tt := range tests {
+		got, err := parseDateOption(tt.value, now, time.UTC, tt.end)
+		if err != nil {
+			t.Errorf("%s: %v", tt.value, err)
+			continue
+		}
+		if got.Format(jqlTimeLayout) != tt.want {
+			t.Errorf("%s (end %v): expected %s, got %s", tt.value, tt.end, tt.want, got.Format(jqlTimeLayout))
+		}
+	}
+
+	// Days start in the time zone of the query, where it's already the 14th.
+	if got, _ := parseDateOption("now/d", now, sydney, false); got.Format(jqlTimeLayout) != "2024-03-14 00:00" {
+		t.Errorf("expected the start of the day in Sydney, got %s", got.Format(jqlTimeLayout))
+	}
+
+	for _, value := range []string{"", "30d", "now-30", "now-30x", "yesterday", "2024-13-01"} {
+		if _, err := parseDateOption(value, now, time.UTC, false); err == nil {
+			t.Errorf("expected %q to be rejected", value)
+		}
+	}
+}
+
+func TestResolveCreatedFilters(t *testing.T) {
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.UTC)
+	qm := queryModel{CreatedAfter: "now-1M/M", CreatedBefore: "now-1M/M"}
+	if err := qm.resolveCreatedFilters(now); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`created >= '2024-02-01 00:00'`, `created < '2024-03-01 00:00'`}
+	if got := createdClauses(qm); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	for _, qm := range []queryModel{
+		{CreatedAfter: "last quarter"},
+		{CreatedBefore: "-1q"},
+		{CreatedAfter: "2024-02-01", CreatedBefore: "2024-01-01"},
+	} {
+		if err := qm.resolveCreatedFilters(now); err == nil {
+			t.Errorf("expected %q to %q to be rejected", qm.CreatedAfter, qm.CreatedBefore)
+		}
+	}
+}
+
+func TestFinalJQLCreatedFilters(t *testing.T) {
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	qm := queryModel{JQLQuery: "project = A ORDER BY created", createdAfter: &after}
+	jql, notice := finalJQL(qm, testTimeRange())
+	if !strings.Contains(jql, `) AND updated >= `) || !strings.HasSuffix(jql, ` AND created >= '2024-01-01 00:00' ORDER BY created`) {
+		t.Errorf("expected both filters before the ORDER BY clause, got %q", jql)
+	}
+	if notice == nil || !strings.Contains(notice.Text, "the dashboard time range and the created dates of the query") {
+		t.Errorf("expected the notice to name both filters, got %v", notice)
+	}
+
+	qm.JQLQuery = ""
+	if jql, _ := finalJQL(qm, testTimeRange()); jql != `created >= '2024-01-01 00:00'` {
+		t.Errorf("expected the created filter alone without a JQL, got %q", jql)
+	}
+}
+
+func TestCreatedFiltersInExecutedQuery(t *testing.T) {
+	var searchedJQL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			JQL string `json:"jql"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		searchedJQL = body.JQL
+		fmt.Fprint(w, `{"issues":[],"isLast":true}`)
+	}))
+	defer server.Close()
+
+	query := backend.DataQuery{
+		RefID:     "A",
+		JSON:      []byte(`{"metric":"jql","jqlQuery":"project = A","createdAfter":"2023-10-01","createdBefore":"2024-01-01"}`),
+		TimeRange: testTimeRange(),
+	}
+	config := &models.PluginSettings{Secrets: &models.SecretPluginSettings{}}
+	res := (&Datasource{}).query(context.Background(), jira.NewClient(server.URL, "user", "token", ""), config, query)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if !strings.Contains(searchedJQL, `updated >= `) || !strings.Contains(searchedJQL, `AND created >= '2023-10-01 00:00' AND created < '2024-01-01 00:00'`) {
+		t.Errorf("expected the search to AND the time range and the created dates, got %q", searchedJQL)
+	}
+	if got := res.Frames[0].Meta.ExecutedQueryString; got != searchedJQL {
+		t.Errorf("expected the searched JQL in the frame meta, got %q", got)
+	}
+}
@@ -0,0 +1,302 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// cycle is a completed start -> end pass of an issue through the workflow.
+type cycle struct {
+	issue jira.Issue
+	start time.Time
+	end   time.Time
+	// days is the cycle time in (inclusive) calendar days, or in the AgeUnit of
+	// the query.
+	days float64
+	// startStatus and endStatus are the statuses the cycle was measured between,
+	// those of the query or of the statusMappings of the issue's project.
+	startStatus, endStatus string
+}
+
+// collectCycles returns the completed cycle of every issue that reached one of the
+// query's start statuses and one of its end statuses within the time range, or
+// those of the statusMappings of its project.
+func collectCycles(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) []cycle {
+	global := cycleStatuses{
+		start:  qm.StartStatus,
+		end:    qm.EndStatus,
+		starts: qm.statusSet(qm.StartStatus),
+		ends:   qm.statusSet(qm.EndStatus),
+	}
+
+	var cycles []cycle
+	for _, issue := range issues {
+		statuses := qm.cycleStatusesOf(issue, global)
+		find := func() (cycle, bool) { return findCycle(issue, statuses.starts, statuses.ends, timeRange) }
+		if qm.EndCondition == endOnResolution {
+			find = func() (cycle, bool) { return findResolutionCycle(issue, statuses.starts, timeRange) }
+		}
+		if c, ok := find(); ok {
+			if qm.AgeUnit != "" {
+				c.days = qm.age(c.start, c.end)
+			}
+			c.startStatus, c.endStatus = statuses.start, statuses.end
+			cycles = append(cycles, c)
+		}
+	}
+	return cycles
+}
+
+// statusWildcard matches any status, like allValues.
+const statusWildcard = "*"
+
+// statusSet matches status transitions by the status they enter. Statuses
+// prefixed with "!" match by the status they leave instead: "!Backlog" matches
+// any transition out of Backlog. With both, a transition has to leave one of
+// the excluded statuses for one of the included ones. "*" or allValues match
+// every transition.
+type statusSet struct {
+	include []string
+	exclude []string
+	// ids maps status names to their ids in the project the query is scoped to,
+	// or in Jira, see matchStatusIDs; nil to match by name.
+	ids map[string][]string
+}
+
+// newStatusSet splits statuses into included ones and "!" excluded ones.
+func newStatusSet(statuses []string) statusSet {
+	var s statusSet
+	for _, status := range statuses {
+		if name, ok := strings.CutPrefix(status, "!"); ok {
+			s.exclude = append(s.exclude, strings.TrimSpace(name))
+		} else if status == statusWildcard {
+			s.include = append(s.include, allValues)
+		} else {
+			s.include = append(s.include, status)
+		}
+	}
+	return s
+}
+
+// statusSet returns the statusSet of the comma-separated statuses, scoped to the
+// project of the query if it has one.
+func (qm queryModel) statusSet(raw string) statusSet {
+	s := newStatusSet(parseList(raw))
+	s.ids = qm.statusIDs
+	return s
+}
+
+// has reports whether the status with the given name and id is one of names.
+// With status ids, statuses are compared by id if the change has one and the
+// name is among the ids, by name otherwise.
+func (s statusSet) has(names []string, name, id string) bool {
+	if s.ids == nil || id == "" || containsString(names, allValues) {
+		return listHas(names, name)
+	}
+	for _, n := range names {
+		if ids, ok := s.ids[n]; ok && containsString(ids, id) || !ok && n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the status change item matches the set.
+func (s statusSet) matches(item jira.Item) bool {
+	if len(s.exclude) > 0 && (!s.has(s.exclude, item.FromString, item.From) || s.has(s.exclude, item.ToString, item.To)) {
+		return false
+	}
+	if len(s.include) > 0 {
+		return s.has(s.include, item.ToString, item.To)
+	}
+	return len(s.exclude) > 0
+}
+
+// matchesAll reports whether the set matches every transition.
+func (s statusSet) matchesAll() bool {
+	return len(s.exclude) == 0 && containsString(s.include, allValues)
+}
+
+// validateStatusSet rejects "!" without a status, excluded wildcards and
+// statuses that are both included and excluded, which can never match.
+func validateStatusSet(name, raw string) error {
+	s := newStatusSet(parseList(raw))
+	for _, status := range s.exclude {
+		if status == "" {
+			return fmt.Errorf("%s has a \"!\" without a status", name)
+		}
+		if status == statusWildcard || status == allValues {
+			return fmt.Errorf("%s can't exclude all statuses", name)
+		}
+		if containsString(s.include, status) {
+			return fmt.Errorf("%s both includes and excludes %s", name, status)
+		}
+	}
+	return nil
+}
+
+// validateCycleStatuses rejects start and end statuses that both match every
+// transition, which would measure from the first to the last status change of
+// every issue regardless of the workflow.
+func validateCycleStatuses(name, start, end string) error {
+	if newStatusSet(parseList(start)).matchesAll() && newStatusSet(parseList(end)).matchesAll() {
+		return fmt.Errorf("%s can't both match all statuses, list the statuses of one of them", name)
+	}
+	return nil
+}
+
+// findCycle uses the earliest transition matching a start status and the latest
+// matching an end status within the time range, see statusSet.
+func findCycle(issue jira.Issue, starts, ends statusSet, timeRange backend.TimeRange) (cycle, bool) {
+	if issue.Changelog == nil {
+		return cycle{}, false
+	}
+
+	var startCreated, endCreated time.Time
+	var foundStart, foundEnd bool
+
+	for _, history := range issue.Changelog.Histories {
+		createdTime, err := parseJiraTime(history.Created)
+		if err != nil {
+			continue
+		}
+
+		// Filter by time range
+		if createdTime.Before(timeRange.From) || createdTime.After(timeRange.To) {
+			continue
+		}
+
+		for _, item := range history.Items {
+			if item.Field == "status" {
+				isStart := starts.matches(item)
+				isEnd := ends.matches(item)
+
+				if isStart {
+					// Logic: use earliest timestamp for start status
+					// If we haven't found a start status yet, or if this one is earlier than the existing one, update it.
+					// Wait, histories are usually chronological (or reverse?). Jira API returns reverse chronological by default in some views, but standard changelog is chronological?
+					// The current loop iterates histories in order. If they are chronological, the FIRST match is the earliest.
+					// If they are reverse chronological, the LAST match is the earliest.
+					// Assuming standard chronological order from search/jql expand:
+
+					// If we want the EARLIEST occurrence of ANY start status:
+					if !foundStart {
+						startCreated = createdTime
+						foundStart = true
+					} else {
+						// If we already found a start, only update if this one is earlier (unlikely if loop is chronological)
+						// OR if we want to reset start logic?
+						// The user requirement: "using the earlier date for the start".
+						// If an issue moves StartA -> StartB -> End, cycle time should be StartA to End?
+						// Yes, "earliest date for start".
+						if createdTime.Before(startCreated) {
+							startCreated = createdTime
+						}
+					}
+				}
+
+				if isEnd {
+					// Logic: use latest timestamp for end status
+					// If we want LATEST occurrence of ANY end status:
+					if !foundEnd {
+						endCreated = createdTime
+						foundEnd = true
+					} else {
+						if createdTime.After(endCreated) {
+							endCreated = createdTime
+						}
+					}
+				}
+
+				// We only emit a row if we have both start and end, AND we are processing the END transition?
+				// The previous logic emitted a row *every time* both flags were true inside the loop.
+				// This means if I have Start -> End -> End2, it emitted for End and End2 (using same Start).
+				// If I have Start -> Start2 -> End, it emitted for End (using Start2 if it overwrote, or Start1).
+
+				// User logic: "using the earlier date for the start and later date for the end".
+				// This implies we should process the WHOLE history for an issue, find the min(Start) and max(End), and THEN emit ONE row per issue (or per cycle?).
+				// If we emit one row per issue, we should move the `frame.AppendRow` OUTSIDE the history loop.
+
+				// HOWEVER, if an issue cycles multiple times (Start -> End -> Start -> End), do we want multiple rows?
+				// Usually yes. But the user said "earliest start and latest end". This might imply one single cycle per issue spanning the whole range.
+				// Let's assume one cycle per issue for "Earliest Start" and "Latest End" logic across the filtered time range.
+				// If so, we just accumulate timestamps in the loop and append ONCE after the loop.
+			}
+		}
+	}
+
+	if !foundStart || !foundEnd {
+		return cycle{}, false
+	}
+
+	return cycle{
+		issue: issue,
+		start: startCreated,
+		end:   endCreated,
+		days:  cycleDays(startCreated, endCreated),
+	}, true
+}
+
+// endOnResolution ends cycles when a resolution is set instead of at an end status.
+const endOnResolution = "resolution"
+
+// findResolutionCycle is findCycle for workflows that mark completion with a
+// resolution: the cycle ends with the latest change within the time range that
+// set a resolution. Issues whose changelog (e.g. truncated) has no such change
+// fall back to the resolutiondate field. Issues whose resolution was cleared
+// last are not done.
+func findResolutionCycle(issue jira.Issue, starts statusSet, timeRange backend.TimeRange) (cycle, bool) {
+	if issue.Changelog == nil {
+		return cycle{}, false
+	}
+
+	var start, end time.Time
+	var foundStart, foundEnd, cleared, changed bool
+	inRange := func(t time.Time) bool { return !t.Before(timeRange.From) && !t.After(timeRange.To) }
+	for _, history := range issue.Changelog.Histories {
+		createdTime, err := parseJiraTime(history.Created)
+		if err != nil {
+			continue
+		}
+		for _, item := range history.Items {
+			switch {
+			case item.Field == "status" && inRange(createdTime) && starts.matches(item):
+				if !foundStart || createdTime.Before(start) {
+					start, foundStart = createdTime, true
+				}
+			case item.Field == "resolution":
+				changed = true
+				cleared = item.ToString == ""
+				if !cleared && inRange(createdTime) && (!foundEnd || createdTime.After(end)) {
+					end, foundEnd = createdTime, true
+				}
+			}
+		}
+	}
+	if !changed {
+		if resolved := timeField(issue, "resolutiondate"); resolved != nil && inRange(*resolved) {
+			end, foundEnd = *resolved, true
+		}
+	}
+
+	if !foundStart || !foundEnd || cleared {
+		return cycle{}, false
+	}
+	return cycle{
+		issue: issue,
+		start: start,
+		end:   end,
+		days:  cycleDays(start, end),
+	}, true
+}
+
+// cycleDays counts the calendar days between start and end, inclusive of both.
+func cycleDays(start, end time.Time) float64 {
+	diff := math.Abs(float64(end.Sub(start).Milliseconds()))
+	return math.Ceil(diff/(1000*3600*24)) + 1
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// formatTimeSeries selects the time series output of metrics that support it.
+const formatTimeSeries = "timeseries"
+
+// getCycletimeSeriesData buckets completed cycles by their end date and emits the
+// configured quantile per bucket, so that alert rules can be defined on it.
+// Buckets without completions are null rather than zero so that alerts don't
+// resolve just because nothing finished.
+func (d *Datasource) getCycletimeSeriesData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	size, err := bucketSize(qm, timeRange)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	kept, excluded, err := filterCycles(collectCycles(issues, qm, timeRange), qm)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	buckets := bucketStarts(timeRange, size, qm.loc())
+	perBucket := make([][]float64, len(buckets))
+	for _, c := range kept {
+		if i, ok := bucketIndex(timeRange, buckets, c.end); ok {
+			perBucket[i] = append(perBucket[i], c.days)
+		}
+	}
+
+	values := make([]*float64, len(buckets))
+	for i, days := range perBucket {
+		if len(days) == 0 {
+			continue
+		}
+		v := quantile(days, qm.Quantile, qm.QuantileMethod)
+		values[i] = &v
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Time", nil, buckets),
+		data.NewField(fmt.Sprintf("P%g", qm.Quantile), nil, values),
+	)
+	frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti})
+	setBucketInterval(frame, size)
+	if filtersOutliers(qm) {
+		setCustomMeta(frame, "excludedRows", len(excluded))
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
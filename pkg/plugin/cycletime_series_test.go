@@ -0,0 +1,38 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestCycletimeSeriesNullBuckets(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Done"},
+		),
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", Quantile: 85, Format: formatTimeSeries, Interval: "1w"}
+
+	res := ds.getCycletimeSeriesData(issues, qm, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Meta == nil || frame.Meta.Type != data.FrameTypeTimeSeriesMulti {
+		t.Errorf("expected a TimeSeriesMulti frame")
+	}
+
+	values := frame.Fields[1]
+	if v := values.At(0).(*float64); v == nil || *v != 2 {
+		t.Errorf("expected 2 days in the first bucket, got %v", v)
+	}
+	for i := 1; i < values.Len(); i++ {
+		if v := values.At(i).(*float64); v != nil {
+			t.Errorf("expected null in empty bucket %d, got %v", i, *v)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+const (
+	trendWindowCount    = "count"
+	trendWindowDuration = "duration"
+
+	defaultTrendWindowIssues = 30
+	defaultTrendWindowDays   = 30
+)
+
+// getCycletimeTrendData orders completed cycles by end date and emits, for every
+// completed issue, the configured percentile over a trailing window. The window is
+// either the last N completed issues (qm.TrendWindowType "count", the default) or
+// the cycles that ended in the last N days ("duration").
+func (d *Datasource) getCycletimeTrendData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	windowType := qm.TrendWindowType
+	if windowType == "" {
+		windowType = trendWindowCount
+	}
+	windowSize := qm.TrendWindowSize
+
+	switch windowType {
+	case trendWindowCount:
+		if windowSize <= 0 {
+			windowSize = defaultTrendWindowIssues
+		}
+	case trendWindowDuration:
+		if windowSize <= 0 {
+			windowSize = defaultTrendWindowDays
+		}
+	default:
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unknown trend window type: %s", windowType))
+	}
+
+	cycles, excluded, err := filterCycles(collectCycles(issues, qm, timeRange), qm)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	sort.SliceStable(cycles, func(i, j int) bool { return cycles[i].end.Before(cycles[j].end) })
+
+	frame := data.NewFrame("response",
+		data.NewField("Time", nil, []time.Time{}),
+		data.NewField(fmt.Sprintf("P%g", qm.Quantile), nil, []float64{}),
+	)
+	frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti})
+	if filtersOutliers(qm) {
+		setCustomMeta(frame, "excludedRows", len(excluded))
+	}
+
+	for i, c := range cycles {
+		var window []float64
+		for j := i; j >= 0; j-- {
+			if windowType == trendWindowCount && i-j >= windowSize {
+				break
+			}
+			if windowType == trendWindowDuration && c.end.Sub(cycles[j].end) > time.Duration(windowSize)*24*time.Hour {
+				break
+			}
+			window = append(window, cycles[j].days)
+		}
+
+		frame.AppendRow(c.end, quantile(window, qm.Quantile, qm.QuantileMethod))
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
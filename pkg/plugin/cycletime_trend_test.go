@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestCycletimeTrendCountWindow(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		// 3 days
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		// 2 days (inclusive), completes last
+		newTestIssue("T-2", "Story",
+			[3]string{"2024-01-10T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-10T12:00:00.000+0000", "In Progress", "Done"},
+		),
+		// 5 days
+		newTestIssue("T-3", "Story",
+			[3]string{"2024-01-01T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+		),
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", Quantile: 100, TrendWindowSize: 2}
+
+	res := ds.getCycletimeTrendData(issues, qm, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+
+	expected := []float64{3, 5, 5}
+	if frame.Rows() != len(expected) {
+		t.Fatalf("expected %d points, got %d", len(expected), frame.Rows())
+	}
+	for i, v := range expected {
+		if got := frame.Fields[1].At(i).(float64); got != v {
+			t.Errorf("point %d: expected %v, got %v", i, v, got)
+		}
+	}
+
+	qm.TrendWindowType = "sliding"
+	if res := ds.getCycletimeTrendData(issues, qm, testTimeRange()); res.Error == nil {
+		t.Error("expected an error for an unknown window type")
+	}
+}
@@ -3,15 +3,21 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
-	"sort"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/tracing"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"github.com/achan/grafana-jira-datasource/pkg/jira"
 	"github.com/achan/grafana-jira-datasource/pkg/models"
 )
@@ -24,25 +30,75 @@ import (
 var (
 	_ backend.QueryDataHandler      = (*Datasource)(nil)
 	_ backend.CheckHealthHandler    = (*Datasource)(nil)
+	_ backend.CallResourceHandler   = (*Datasource)(nil)
 	_ instancemgmt.InstanceDisposer = (*Datasource)(nil)
 )
 
-// NewDatasource creates a new datasource instance.
-func NewDatasource(_ context.Context, _ backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
-	return &Datasource{}, nil
+// NewDatasource creates a new datasource instance. The SDK creates a new instance
+// whenever the settings change, so everything kept across requests lives here and
+// is released by Dispose: nothing outlives a token rotation.
+func NewDatasource(_ context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+	ds := &Datasource{
+		httpClient: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		breaker:    jira.NewCircuitBreaker(jira.DefaultFailureThreshold, jira.DefaultCooldown),
+	}
+	// Invalid settings are reported by QueryData and CheckHealth, the cache just
+	// falls back to the default TTL.
+	ttl := (&models.PluginSettings{}).CacheTTL()
+	if config, err := models.LoadPluginSettings(settings); err == nil {
+		ttl = config.CacheTTL()
+		if path := metadataPath(settings.UID, config.URL); config.MetadataSnapshots && path != "" {
+			ds.metadata = newMetadataStore(path)
+		}
+	}
+	if ttl > 0 {
+		ds.cache = jira.NewCache(ttl)
+	}
+	ds.CallResourceHandler = httpadapter.New(ds.newResourceMux())
+	return ds, nil
 }
 
 // Datasource is an example datasource which can respond to data queries, reports
-// its health and has streaming skills.
-type Datasource struct{}
+// its health and serves resource routes for the frontend.
+type Datasource struct {
+	backend.CallResourceHandler
+
+	// httpClient holds the connections to Jira of all requests.
+	httpClient *http.Client
+	// cache is shared by the clients of all requests, nil when caching is disabled.
+	cache *jira.Cache
+	// breaker stops requests to Jira while it keeps failing.
+	breaker *jira.CircuitBreaker
+	// metadata persists metadata lookups across restarts, nil when disabled.
+	metadata *metadataStore
+	// flight shares concurrent metadata loads, see loadMetadata.
+	flight metadataFlight
+	// queries shares overlapping identical queries, see queryFlight.
+	queries queryFlight
+}
 
 // Dispose here tells plugin SDK that plugin wants to clean up resources when a new instance
 // created. As soon as datasource settings change detected by SDK old datasource instance will
-// be disposed and a new one will be created using NewSampleDatasource factory function.
+// be disposed and a new one will be created using NewDatasource factory function.
 func (d *Datasource) Dispose() {
-	// Clean up datasource instance resources.
+	if d.httpClient != nil {
+		d.httpClient.CloseIdleConnections()
+	}
+	if d.cache != nil {
+		d.cache.Clear()
+	}
+	// Nothing fetches from Jira with the old settings after this returns.
+	d.flight.close()
+	if d.metadata != nil {
+		d.metadata.close()
+	}
 }
 
+// queryDeadlineBuffer is how long before the deadline Grafana sets for a request
+// searches stop fetching pages, so that there's time left to answer with a
+// partial result instead of running into Grafana's gateway timeout.
+const queryDeadlineBuffer = 2 * time.Second
+
 // QueryData handles multiple queries and returns multiple responses.
 // req contains the queries []DataQuery (where each query contains RefID as a unique identifier).
 // The QueryDataResponse contains a map of RefID to the response for each query, and each response
@@ -56,11 +112,29 @@ func (d *Datasource) QueryData(ctx context.Context, req *backend.QueryDataReques
 		return nil, fmt.Errorf("failed to load settings: %w", err)
 	}
 
-	client := jira.NewClient(config.URL, config.Username, config.Secrets.Token)
+	client := d.cachedClient(config, req.PluginContext)
+	if deadline, ok := ctx.Deadline(); ok {
+		ctx = jira.WithSearchDeadline(ctx, deadline.Add(-queryDeadlineBuffer))
+	}
 
 	// loop over queries and execute them individually.
 	for _, q := range req.Queries {
-		res := d.query(ctx, client, q)
+		before := client.CacheStats()
+		queryCtx, span := tracing.DefaultTracer().Start(ctx, "query", trace.WithAttributes(attribute.String("refId", q.RefID)))
+		res := d.queries.do(queryCtx, q, func(ctx context.Context) backend.DataResponse {
+			return d.query(ctx, client, config, q)
+		})
+		if res.Error != nil {
+			jira.SpanError(span, res.Error)
+		}
+		span.End()
+		if d.cache != nil {
+			setCacheStatsMeta(&res, client.CacheStats().Sub(before))
+		}
+		if limit := client.RateLimit(); limit != nil {
+			setRateLimitMeta(&res, limit)
+		}
+		setQueryCacheMeta(&res, newQueryCacheHint(config, q, time.Now()))
 
 		// save the response in a hashmap
 		// based on with RefID as identifier
@@ -76,9 +150,344 @@ type queryModel struct {
 	StartStatus string  `json:"startStatus"`
 	EndStatus   string  `json:"endStatus"`
 	Metric      string  `json:"metric"`
+
+	// QuantileMethod is "linear" (default), "nearestRank", "lower" or "higher".
+	QuantileMethod string `json:"quantileMethod"`
+	// IssueTypeFilter restricts aggregate metrics to the listed issue types (comma-separated).
+	IssueTypeFilter string `json:"issueTypeFilter"`
+	// Normalize turns transitionMatrix counts into percentages per FromStatus.
+	Normalize bool `json:"normalize"`
+	// HandoversInCycle only counts assignee changes between the start and end statuses.
+	HandoversInCycle bool `json:"handoversInCycle"`
+	// IgnoreUnassign skips assignee changes to nobody when counting handovers.
+	IgnoreUnassign bool `json:"ignoreUnassign"`
+	// TrendWindowType is "count" (last N issues) or "duration" (last N days) for cycletimeTrend.
+	TrendWindowType string `json:"trendWindowType"`
+	// TrendWindowSize is the number of issues or days in the cycletimeTrend window.
+	TrendWindowSize int `json:"trendWindowSize"`
+	// Interval is the bucket size of time series metrics, e.g. "1d" or "12h".
+	Interval string `json:"interval"`
+	// SplitByIssueType emits one labelled series per issue type.
+	SplitByIssueType bool `json:"splitByIssueType"`
+	// SplitByProject builds the frames of the metric separately for every project,
+	// including the projects of the JQL without issues, see buildProjectFrames.
+	SplitByProject bool `json:"splitByProject"`
+	// MaxRows caps the rows returned by the jql and changelogRaw metrics.
+	MaxRows int `json:"maxRows"`
+	// SortBy is the column table metrics are sorted by, SortOrder is "asc" or "desc".
+	SortBy    string `json:"sortBy"`
+	SortOrder string `json:"sortOrder"`
+	// IncludeURL appends a URL column with the link to each issue to the jql,
+	// cycletime and changelogRaw tables.
+	IncludeURL bool `json:"includeURL"`
+	// PageToken and PageSize fetch a single page of the jql metric, starting at
+	// the nextPageToken of the previous page, for tables that load more rows on
+	// demand.
+	PageToken string `json:"pageToken"`
+	PageSize  int    `json:"pageSize"`
+	// IncludeDescription adds the rendered issue description to the jql metric.
+	IncludeDescription bool `json:"includeDescription"`
+	// ChangelogDepth is "truncated" to use the changelogs as the search returns
+	// them or "full" to back-fill truncated ones, see backfillChangelogs. Empty
+	// uses the changelogDepth of the datasource.
+	ChangelogDepth string `json:"changelogDepth"`
+	// CompactColumns sends the repetitive string columns of the jql, changelogRaw
+	// and cycletime tables as enum fields, see compactColumns.
+	CompactColumns bool `json:"compactColumns"`
+	// IncludeStatusSince adds when issues entered their current status and the
+	// days since to the jql metric, fetching the changelog it needs.
+	IncludeStatusSince bool `json:"includeStatusSince"`
+	// IncludeBugFields adds the AffectsVersions and Environment of issues to the
+	// jql metric, for bug triage.
+	IncludeBugFields bool `json:"includeBugFields"`
+	// DescriptionFormat is "html" (default) or "text" to strip the markup.
+	DescriptionFormat string `json:"descriptionFormat"`
+	// DescriptionMaxLength truncates descriptions, 0 uses the default and -1 disables truncation.
+	DescriptionMaxLength int `json:"descriptionMaxLength"`
+	// MaxTextLength overrides the maxTextLength of the datasource, 0 disables
+	// trimming. Without a descriptionMaxLength it applies to descriptions too.
+	MaxTextLength *int `json:"maxTextLength"`
+	// LinkTypes restricts the links metric to these link types (comma-separated).
+	LinkTypes string `json:"linkTypes"`
+	// NodeStat drives the node graph main stat: "statusCategory" (default) or "cycletime".
+	NodeStat string `json:"nodeStat"`
+	// IncludeSubtasks adds SubtaskCount and SubtasksDone columns to the jql metric.
+	IncludeSubtasks bool `json:"includeSubtasks"`
+	// SubtaskStoryPoints also fetches the subtasks to sum their story points.
+	SubtaskStoryPoints bool `json:"subtaskStoryPoints"`
+	// IncludeArchived also lists archived projects in the projects metric.
+	IncludeArchived bool `json:"includeArchived"`
+	// Format selects an alternative output shape of a metric, e.g. "timeseries".
+	Format string `json:"format"`
+	// SeedFromCount seeds backlogGrowth with the open backlog size at the range start.
+	SeedFromCount bool `json:"seedFromCount"`
+	// MinCycleDays and MaxCycleDays exclude shorter or longer cycles from cycle time aggregates.
+	MinCycleDays float64 `json:"minCycleDays"`
+	MaxCycleDays float64 `json:"maxCycleDays"`
+	// OutlierFilter "iqr" also excludes cycles outside 1.5 interquartile ranges.
+	OutlierFilter string `json:"outlierFilter"`
+	// ListExcluded keeps excluded cycles in the cycletime table, flagged in an Excluded column.
+	ListExcluded bool `json:"listExcluded"`
+	// AnnotationRegions makes annotations span from the start status to the end status.
+	AnnotationRegions bool `json:"annotationRegions"`
+	// IssueKeys fetches exactly these issues instead of searching with the JQL.
+	IssueKeys []string `json:"issueKeys"`
+	// ExcludeSubtasks leaves subtasks out of every metric, since they aren't
+	// independent units of value.
+	ExcludeSubtasks bool `json:"excludeSubtasks"`
+	// DryRun returns the final JQL and the approximate number of matching issues
+	// instead of fetching them.
+	DryRun bool `json:"dryRun"`
+	// CurrentStatusFilter keeps only the fetched issues that are in one of these
+	// statuses now, e.g. the cycle time of what sits in UAT, without putting the
+	// statuses into the JQL.
+	CurrentStatusFilter []string `json:"currentStatusFilter"`
+	// IncludeUnresolved counts the issues that are still unresolved per created
+	// week in cohort.
+	IncludeUnresolved bool `json:"includeUnresolved"`
+	// IncludeSummary appends a "summary" frame with distribution statistics to cycletime.
+	IncludeSummary bool `json:"includeSummary"`
+	// TargetDays adds a WithinTarget column to the cycletime table and a "target"
+	// frame with the share of cycles within it. HideQuantile then leaves out the
+	// Quantile column.
+	TargetDays   *float64 `json:"targetDays"`
+	HideQuantile bool     `json:"hideQuantile"`
+	// Segments measures several parts of the workflow per issue in cycletime, e.g.
+	// queue and touch time, instead of the start and end statuses.
+	Segments []segment `json:"segments"`
+	// ReviewStatus is the status (or comma-separated statuses) reviewLatency measures the wait in.
+	ReviewStatus string `json:"reviewStatus"`
+	// StatusMappings are the start and end statuses of cycletime per project
+	// key, for projects whose statuses are named differently.
+	StatusMappings map[string]statusMapping `json:"statusMappings"`
+	// AggregateBy "project" appends a rollup frame with cycle time statistics per project.
+	AggregateBy string `json:"aggregateBy"`
+	// GroupBy "labels" or "affectsVersion" appends a rollup frame with cycle time
+	// statistics per label or affects version.
+	GroupBy string `json:"groupBy"`
+	// LabelAllowlist counts issues only towards the first of these labels they have
+	// (comma-separated) instead of towards each of their labels.
+	LabelAllowlist string `json:"labelAllowlist"`
+	// MinSampleSize flags rollup rows with fewer cycles as LowSample, 0 uses the default.
+	MinSampleSize int `json:"minSampleSize"`
+	// FlowStatus is the status (or comma-separated statuses) statusFlow counts transitions of.
+	FlowStatus string `json:"flowStatus"`
+	// DefectTypes are the issue types defectRatio counts as defects (comma-separated).
+	DefectTypes string `json:"defectTypes"`
+	// Sprint is the id or name of the sprint sprintChurn and burndown look at.
+	Sprint string `json:"sprint"`
+	// StatusOrder is the workflow order (comma-separated statuses) firstTimeRight
+	// checks issues against, BoardID takes it from the columns of a board instead.
+	StatusOrder string `json:"statusOrder"`
+	BoardID     int    `json:"boardId"`
+	// PriorityWeights are the weights weightedCount sums per issue by priority
+	// name, e.g. {"Blocker": 8, "Critical": 5}. PriorityBreakdown adds a frame
+	// with the score of each priority.
+	PriorityWeights   map[string]float64 `json:"priorityWeights"`
+	PriorityBreakdown bool               `json:"priorityBreakdown"`
+	// BurndownUnit is "storyPoints" (default) or "issueCount".
+	BurndownUnit string `json:"burndownUnit"`
+	// IncidentTypes are the issue types mttr looks at (comma-separated), default Incident.
+	IncidentTypes string `json:"incidentTypes"`
+	// AgeUnit measures cycle time and agingWip ages in "calendarDays" (default),
+	// "businessDays" or "workingHours" of the datasource's business calendar.
+	AgeUnit string `json:"ageUnit"`
+	// Timezone is the dashboard time zone, e.g. "Australia/Sydney", "utc" or "browser".
+	Timezone string `json:"timezone"`
+	// AuthorFilter keeps only the changelogRaw changes made by these users
+	// (comma-separated account ids or display names), and adds an Author column.
+	AuthorFilter string `json:"authorFilter"`
+	// AuthorMatch is "exact" (default) or "contains" to match part of a name.
+	AuthorMatch string `json:"authorMatch"`
+	// CreatedAfter and CreatedBefore limit the search to issues created in this
+	// range regardless of the dashboard time range: relative dates like
+	// Grafana's, e.g. "now-90d" or "-1M/M", or absolute ones like "2024-01-15".
+	CreatedAfter  string `json:"createdAfter"`
+	CreatedBefore string `json:"createdBefore"`
+	// EndCondition "resolution" ends cycletime cycles when a resolution is set
+	// instead of at the end statuses.
+	EndCondition string `json:"endCondition"`
+	// ProjectAttribution is "completion" (default) to count the cycles of issues
+	// that moved between projects towards the project they were completed in, or
+	// "start" for the one they were started in.
+	ProjectAttribution string `json:"projectAttribution"`
+	// Project scopes cycletime status matching to the statuses of this project
+	// (key, name or id), taken from a "project = X" JQL clause if empty.
+	Project string `json:"project"`
+
+	// location is the resolved Timezone, see loc().
+	location *time.Location
+	// createdAfter and createdBefore are the resolved CreatedAfter and
+	// CreatedBefore, see createdClauses.
+	createdAfter  *time.Time
+	createdBefore *time.Time
+	// calendar is the business calendar AgeUnit is measured in, see age().
+	calendar *businessCalendar
+	// changelogDepth is the resolved ChangelogDepth, see resolveChangelogDepth.
+	changelogDepth string
+	// storyPointsField is the story points field of the datasource settings.
+	storyPointsField string
+	// textLength is the number of characters summaries and other free text are
+	// trimmed to, 0 for no trimming, see text().
+	textLength int
+	// anonymizer hides user names when the datasource anonymizes users, see userName().
+	anonymizer *userAnonymizer
+	// splitThreshold and maxSplits are the search splitting settings of the
+	// datasource, see splitBoundaries.
+	splitThreshold int
+	maxSplits      int
+	// statusIDs are the status ids by name in the project scope or in Jira, see
+	// statusSet().
+	statusIDs map[string][]string
+	// maxDataPoints and queryInterval are what Grafana suggests for the panel,
+	// see bucketSize.
+	maxDataPoints int64
+	queryInterval time.Duration
 }
 
-func (d *Datasource) query(_ context.Context, client *jira.Client, query backend.DataQuery) backend.DataResponse {
+// validate rejects unknown percentile methods and option values containing
+// newlines. Options such as status names end up in JQL, where a newline can't
+// occur in a legitimate value. The JQL itself may span several lines.
+func (qm queryModel) validate() error {
+	options := map[string]string{
+		"startStatus":        qm.StartStatus,
+		"endStatus":          qm.EndStatus,
+		"flowStatus":         qm.FlowStatus,
+		"reviewStatus":       qm.ReviewStatus,
+		"aggregateBy":        qm.AggregateBy,
+		"groupBy":            qm.GroupBy,
+		"labelAllowlist":     qm.LabelAllowlist,
+		"metric":             qm.Metric,
+		"issueTypeFilter":    qm.IssueTypeFilter,
+		"trendWindowType":    qm.TrendWindowType,
+		"interval":           qm.Interval,
+		"sortBy":             qm.SortBy,
+		"sortOrder":          qm.SortOrder,
+		"descriptionFormat":  qm.DescriptionFormat,
+		"linkTypes":          qm.LinkTypes,
+		"nodeStat":           qm.NodeStat,
+		"format":             qm.Format,
+		"timezone":           qm.Timezone,
+		"createdAfter":       qm.CreatedAfter,
+		"createdBefore":      qm.CreatedBefore,
+		"authorFilter":       qm.AuthorFilter,
+		"authorMatch":        qm.AuthorMatch,
+		"pageToken":          qm.PageToken,
+		"outlierFilter":      qm.OutlierFilter,
+		"ageUnit":            qm.AgeUnit,
+		"defectTypes":        qm.DefectTypes,
+		"incidentTypes":      qm.IncidentTypes,
+		"sprint":             qm.Sprint,
+		"statusOrder":        qm.StatusOrder,
+		"burndownUnit":       qm.BurndownUnit,
+		"project":            qm.Project,
+		"projectAttribution": qm.ProjectAttribution,
+		"endCondition":       qm.EndCondition,
+	}
+	for name, value := range options {
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%s must not contain line breaks", name)
+		}
+	}
+	if err := validateAgeUnit(qm.AgeUnit); err != nil {
+		return err
+	}
+	for name, value := range map[string]string{"startStatus": qm.StartStatus, "endStatus": qm.EndStatus} {
+		if err := validateStatusSet(name, value); err != nil {
+			return err
+		}
+	}
+	if err := validateCycleStatuses("startStatus and endStatus", qm.StartStatus, qm.EndStatus); err != nil {
+		return err
+	}
+	if err := validateSegments(qm.Segments); err != nil {
+		return err
+	}
+	if err := validateSegmentOptions(qm); err != nil {
+		return err
+	}
+	if err := validateStatusMappings(qm.StatusMappings); err != nil {
+		return err
+	}
+	if qm.AggregateBy != "" && qm.AggregateBy != aggregateByProject {
+		return fmt.Errorf("unknown aggregateBy: %s", qm.AggregateBy)
+	}
+	if qm.GroupBy != "" && qm.GroupBy != groupByLabels && qm.GroupBy != groupByAffectsVersion {
+		return fmt.Errorf("unknown groupBy: %s", qm.GroupBy)
+	}
+	if qm.EndCondition != "" && qm.EndCondition != endOnResolution {
+		return fmt.Errorf("unknown endCondition: %s", qm.EndCondition)
+	}
+	if err := validateAuthorMatch(qm.AuthorMatch); err != nil {
+		return err
+	}
+	if err := validatePaging(qm); err != nil {
+		return err
+	}
+	if err := validateTarget(qm); err != nil {
+		return err
+	}
+	if err := validatePriorityWeights(qm.PriorityWeights); err != nil {
+		return err
+	}
+	if err := validateChangelogDepth("changelogDepth", qm.ChangelogDepth); err != nil {
+		return err
+	}
+	if err := validateSplitByProject(qm); err != nil {
+		return err
+	}
+	switch qm.ProjectAttribution {
+	case "", attributeAtCompletion, attributeAtStart:
+	default:
+		return fmt.Errorf("unknown projectAttribution: %s", qm.ProjectAttribution)
+	}
+	return validateQuantileMethod(qm.QuantileMethod)
+}
+
+// jiraErrorResponse is the response for a failed request to Jira. Requests that
+// got no response at all are downstream errors with the friendly message of the
+// jira.NetworkError, and a timeout or bad gateway status; their underlying error
+// is logged. Other errors are prefixed with what failed.
+func jiraErrorResponse(prefix string, err error) backend.DataResponse {
+	var netErr *jira.NetworkError
+	if !errors.As(err, &netErr) {
+		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("%s: %v", prefix, err.Error()))
+	}
+	log.DefaultLogger.Warn(prefix, "host", netErr.Host, "category", netErr.Category, "error", netErr.Err)
+	status := backend.StatusBadGateway
+	if netErr.Timeout() {
+		status = backend.StatusTimeout
+	}
+	return backend.ErrDataResponseWithSource(status, backend.ErrorSourceDownstream, netErr.Error())
+}
+
+// jiraRequestError is a failed request to Jira of a helper that can also fail on
+// the options of the query, so that the response tells the two apart, see
+// queryErrorResponse.
+type jiraRequestError struct {
+	prefix string
+	err    error
+}
+
+func (e *jiraRequestError) Error() string {
+	return fmt.Sprintf("%s: %v", e.prefix, e.err)
+}
+
+func (e *jiraRequestError) Unwrap() error {
+	return e.err
+}
+
+// queryErrorResponse is the response for an error of a helper: the
+// jiraErrorResponse of a *jiraRequestError, a bad request otherwise.
+func queryErrorResponse(err error) backend.DataResponse {
+	var reqErr *jiraRequestError
+	if errors.As(err, &reqErr) {
+		return jiraErrorResponse(reqErr.prefix, reqErr.err)
+	}
+	return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+}
+
+func (d *Datasource) query(ctx context.Context, client *jira.Client, config *models.PluginSettings, query backend.DataQuery) backend.DataResponse {
 	// var response backend.DataResponse // Unused variable removed
 
 	// Unmarshal the JSON into our queryModel.
@@ -88,10 +497,187 @@ func (d *Datasource) query(_ context.Context, client *jira.Client, query backend
 	if err != nil {
 		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("json unmarshal: %v", err.Error()))
 	}
+	if err := qm.validate(); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	qm.maxDataPoints, qm.queryInterval = query.MaxDataPoints, query.Interval
+	qm.location, err = queryLocation(qm, config)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	if qm.usesBusinessCalendar() {
+		// Invalid holidays or working hours only fail the queries measured in them.
+		qm.calendar, err = newBusinessCalendar(config, qm.loc())
+		if err != nil {
+			return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+		}
+	}
+	if err := qm.resolveCreatedFilters(time.Now()); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	qm.changelogDepth, err = resolveChangelogDepth(qm, config)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	qm.storyPointsField = config.StoryPointsField
+	qm.textLength = config.TextLength()
+	if qm.MaxTextLength != nil {
+		qm.textLength = *qm.MaxTextLength
+	}
+	qm.splitThreshold, qm.maxSplits = config.SplitSearchThreshold, config.MaxSearchSplits
+	if config.AnonymizeUsers {
+		if config.Secrets.AnonymizeSalt == "" {
+			return backend.ErrDataResponse(backend.StatusBadRequest, "anonymizeUsers requires an anonymizeSalt in the secure settings")
+		}
+		qm.anonymizer = &userAnonymizer{salt: []byte(config.Secrets.AnonymizeSalt)}
+	}
+	defer observeQuery(qm.Metric, time.Now())
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("metric", qm.Metric))
 
+	// Metrics that don't look at issues at all.
+	if qm.Metric == "projects" {
+		res := d.getProjectsData(ctx, client, qm)
+		nameFrames(&res, query.RefID, qm.Metric)
+		return res
+	}
+
+	if err := checkTimeRange(qm, config, query.TimeRange); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	if qm.DryRun {
+		res := d.getDryRunData(ctx, client, qm, query.TimeRange)
+		setFrameHints(&res)
+		nameFrames(&res, query.RefID, qm.Metric)
+		return res
+	}
+
+	var issues []jira.Issue
+	var notices []data.Notice
+	var paginationErr *jira.PaginationError
+	var nextPageToken string
+	if len(qm.IssueKeys) > 0 {
+		// Issues picked by key bypass the JQL and the dashboard time range.
+		issues, notices, err = fetchIssuesByKey(ctx, client, qm)
+		if err != nil {
+			return jiraErrorResponse("jira issue fetch failed", err)
+		}
+	} else if qm.paged() {
+		issues, notices, nextPageToken, err = searchIssuePage(ctx, client, qm, query.TimeRange)
+		if err != nil {
+			return jiraErrorResponse("jira search failed", err)
+		}
+	} else {
+		issues, notices, paginationErr, err = d.searchIssues(ctx, client, qm, query.TimeRange)
+		if err != nil {
+			return jiraErrorResponse("jira search failed", err)
+		}
+	}
+
+	backfill, backfillErr := backfillChangelogs(ctx, client, qm, issues)
+	restricted := restrictedFields(issues, requestedFields(qm))
+	statusNamesErr := resolveStatusNames(func() ([]jira.NamedValue, error) { return d.statuses(ctx, client) }, issues)
+	if qm.Metric == "cycletime" {
+		if notice := d.scopeStatuses(ctx, client, &qm, issues); notice != nil {
+			notices = append(notices, *notice)
+		}
+	}
+	if notice := d.matchStatusIDs(ctx, client, &qm, issues); notice != nil {
+		notices = append(notices, *notice)
+	}
+	if notice := d.checkStatusNames(ctx, client, qm); notice != nil {
+		notices = append(notices, *notice)
+	}
+
+	kept := filterByCurrentStatus(issues, qm.CurrentStatusFilter)
+	excludedSubtasks := 0
+	if qm.ExcludeSubtasks {
+		kept, excludedSubtasks = withoutSubtasks(kept)
+	}
+
+	var res backend.DataResponse
+	var projects []string
+	if qm.SplitByProject {
+		res, projects = d.buildProjectFrames(ctx, client, config, qm, query.TimeRange, kept)
+	} else {
+		res = d.buildFrames(ctx, client, config, qm, query.TimeRange, kept)
+	}
+	if qm.IncludeURL {
+		appendIssueURLs(&res, qm.Metric, config.URL)
+	}
+	if len(qm.IssueKeys) == 0 {
+		jql, _ := finalJQL(qm, query.TimeRange)
+		setExecutedQuery(&res, jql)
+	}
+	if qm.CompactColumns {
+		compactColumns(&res, qm.Metric)
+	}
+	setFrameHints(&res)
+	if qm.SplitByProject {
+		nameProjectFrames(&res, projects)
+	}
+	nameFrames(&res, query.RefID, qm.Metric)
+	if qm.paged() {
+		setNextPageToken(&res, nextPageToken)
+	}
+	if qm.ExcludeSubtasks {
+		for _, frame := range res.Frames {
+			setCustomMeta(frame, "excludedSubtasks", excludedSubtasks)
+		}
+	}
+	setRestrictedFields(&res, restricted)
+	if backfill != nil {
+		for _, frame := range res.Frames {
+			setCustomMeta(frame, "changelogBackfill", backfill)
+		}
+	}
+	if backfillErr != nil {
+		appendNotice(&res, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Back-filled %d of %d truncated change logs, the others are incomplete: %v", backfill.Issues, backfill.Truncated, backfillErr),
+		})
+	}
+	if statusNamesErr != nil {
+		appendNotice(&res, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Some status changes only have status ids, which couldn't be resolved to names: %v", statusNamesErr),
+		})
+	}
+	for _, notice := range notices {
+		appendNotice(&res, notice)
+	}
+	if notice := unknownOptionsNotice(unknownOptions(query.JSON)); notice != nil {
+		appendNotice(&res, *notice)
+	}
+	if paginationErr != nil {
+		appendNotice(&res, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Partial result from %d issues: %s.", len(issues), paginationErr.Error()),
+		})
+	}
+	return res
+}
+
+// searchNotices are the notices of a search: the one about the JQL, if any, and
+// the warnings of Jira.
+func searchNotices(jqlNotice *data.Notice, warnings []string) []data.Notice {
+	var notices []data.Notice
+	if jqlNotice != nil {
+		notices = append(notices, *jqlNotice)
+	}
+	for _, warning := range warnings {
+		notices = append(notices, data.Notice{Severity: data.NoticeSeverityInfo, Text: "Jira: " + warning})
+	}
+	return notices
+}
+
+// searchIssues fetches the issues matching the JQL of qm, limited to the time
+// range, with notices of how the JQL was changed and of the warnings Jira sent.
+// A search that was aborted returns the issues so far with its
+// *jira.PaginationError and no error.
+func (d *Datasource) searchIssues(ctx context.Context, client *jira.Client, qm queryModel, timeRange backend.TimeRange) ([]jira.Issue, []data.Notice, *jira.PaginationError, error) {
 	// Append time range filter to JQL to reduce load
 	// Format: "YYYY-MM-DD HH:mm"
-	// Example: "project = PLAT AND updated >= '2023-01-01 00:00'"
+	// Example: "(project = PLAT) AND updated >= '2023-01-01 00:00'"
 	// We only care about From time because filtering "To" might exclude issues updated *after* the window but were active *during* the window?
 	// Actually, if we want cycle time in a window, the issue must have had activity. 
 	// "updated >= From" is safe because if it wasn't updated since From, it couldn't have transitioned in that window (except if we care about "open during", but cycle time is about transitions).
@@ -101,155 +687,497 @@ func (d *Datasource) query(_ context.Context, client *jira.Client, query backend
 	// One edge case: Issue created before From, Updated before From, but still Open. It won't be fetched. 
 	// But if it wasn't updated in the window, it didn't change status in the window, so cycle time/changelog won't have entries in the window anyway.
 	// So "updated >= From" is safe optimization.
-	
-	jql := qm.JQLQuery
-	if jql != "" {
-		fromTime := query.TimeRange.From.Format("2006-01-02 15:04")
-		// Check if JQL already has "order by" to avoid syntax error (order by must be last)
-		// Basic check: split by "order by" (case insensitive)
-		// This is tricky parsing. For now, let's append it at the END if no order by, or insert it.
-		// A safer way: Use parentheses? "(original_jql) AND updated >= ..."
-		// But "order by" must be outside parens.
-		// If user provides "order by", we might break it. 
-		// Simpler approach: Assume user might provide "order by".
-		// We can try to append it. If JQL has "order by", we should insert before it.
-		// But regex is fragile.
-		// Alternative: Just append it and warn user? No.
-		// Let's just append " AND updated >= ..." and hope user puts "order by" at end if at all? 
-		// Actually, standard JQL allows "AND" clauses before "ORDER BY". 
-		// If the user's string ends with "ORDER BY ...", appending " AND ..." is invalid syntax.
-		// 
-		// Let's keep it simple: Append it. If the user has "ORDER BY", they should move it or we accept it might fail for complex custom JQLs.
-		// Or... we can check if "order by" exists and insert before it.
-		// But wait, the previous `SearchChangelogs` logic paginates. It doesn't rely on sorting?
-		// Actually, `jql` is passed as string.
-		// Let's implement a simple heuristic: if "order by" is found, insert before it.
-		
-		// Note: We won't implement complex parsing now. Just append it, assuming most users put filter logic first.
-		// If "ORDER BY" is present, we wrap the original query in parens? No, parens don't work around ORDER BY.
-		// Let's just assume valid JQL structure.
-		// Ideally we would put: `(user_jql) AND updated >= '...'`.
-		// But if user_jql has ORDER BY, `( ... ORDER BY ...) AND ...` is invalid.
-		
-		// Decision: Append ` AND updated >= '...'`.
-		// Limitation: User JQL must NOT end with ORDER BY for this to work perfectly.
-		// Or we can warn.
-		
-		jql += fmt.Sprintf(" AND updated >= '%s'", fromTime)
-	}
-
-	// Fetch issues from Jira
-	issues, err := client.SearchChangelogs(jql)
+	jql, jqlNotice := finalJQL(qm, timeRange)
+
+	// Fetch issues from Jira, in parts if there are many.
+	var issues []jira.Issue
+	var warnings []string
+	var err error
+	if boundaries := splitBoundaries(ctx, client, qm, timeRange, jql); len(boundaries) > 0 {
+		issues, warnings, err = searchSplit(ctx, client, qm, timeRange, boundaries)
+	} else {
+		issues, warnings, err = client.SearchChangelogs(ctx, jql, searchOptions(qm))
+	}
+	notices := searchNotices(jqlNotice, warnings)
+	var paginationErr *jira.PaginationError
+	if errors.As(err, &paginationErr) {
+		// Work with what was fetched, the notice says that it is incomplete.
+		return issues, notices, paginationErr, nil
+	}
 	if err != nil {
-		// backend.StatusInternalServerError is not exported or valid in this SDK version likely.
-		// Using backend.StatusBadRequest or constructing error with status.
-		// Standard way is to return DataResponse with Error field.
-		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("jira search failed: %v", err.Error()))
+		return nil, nil, nil, err
 	}
+	return issues, notices, nil, nil
+}
+
+// filtersByTimeRange reports whether the search of the query is limited to the
+// issues updated in the dashboard time range. weightedCount looks at what
+// matches the JQL now, however long ago it changed.
+func filtersByTimeRange(qm queryModel) bool {
+	return qm.Metric != "weightedCount"
+}
+
+// finalJQL returns the JQL of the query limited to the dashboard time range and
+// the createdAfter and createdBefore options, which apply on top of each other,
+// and a notice of how it was changed if it was.
+func finalJQL(qm queryModel, timeRange backend.TimeRange) (string, *data.Notice) {
+	jql := qm.JQLQuery
+	var clauses []string
+	var reasons []string
+	if jql != "" && filtersByTimeRange(qm) {
+		fromTime := jira.QuoteJQL(timeRange.From.In(qm.loc()).Format(jqlTimeLayout))
+		clause := fmt.Sprintf("updated >= %s", fromTime)
+		if qm.Metric == "wip" || qm.Metric == "agingWip" {
+			// WIP also needs issues that were started before the window and haven't
+			// changed since, so anything that isn't done yet is fetched as well.
+			clause = fmt.Sprintf("(updated >= %s OR statusCategory != Done)", fromTime)
+		}
+		clauses = append(clauses, clause)
+		reasons = append(reasons, "the dashboard time range")
+	}
+	if created := createdClauses(qm); len(created) > 0 {
+		clauses = append(clauses, created...)
+		reasons = append(reasons, "the created dates of the query")
+	}
+	if len(clauses) == 0 {
+		return jql, nil
+	}
+	jql, notice := addFilter(jql, strings.Join(clauses, " AND "), "to limit it to "+strings.Join(reasons, " and "))
+	return jql, &notice
+}
+
+// checkTimeRange rejects time ranges longer than the maxTimeRangeDays of the
+// datasource, which would search most of the Jira history. Queries that don't
+// filter by time, agingWip, weightedCount and issues picked by key, are exempt.
+func checkTimeRange(qm queryModel, config *models.PluginSettings, timeRange backend.TimeRange) error {
+	if config == nil || config.MaxTimeRangeDays <= 0 || qm.Metric == "agingWip" || qm.Metric == "weightedCount" || len(qm.IssueKeys) > 0 {
+		return nil
+	}
+	days := timeRange.To.Sub(timeRange.From).Hours() / 24
+	if days > float64(config.MaxTimeRangeDays) {
+		return fmt.Errorf("the time range of %.0f days exceeds the limit of %d days, zoom in or raise maxTimeRangeDays in the datasource settings", math.Ceil(days), config.MaxTimeRangeDays)
+	}
+	return nil
+}
+
+// nameFrames names the frames of a query "<RefID> <metric>", e.g. "A cycletime",
+// so that transformations and the query inspector can tell them apart. Builders
+// name their main frame "response"; additional frames keep their name as a
+// suffix, e.g. "A handovers summary". Node graph frames must stay "nodes" and
+// "edges" for the panel to find them.
+func nameFrames(res *backend.DataResponse, refID, metric string) {
+	for _, frame := range res.Frames {
+		frame.RefID = refID
+		if frame.Meta != nil && frame.Meta.PreferredVisualization == data.VisTypeNodeGraph {
+			continue
+		}
+		name := strings.TrimSpace(refID + " " + metric)
+		if frame.Name != "response" && frame.Name != "" {
+			name += " " + frame.Name
+		}
+		frame.Name = name
+	}
+}
+
+// seriesTypeVersion is the data plane contract version of the time series frames.
+var seriesTypeVersion = data.FrameTypeVersion{0, 1}
+
+// setFrameHints suggests the time series panel for time series frames and the
+// table for the others, unless a builder already suggested a visualization such
+// as the node graph, so that Explore renders frames with and without rows
+// sensibly. Time series frames are marked as following the data plane contract
+// for their type, which transformations and alerting rely on.
+func setFrameHints(res *backend.DataResponse) {
+	for _, frame := range res.Frames {
+		if frame.Meta == nil {
+			frame.SetMeta(&data.FrameMeta{})
+		}
+		switch frame.Meta.Type {
+		case data.FrameTypeTimeSeriesWide, data.FrameTypeTimeSeriesMulti, data.FrameTypeTimeSeriesLong:
+			frame.Meta.TypeVersion = seriesTypeVersion
+			if frame.Meta.PreferredVisualization == "" {
+				frame.Meta.PreferredVisualization = data.VisTypeGraph
+			}
+		default:
+			if frame.Meta.PreferredVisualization == "" {
+				frame.Meta.PreferredVisualization = data.VisTypeTable
+			}
+		}
+	}
+}
+
+// buildFrames turns the fetched issues into the frames of the query's metric.
+func (d *Datasource) buildFrames(ctx context.Context, client *jira.Client, config *models.PluginSettings, qm queryModel, timeRange backend.TimeRange, issues []jira.Issue) backend.DataResponse {
+	_, span := tracing.DefaultTracer().Start(ctx, "buildFrames", trace.WithAttributes(attribute.Int("issues", len(issues))))
+	defer span.End()
 
 	switch qm.Metric {
 	case "changelogRaw":
-		return d.getChangelogRawData(issues)
+		issues = filterChangelogAuthors(issues, qm)
+		if qm.Format == formatStatusTimestamps {
+			return d.getStatusTimestampsData(issues, qm)
+		}
+		if qm.Format == formatLabeled {
+			return d.getChangelogLabeledData(issues, qm)
+		}
+		return d.getChangelogRawData(issues, qm)
 	case "cycletime":
-		return d.getCycletimeData(issues, qm, query.TimeRange)
+		if len(qm.Segments) > 0 {
+			return d.getSegmentsData(issues, qm, timeRange)
+		}
+		var res backend.DataResponse
+		if qm.Format == formatTimeSeries {
+			res = d.getCycletimeSeriesData(issues, qm, timeRange)
+		} else {
+			res = d.getCycletimeData(issues, qm, timeRange)
+		}
+		if (qm.IncludeSummary || qm.TargetDays != nil || qm.AggregateBy != "" || qm.GroupBy != "") && res.Error == nil {
+			// The builders above already validated the outlier options.
+			kept, _, _ := filterCycles(collectCycles(issues, qm, timeRange), qm)
+			if qm.IncludeSummary {
+				res.Frames = append(res.Frames, summaryFrame(cycleDaysOf(kept), qm.QuantileMethod))
+			}
+			if qm.TargetDays != nil {
+				res.Frames = append(res.Frames, targetFrame(cycleDaysOf(kept), *qm.TargetDays))
+			}
+			if qm.AggregateBy != "" {
+				res.Frames = append(res.Frames, projectRollupFrame(kept, qm, timeRange))
+			}
+			switch qm.GroupBy {
+			case groupByLabels:
+				res.Frames = append(res.Frames, labelRollupFrame(kept, qm, timeRange))
+			case groupByAffectsVersion:
+				res.Frames = append(res.Frames, versionRollupFrame(kept, qm, timeRange))
+			}
+		}
+		return res
 	case "jql":
-		return d.getJQLData(issues)
+		if qm.IncludeSubtasks && qm.SubtaskStoryPoints {
+			points, notice, err := subtaskPoints(ctx, client, issues, config.StoryPointsField)
+			if err != nil {
+				return queryErrorResponse(err)
+			}
+			res := d.getJQLData(issues, qm, points)
+			if notice != nil && len(res.Frames) > 0 {
+				res.Frames[0].AppendNotices(*notice)
+			}
+			return res
+		}
+		return d.getJQLData(issues, qm, nil)
+	case "transitionMatrix":
+		return d.getTransitionMatrixData(issues, qm, timeRange)
+	case "annotations":
+		return d.getAnnotationsData(issues, qm, timeRange, config.URL)
+	case "timeToFirstTransition":
+		return d.getTimeToFirstTransitionData(issues, qm, timeRange)
+	case "handovers":
+		return d.getHandoversData(issues, qm, timeRange)
+	case "cycletimeTrend":
+		return d.getCycletimeTrendData(issues, qm, timeRange)
+	case "defectRatio":
+		return d.getDefectRatioData(issues, qm, config, timeRange)
+	case "firstTimeRight":
+		return d.getFirstTimeRightData(ctx, client, issues, qm, timeRange)
+	case "weightedCount":
+		return d.getWeightedCountData(issues, qm)
+	case "sprintChurn":
+		return d.getSprintChurnData(ctx, client, issues, qm)
+	case "burndown":
+		return d.getBurndownData(ctx, client, issues, qm)
+	case "mttr":
+		return d.getMTTRData(issues, qm, timeRange)
+	case "reviewLatency":
+		return d.getReviewLatencyData(issues, qm, timeRange)
+	case "statusFlow":
+		return d.getStatusFlowData(issues, qm, timeRange)
+	case "agingWip":
+		return d.getAgingWipData(issues, qm, timeRange)
+	case "wip":
+		return d.getWIPData(issues, qm, timeRange)
+	case "links":
+		if qm.Format == formatNodeGraph {
+			return d.getNodeGraphData(issues, qm, timeRange)
+		}
+		return d.getLinksData(issues, qm)
+	case "backlogGrowth":
+		return d.getBacklogGrowthData(ctx, client, issues, qm, timeRange)
+	case "cohort":
+		return d.getCohortData(issues, qm, timeRange)
 	default:
 		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unknown metric: %s", qm.Metric))
 	}
 }
 
-func (d *Datasource) getJQLData(issues []jira.Issue) backend.DataResponse {
+// searchOptions returns the extra fields and expansions the query needs on top of
+// the default search.
+func searchOptions(qm queryModel) jira.SearchOptions {
+	var opts jira.SearchOptions
+	if qm.Metric == "jql" {
+		opts.Fields = append(opts.Fields, "watches", "votes")
+		// The table only needs the changelog for the status since.
+		opts.SkipChangelog = !qm.IncludeStatusSince
+	}
+	if qm.Metric == "jql" && qm.IncludeDescription {
+		opts.Fields = append(opts.Fields, "description")
+		opts.Expand = append(opts.Expand, "renderedFields")
+	}
+	if qm.Metric == "jql" && qm.IncludeSubtasks {
+		opts.Fields = append(opts.Fields, "subtasks")
+	}
+	if qm.Metric == "jql" && qm.IncludeBugFields {
+		opts.Fields = append(opts.Fields, "versions", "environment")
+		opts.Expand = append(opts.Expand, "renderedFields")
+	}
+	if qm.GroupBy == groupByAffectsVersion {
+		opts.Fields = append(opts.Fields, "versions")
+	}
+	if qm.Metric == "links" {
+		opts.Fields = append(opts.Fields, "issuelinks")
+	}
+	if qm.Metric == "annotations" || qm.GroupBy == groupByLabels {
+		opts.Fields = append(opts.Fields, "labels")
+	}
+	if qm.Metric == "cycletime" && (qm.Format == "" || qm.EndCondition == endOnResolution) {
+		opts.Fields = append(opts.Fields, "resolutiondate")
+	}
+	if qm.Metric == "reviewLatency" {
+		opts.Fields = append(opts.Fields, "assignee")
+	}
+	if qm.Metric == "mttr" {
+		opts.Fields = append(opts.Fields, "priority", "resolutiondate")
+	}
+	if qm.Metric == "weightedCount" {
+		opts.Fields = append(opts.Fields, "priority")
+		opts.SkipChangelog = true
+	}
+	if qm.Metric == "cohort" {
+		opts.Fields = append(opts.Fields, "resolutiondate")
+	}
+	if (qm.Metric == "sprintChurn" || qm.Metric == "burndown") && qm.storyPointsField != "" {
+		opts.Fields = append(opts.Fields, qm.storyPointsField)
+	}
+	opts.ChangelogFields = changelogFields(qm)
+	return opts
+}
+
+// changelogFields returns the changelog fields the metric of the query reads,
+// nil for all of them. Dropping the others while searching keeps large
+// changelogs from piling up in memory.
+func changelogFields(qm queryModel) []string {
+	fields := []string{"status"}
+	switch qm.Metric {
+	case "changelogRaw":
+		return nil
+	case "cycletime":
+		fields = append(fields, keyField)
+	case "handovers":
+		fields = append(fields, "assignee")
+	case "sprintChurn", "burndown":
+		fields = append(fields, sprintField)
+	}
+	if qm.EndCondition == endOnResolution {
+		fields = append(fields, "resolution")
+	}
+	return fields
+}
+
+// getJQLData returns the raw issue table. subtaskPoints holds the summed subtask
+// story points per parent key when they were fetched.
+func (d *Datasource) getJQLData(issues []jira.Issue, qm queryModel, subtaskPoints map[string]float64) backend.DataResponse {
 	var response backend.DataResponse
 
 	frame := data.NewFrame("response",
 		data.NewField("Key", nil, []string{}),
-		data.NewField("Summary", nil, []string{}),
-		data.NewField("Status", nil, []string{}),
-		data.NewField("IssueType", nil, []string{}),
-		data.NewField("Project", nil, []string{}),
+		data.NewField("Summary", nil, []*string{}),
+		data.NewField("Status", nil, []*string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Project", nil, []*string{}),
+		data.NewField("Watchers", nil, []*int64{}),
+		data.NewField("Votes", nil, []*int64{}),
 	)
 
+	// Rows can only be dropped up front when they don't have to be sorted first.
+	limit := rowLimit(qm)
+	if len(issues) > limit && qm.SortBy == "" {
+		addTruncationNotice(frame, len(issues)-limit, limit)
+		issues = issues[:limit]
+	}
+
+	descriptionLength := qm.DescriptionMaxLength
+	switch {
+	case descriptionLength != 0:
+	case qm.MaxTextLength != nil && *qm.MaxTextLength == 0:
+		descriptionLength = -1
+	case qm.MaxTextLength != nil:
+		descriptionLength = *qm.MaxTextLength
+	default:
+		descriptionLength = defaultDescriptionLength
+	}
+	if qm.IncludeDescription {
+		frame.Fields = append(frame.Fields, data.NewField("Description", nil, []*string{}))
+	}
+	if qm.IncludeStatusSince {
+		frame.Fields = append(frame.Fields,
+			data.NewField("StatusSince", nil, []*time.Time{}),
+			data.NewField("DaysInStatus", nil, []*float64{}),
+		)
+	}
+	if qm.IncludeBugFields {
+		frame.Fields = append(frame.Fields,
+			data.NewField("AffectsVersions", nil, []*string{}),
+			data.NewField("Environment", nil, []*string{}),
+		)
+	}
+	if qm.IncludeSubtasks {
+		frame.Fields = append(frame.Fields,
+			data.NewField("SubtaskCount", nil, []int64{}),
+			data.NewField("SubtasksDone", nil, []int64{}),
+		)
+		if subtaskPoints != nil {
+			frame.Fields = append(frame.Fields, data.NewField("SubtaskPoints", nil, []*float64{}))
+		}
+	}
+
+	now := time.Now()
 	for _, issue := range issues {
-		summary := ""
-		if s, ok := issue.Fields["summary"].(string); ok {
-			summary = s
-		}
-		
-		status := ""
-		if st, ok := issue.Fields["status"].(map[string]interface{}); ok {
-			if name, ok := st["name"].(string); ok {
-				status = name
+		summary, _ := issue.Fields["summary"].(string)
+
+		status := issueStatus(issue)
+
+		// Fields that aren't returned, e.g. due to permissions, are left empty.
+		watchers := countField(issue, "watches", "watchCount")
+		votes := countField(issue, "votes", "votes")
+
+		row := []interface{}{
+			issue.Key,
+			qm.text(summary),
+			optionalString(status),
+			optionalString(issueTypeName(issue)),
+			optionalString(projectKey(issue)),
+			watchers,
+			votes,
+		}
+
+		if qm.IncludeDescription {
+			// The rendered HTML is only present when renderedFields was expanded.
+			var description *string
+			if html, ok := issue.RenderedFields["description"].(string); ok {
+				if qm.DescriptionFormat == "text" {
+					html = htmlToText(html)
+				}
+				text := truncateText(html, descriptionLength)
+				description = &text
 			}
+			row = append(row, description)
 		}
 
-		issueType := ""
-		if it, ok := issue.Fields["issuetype"].(map[string]interface{}); ok {
-			if name, ok := it["name"].(string); ok {
-				issueType = name
+		if qm.IncludeStatusSince {
+			var days *float64
+			since := statusSince(issue)
+			if since != nil {
+				d := now.Sub(*since).Hours() / 24
+				days = &d
 			}
+			row = append(row, since, days)
+		}
+
+		if qm.IncludeBugFields {
+			row = append(row, optionalString(strings.Join(affectsVersions(issue), ", ")), qm.text(issueEnvironment(issue)))
 		}
 
-		project := ""
-		if p, ok := issue.Fields["project"].(map[string]interface{}); ok {
-			// Try key, then name
-			if key, ok := p["key"].(string); ok {
-				project = key
-			} else if name, ok := p["name"].(string); ok {
-				project = name
+		if qm.IncludeSubtasks {
+			count, done := subtaskProgress(issue)
+			row = append(row, count, done)
+			if subtaskPoints != nil {
+				var points *float64
+				if sp, ok := subtaskPoints[issue.Key]; ok {
+					points = &sp
+				}
+				row = append(row, points)
 			}
 		}
 
-		frame.AppendRow(issue.Key, summary, status, issueType, project)
+		frame.AppendRow(row...)
 	}
 
+	if err := sortFrame(frame, qm, "", "", "Key"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	addTruncationNotice(frame, truncateFrame(frame, limit), limit)
+
 	response.Frames = append(response.Frames, frame)
 	return response
 }
 
-func (d *Datasource) getChangelogRawData(issues []jira.Issue) backend.DataResponse {
+func (d *Datasource) getChangelogRawData(issues []jira.Issue, qm queryModel) backend.DataResponse {
 	var response backend.DataResponse
 	
 	frame := data.NewFrame("response",
 		data.NewField("IssueKey", nil, []string{}),
-		data.NewField("IssueType", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
 		data.NewField("Created", nil, []time.Time{}),
 		data.NewField("field", nil, []string{}),
-		data.NewField("fromValue", nil, []string{}),
-		data.NewField("toValue", nil, []string{}),
+		data.NewField("fromValue", nil, []*string{}),
+		data.NewField("toValue", nil, []*string{}),
+		data.NewField("HistoryID", nil, []string{}),
+		data.NewField("EventIndex", nil, []int64{}),
 	)
+	withAuthor := len(authorFilters(qm)) > 0
+	if withAuthor {
+		frame.Fields = append(frame.Fields, data.NewField("Author", nil, []*string{}))
+	}
 
-	for _, issue := range issues {
-		if issue.Changelog == nil {
-			continue
-		}
-		
-		issueType := "Unknown"
-		if it, ok := issue.Fields["issuetype"].(map[string]interface{}); ok {
-			if name, ok := it["name"].(string); ok {
-				issueType = name
-			}
-		}
+	// Rows can only be dropped while building when they don't have to be sorted first.
+	limit := rowLimit(qm)
+	buildLimit := limit
+	if qm.SortBy != "" {
+		buildLimit = math.MaxInt
+	}
+	dropped := 0
 
-		for _, history := range issue.Changelog.Histories {
-			createdTime, err := time.Parse("2006-01-02T15:04:05.000-0700", history.Created)
-			if err != nil {
-				continue
-			}
+	for _, issue := range issues {
+		issueType := optionalString(issueTypeName(issue))
 
+		// EventIndex numbers the changes of every issue in order, so that changes
+		// at the same time can still be put in sequence however the rows are sorted.
+		var eventIndex int64
+		for _, dated := range chronologicalHistories(issue) {
+			history := dated.history
 			for _, item := range history.Items {
-				frame.AppendRow(
+				eventIndex++
+				// Keep counting past the limit so the notice can say how much was dropped.
+				if frame.Rows() >= buildLimit {
+					dropped++
+					continue
+				}
+				if containsString(userFields, item.Field) {
+					item.FromString, item.ToString = qm.userName(item.FromString), qm.userName(item.ToString)
+				}
+				row := []interface{}{
 					issue.Key,
 					issueType,
-					createdTime,
+					dated.at,
 					item.Field,
-					item.FromString,
-					item.ToString,
-				)
+					// A field that was empty before or after the change has no value.
+					optionalString(item.FromString),
+					optionalString(item.ToString),
+					history.ID,
+					eventIndex,
+				}
+				if withAuthor {
+					row = append(row, qm.authorName(history.Author))
+				}
+				frame.AppendRow(row...)
 			}
 		}
 	}
 
+	if err := sortFrame(frame, qm, "", "", "IssueKey"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	dropped += truncateFrame(frame, limit)
+	addTruncationNotice(frame, dropped, limit)
+
 	response.Frames = append(response.Frames, frame)
 	return response
 }
@@ -259,8 +1187,8 @@ func (d *Datasource) getCycletimeData(issues []jira.Issue, qm queryModel, timeRa
 
 	frame := data.NewFrame("response",
 		data.NewField("IssueKey", nil, []string{}),
-		data.NewField("IssueType", nil, []string{}),
-		data.NewField("Project", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Project", nil, []*string{}),
 		data.NewField("StartStatus", nil, []string{}),
 		data.NewField("EndStatus", nil, []string{}),
 		data.NewField("EndStatusCreated", nil, []time.Time{}),
@@ -268,165 +1196,57 @@ func (d *Datasource) getCycletimeData(issues []jira.Issue, qm queryModel, timeRa
 		data.NewField("Quantile", nil, []float64{}),
 	)
 
-	var cycleTimes []float64
-
-	for _, issue := range issues {
-		if issue.Changelog == nil {
-			continue
-		}
-
-		issueType := "Unknown"
-		if it, ok := issue.Fields["issuetype"].(map[string]interface{}); ok {
-			if name, ok := it["name"].(string); ok {
-				issueType = name
-			}
-		}
-
-		project := ""
-		if p, ok := issue.Fields["project"].(map[string]interface{}); ok {
-			// Try key, then name
-			if key, ok := p["key"].(string); ok {
-				project = key
-			} else if name, ok := p["name"].(string); ok {
-				project = name
-			}
-		}
-
-		var startCreated, endCreated time.Time
-		var foundStart, foundEnd bool
-
-		// For now we iterate as is.
-
-		// Handle Grafana multi-value variable format "{Val1,Val2}" by stripping braces
-		startStatusRaw := strings.Trim(qm.StartStatus, "{}")
-		endStatusRaw := strings.Trim(qm.EndStatus, "{}")
+	kept, excluded, err := filterCycles(collectCycles(issues, qm, timeRange), qm)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
 
-		startStatuses := strings.Split(startStatusRaw, ",")
-		endStatuses := strings.Split(endStatusRaw, ",")
-		for i, s := range startStatuses {
-			startStatuses[i] = strings.TrimSpace(s)
+	rows := kept
+	if qm.ListExcluded {
+		frame.Fields = append(frame.Fields, data.NewField("Excluded", nil, []bool{}))
+		rows = append(append([]cycle{}, kept...), excluded...)
+	}
+	// New columns go last so that table overrides by column index keep working.
+	frame.Fields = append(frame.Fields,
+		data.NewField("Created", nil, []*time.Time{}),
+		data.NewField("Resolved", nil, []*time.Time{}),
+		data.NewField("OriginalKey", nil, []string{}),
+		data.NewField("CurrentKey", nil, []string{}),
+	)
+	if qm.TargetDays != nil {
+		frame.Fields = append(frame.Fields, data.NewField("WithinTarget", nil, []bool{}))
+	}
+	for i, c := range rows {
+		endStatus := c.endStatus
+		if qm.EndCondition == endOnResolution {
+			endStatus = endOnResolution
 		}
-		for i, s := range endStatuses {
-			endStatuses[i] = strings.TrimSpace(s)
+		row := []interface{}{
+			c.issue.Key,
+			optionalString(issueTypeName(c.issue)),
+			optionalString(qm.cycleProject(c)),
+			c.startStatus, // The configured statuses of the query or of the project's mapping, not the specific matched status
+			endStatus,
+			c.end,
+			c.days,
+			0.0,
 		}
-
-		for _, history := range issue.Changelog.Histories {
-			createdTime, err := time.Parse("2006-01-02T15:04:05.000-0700", history.Created)
-			if err != nil {
-				continue
-			}
-
-			// Filter by time range
-			if createdTime.Before(timeRange.From) || createdTime.After(timeRange.To) {
-				continue
-			}
-
-			for _, item := range history.Items {
-				if item.Field == "status" {
-					isStart := false
-					for _, s := range startStatuses {
-						if item.ToString == s {
-							isStart = true
-							break
-						}
-					}
-					
-					isEnd := false
-					for _, s := range endStatuses {
-						if item.ToString == s {
-							isEnd = true
-							break
-						}
-					}
-
-					if isStart {
-						// Logic: use earliest timestamp for start status
-						// If we haven't found a start status yet, or if this one is earlier than the existing one, update it.
-						// Wait, histories are usually chronological (or reverse?). Jira API returns reverse chronological by default in some views, but standard changelog is chronological?
-						// The current loop iterates histories in order. If they are chronological, the FIRST match is the earliest.
-						// If they are reverse chronological, the LAST match is the earliest.
-						// Assuming standard chronological order from search/jql expand:
-						
-						// If we want the EARLIEST occurrence of ANY start status:
-						if !foundStart {
-							startCreated = createdTime
-							foundStart = true
-						} else {
-							// If we already found a start, only update if this one is earlier (unlikely if loop is chronological) 
-							// OR if we want to reset start logic?
-							// The user requirement: "using the earlier date for the start".
-							// If an issue moves StartA -> StartB -> End, cycle time should be StartA to End?
-							// Yes, "earliest date for start".
-							if createdTime.Before(startCreated) {
-								startCreated = createdTime
-							}
-						}
-					}
-					
-					if isEnd {
-						// Logic: use latest timestamp for end status
-						// If we want LATEST occurrence of ANY end status:
-						if !foundEnd {
-							endCreated = createdTime
-							foundEnd = true
-						} else {
-							if createdTime.After(endCreated) {
-								endCreated = createdTime
-							}
-						}
-					}
-					
-					// We only emit a row if we have both start and end, AND we are processing the END transition?
-					// The previous logic emitted a row *every time* both flags were true inside the loop.
-					// This means if I have Start -> End -> End2, it emitted for End and End2 (using same Start).
-					// If I have Start -> Start2 -> End, it emitted for End (using Start2 if it overwrote, or Start1).
-					
-					// User logic: "using the earlier date for the start and later date for the end".
-					// This implies we should process the WHOLE history for an issue, find the min(Start) and max(End), and THEN emit ONE row per issue (or per cycle?).
-					// If we emit one row per issue, we should move the `frame.AppendRow` OUTSIDE the history loop.
-					
-					// HOWEVER, if an issue cycles multiple times (Start -> End -> Start -> End), do we want multiple rows?
-					// Usually yes. But the user said "earliest start and latest end". This might imply one single cycle per issue spanning the whole range.
-					// Let's assume one cycle per issue for "Earliest Start" and "Latest End" logic across the filtered time range.
-					// If so, we just accumulate timestamps in the loop and append ONCE after the loop.
-				}
-			}
+		if qm.ListExcluded {
+			row = append(row, i >= len(kept))
 		}
-		
-		if foundStart && foundEnd {
-			diff := math.Abs(float64(endCreated.Sub(startCreated).Milliseconds()))
-			cycleTime := math.Ceil(diff/(1000*3600*24)) + 1
-			
-			frame.AppendRow(
-				issue.Key,
-				issueType,
-				project,
-				qm.StartStatus, // We return the config string, not the specific matched status, or we could return "Multiple"
-				qm.EndStatus,
-				endCreated,
-				cycleTime,
-				0.0,
-			)
-			cycleTimes = append(cycleTimes, cycleTime)
+		row = append(row, timeField(c.issue, "created"), timeField(c.issue, "resolutiondate"))
+		row = append(row, originalKey(c.issue, keyChanges(c.issue)), c.issue.Key)
+		if qm.TargetDays != nil {
+			row = append(row, withinTarget(c.days, *qm.TargetDays))
 		}
+		frame.AppendRow(row...)
+	}
+	if filtersOutliers(qm) {
+		setCustomMeta(frame, "excludedRows", len(excluded))
 	}
 
 	// Calculate Quantile
-	quantileValue := 0.0
-	if len(cycleTimes) > 0 {
-		sort.Float64s(cycleTimes)
-		// Simple quantile implementation
-		// Index = q * (n-1)
-		pos := (qm.Quantile / 100.0) * float64(len(cycleTimes)-1)
-		base := int(pos)
-		rest := pos - float64(base)
-		
-		if base+1 < len(cycleTimes) {
-			quantileValue = cycleTimes[base] + rest*(cycleTimes[base+1]-cycleTimes[base])
-		} else {
-			quantileValue = cycleTimes[base]
-		}
-	}
+	quantileValue := quantile(cycleDaysOf(kept), qm.Quantile, qm.QuantileMethod)
 
 	// Update Quantile column
 	// rows := frame.Rows() // Unused variable removed
@@ -434,19 +1254,26 @@ func (d *Datasource) getCycletimeData(issues []jira.Issue, qm queryModel, timeRa
 		// Update the last column (Quantile is index 7)
 		frame.Fields[7].Set(i, quantileValue)
 	}
+	if qm.HideQuantile {
+		frame.Fields = append(frame.Fields[:7], frame.Fields[8:]...)
+	}
+
+	if err := sortFrame(frame, qm, "EndStatusCreated", sortDesc, "IssueKey"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
 
 	response.Frames = append(response.Frames, frame)
 	return response
 }
 
 // CheckHealth handles health checks sent from Grafana to the plugin.
-func (d *Datasource) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
 	res := &backend.CheckHealthResult{}
 	config, err := models.LoadPluginSettings(*req.PluginContext.DataSourceInstanceSettings)
 
 	if err != nil {
 		res.Status = backend.HealthStatusError
-		res.Message = "Unable to load settings"
+		res.Message = fmt.Sprintf("Unable to load settings: %s", err.Error())
 		return res, nil
 	}
 
@@ -456,17 +1283,36 @@ func (d *Datasource) CheckHealth(_ context.Context, req *backend.CheckHealthRequ
 		return res, nil
 	}
 
-	client := jira.NewClient(config.URL, config.Username, config.Secrets.Token)
-	err = client.Myself()
+	// The health check goes through the circuit breaker as well, so that it shows
+	// when queries are failing fast, and its success closes the circuit.
+	client := d.newClient(config, req.PluginContext)
+	if d.breaker != nil {
+		client.SetCircuitBreaker(d.breaker)
+	}
+	err = client.Myself(ctx)
+	if d.breaker != nil {
+		res.JSONDetails, _ = json.Marshal(map[string]interface{}{"circuitBreaker": d.breaker.State()})
+	}
 	if err != nil {
 		res.Status = backend.HealthStatusError
-		res.Message = fmt.Sprintf("Jira connection failed: %s", err.Error())
+		var netErr *jira.NetworkError
+		if errors.As(err, &netErr) {
+			log.DefaultLogger.Warn("health check failed", "host", netErr.Host, "error", netErr.Err)
+			res.Message = netErr.Error()
+		} else {
+			res.Message = fmt.Sprintf("Jira connection failed: %s", err.Error())
+		}
 		return res, nil
 	}
 
-	return &backend.CheckHealthResult{
-		Status:  backend.HealthStatusOk,
-		Message: "Data source is working",
-	}, nil
+	res.Status = backend.HealthStatusOk
+	res.Message = "Data source is working"
+	if movedTo := client.MovedTo(); movedTo != "" {
+		res.Message = fmt.Sprintf("Data source is working, but %s", movedWarning(movedTo))
+	}
+	if len(config.LegacyKeys) > 0 {
+		res.Message += fmt.Sprintf(". Its settings use keys of an early plugin version (%s), save the datasource again to migrate them", strings.Join(config.LegacyKeys, ", "))
+	}
+	return res, nil
 }
 
@@ -2,9 +2,23 @@ package plugin
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
 )
 
 func TestCheckHealth(t *testing.T) {
@@ -32,3 +46,634 @@ func TestCheckHealth(t *testing.T) {
 		t.Errorf("expected 'API Token is missing', got '%s'", res.Message)
 	}
 }
+
+func TestCheckHealthHTMLResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>Sign in</body></html>")
+	}))
+	defer server.Close()
+
+	settings := backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"url":"` + server.URL + `", "username":"user"}`),
+		DecryptedSecureJSONData: map[string]string{"token": "token"},
+	}
+	res, _ := (&Datasource{}).CheckHealth(context.Background(), &backend.CheckHealthRequest{
+		PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+	})
+	if res.Status != backend.HealthStatusError || !strings.Contains(res.Message, "HTML instead of JSON") {
+		t.Errorf("expected the health check to explain the HTML response, got %v: %s", res.Status, res.Message)
+	}
+}
+
+func TestCheckHealthPermanentRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/old/") {
+			http.Redirect(w, r, "/new/"+strings.TrimPrefix(r.URL.Path, "/old/"), http.StatusMovedPermanently)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	settings := backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"url":"` + server.URL + `/old", "username":"user"}`),
+		DecryptedSecureJSONData: map[string]string{"token": "token"},
+	}
+	res, _ := (&Datasource{}).CheckHealth(context.Background(), &backend.CheckHealthRequest{
+		PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+	})
+	if want := "Jira redirected to " + server.URL + "/new — update the datasource URL"; res.Status != backend.HealthStatusOk || !strings.HasSuffix(res.Message, want) {
+		t.Errorf("expected a working datasource with %q, got %v: %s", want, res.Status, res.Message)
+	}
+}
+
+func TestCheckHealthLegacyKeys(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	}))
+	defer server.Close()
+
+	settings := backend.DataSourceInstanceSettings{
+		UID:                     "legacy-health",
+		JSONData:                []byte(`{"path":"` + server.URL + `", "username":"user"}`),
+		DecryptedSecureJSONData: map[string]string{"apiKey": "token"},
+	}
+	// Unlike the log warning, every health check reports the legacy keys.
+	for i := 0; i < 2; i++ {
+		res, _ := (&Datasource{}).CheckHealth(context.Background(), &backend.CheckHealthRequest{
+			PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+		})
+		if want := "Data source is working. Its settings use keys of an early plugin version (path, apiKey), save the datasource again to migrate them"; res.Status != backend.HealthStatusOk || res.Message != want {
+			t.Errorf("expected %q, got %v: %s", want, res.Status, res.Message)
+		}
+	}
+}
+
+func TestJiraErrorResponse(t *testing.T) {
+	res := jiraErrorResponse("jira search failed", &jira.NetworkError{Host: "jira.invalid", Category: jira.NetworkDNS, Err: errors.New("no such host")})
+	if res.Status != backend.StatusBadGateway || res.ErrorSource != backend.ErrorSourceDownstream {
+		t.Errorf("expected a downstream bad gateway, got %v from %q", res.Status, res.ErrorSource)
+	}
+	if !strings.HasPrefix(res.Error.Error(), "Cannot reach Jira at jira.invalid: DNS lookup failed") {
+		t.Errorf("expected the friendly message, got %q", res.Error)
+	}
+
+	res = jiraErrorResponse("jira search failed", &jira.NetworkError{Host: "jira.example.com", Category: jira.NetworkTimeout, Err: errors.New("i/o timeout")})
+	if res.Status != backend.StatusTimeout {
+		t.Errorf("expected a timeout status, got %v", res.Status)
+	}
+
+	res = jiraErrorResponse("jira search failed", &jira.StatusError{StatusCode: 400, Status: "400 Bad Request"})
+	if res.Status != backend.StatusInternal || !strings.HasPrefix(res.Error.Error(), "jira search failed: ") {
+		t.Errorf("expected HTTP errors to keep their message, got %v: %v", res.Status, res.Error)
+	}
+}
+
+// newTestIssue builds an issue with the given type and status transitions.
+// Each transition is a {created, from, to} triple.
+func newTestIssue(key, issueType string, transitions ...[3]string) jira.Issue {
+	changelog := &jira.Changelog{}
+	for i, t := range transitions {
+		changelog.Histories = append(changelog.Histories, jira.History{
+			ID:      fmt.Sprintf("%d", i+1),
+			Created: t[0],
+			Items: []jira.Item{
+				{Field: "status", FromString: t[1], ToString: t[2]},
+			},
+		})
+	}
+
+	return jira.Issue{
+		Key: key,
+		Fields: map[string]interface{}{
+			"issuetype": map[string]interface{}{"name": issueType},
+			"project":   map[string]interface{}{"key": "TEST"},
+		},
+		Changelog: changelog,
+	}
+}
+
+func testTimeRange() backend.TimeRange {
+	return backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestJQLDataMaxRows(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{newTestIssue("T-1", "Story"), newTestIssue("T-2", "Story"), newTestIssue("T-3", "Story")}
+
+	res := ds.getJQLData(issues, queryModel{MaxRows: 2}, nil)
+	frame := res.Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("expected 2 rows, got %d", frame.Rows())
+	}
+	if frame.Meta == nil || len(frame.Meta.Notices) != 1 || frame.Meta.Notices[0].Severity != data.NoticeSeverityWarning {
+		t.Fatalf("expected a truncation warning, got %+v", frame.Meta)
+	}
+
+	res = ds.getJQLData(issues, queryModel{}, nil)
+	if res.Frames[0].Meta != nil {
+		t.Errorf("expected no notice below the default limit")
+	}
+}
+
+func TestJQLDataNullableColumns(t *testing.T) {
+	ds := &Datasource{}
+	issue := newTestIssue("T-1", "Story")
+	issue.Fields["summary"] = "Login fails"
+	delete(issue.Fields, "project")
+
+	frame := ds.getJQLData([]jira.Issue{issue}, queryModel{}, nil).Frames[0]
+	summary, _ := frame.FieldByName("Summary")
+	if v, ok := summary.ConcreteAt(0); !ok || v != "Login fails" {
+		t.Errorf("expected the summary, got %v", v)
+	}
+	for _, name := range []string{"Status", "Project"} {
+		field, _ := frame.FieldByName(name)
+		if _, ok := field.ConcreteAt(0); ok {
+			t.Errorf("expected %s to be null", name)
+		}
+	}
+}
+
+func TestJQLDataStatusSince(t *testing.T) {
+	moved := newTestIssue("T-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Review"},
+	)
+	untouched := newTestIssue("T-2", "Story")
+	untouched.Fields["created"] = "2024-01-03T10:00:00.000+0000"
+	neverCreated := newTestIssue("T-3", "Story")
+
+	qm := queryModel{Metric: "jql", IncludeStatusSince: true}
+	if searchOptions(qm).SkipChangelog || !searchOptions(queryModel{Metric: "jql"}).SkipChangelog {
+		t.Error("expected the jql metric to expand change logs only for the status since")
+	}
+
+	frame := (&Datasource{}).getJQLData([]jira.Issue{moved, untouched, neverCreated}, qm, nil).Frames[0]
+	since, _ := frame.FieldByName("StatusSince")
+	days, _ := frame.FieldByName("DaysInStatus")
+	for i, want := range []string{"2024-01-05T10:00:00Z", "2024-01-03T10:00:00Z", ""} {
+		got, ok := since.ConcreteAt(i)
+		if want == "" {
+			if ok || days.At(i).(*float64) != nil {
+				t.Errorf("row %d: expected nulls without changes and created date", i)
+			}
+			continue
+		}
+		if !ok || got.(time.Time).UTC().Format(time.RFC3339) != want {
+			t.Errorf("row %d: expected the status since %s, got %v", i, want, got)
+		}
+		if d := *days.At(i).(*float64); math.Abs(d-time.Since(got.(time.Time)).Hours()/24) > 0.01 {
+			t.Errorf("row %d: unexpected days in status %v", i, d)
+		}
+	}
+}
+
+func TestCycletimeCreatedResolved(t *testing.T) {
+	ds := &Datasource{}
+	resolved := newTestIssue("T-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+	)
+	resolved.Fields["created"] = "2024-01-01T10:00:00.000+0000"
+	resolved.Fields["resolutiondate"] = "2024-01-05T10:00:00.000+0000"
+	unresolved := newTestIssue("T-2", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-04T10:00:00.000+0000", "In Progress", "Done"},
+	)
+
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", ListExcluded: true, SortBy: "IssueKey"}
+	frame := ds.getCycletimeData([]jira.Issue{resolved, unresolved}, qm, testTimeRange()).Frames[0]
+	if names := [2]string{frame.Fields[9].Name, frame.Fields[10].Name}; names != [2]string{"Created", "Resolved"} {
+		t.Fatalf("expected Created and Resolved to follow Excluded, got %v", names)
+	}
+	created, _ := frame.FieldByName("Created")
+	if v, ok := created.ConcreteAt(0); !ok || !v.(time.Time).Equal(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the creation date, got %v", v)
+	}
+	resolvedField, _ := frame.FieldByName("Resolved")
+	if _, ok := resolvedField.ConcreteAt(1); ok {
+		t.Error("expected Resolved to be null for an unresolved issue")
+	}
+}
+
+func TestCycletimeMovedIssue(t *testing.T) {
+	issue := newTestIssue("NEW-5", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+	)
+	issue.Changelog.Histories = append(issue.Changelog.Histories, jira.History{
+		Created: "2024-01-03T10:00:00.000+0000",
+		Items:   []jira.Item{{Field: "Key", FromString: "OLD-1", ToString: "NEW-5"}},
+	})
+	issue.Fields["project"] = map[string]interface{}{"key": "NEW"}
+
+	for attribution, want := range map[string]string{"": "NEW", "start": "OLD"} {
+		qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", ProjectAttribution: attribution}
+		frame := (&Datasource{}).getCycletimeData([]jira.Issue{issue}, qm, testTimeRange()).Frames[0]
+		project, _ := frame.FieldByName("Project")
+		if v, _ := project.ConcreteAt(0); v != want {
+			t.Errorf("attribution %q: expected project %s, got %v", attribution, want, v)
+		}
+		original, _ := frame.FieldByName("OriginalKey")
+		current, _ := frame.FieldByName("CurrentKey")
+		if original.At(0) != "OLD-1" || current.At(0) != "NEW-5" {
+			t.Errorf("expected OLD-1 and NEW-5, got %v and %v", original.At(0), current.At(0))
+		}
+	}
+}
+
+func TestQueryExcludesSubtasks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"issues":[
+			{"key":"A-1","fields":{"issuetype":{"name":"Story","subtask":false}}},
+			{"key":"A-2","fields":{"issuetype":{"name":"Sub-task","subtask":true}}}
+		]}`)
+	}))
+	defer server.Close()
+
+	query := backend.DataQuery{
+		JSON:      []byte(`{"metric":"jql","jqlQuery":"project = A","excludeSubtasks":true}`),
+		TimeRange: testTimeRange(),
+	}
+	config := &models.PluginSettings{Secrets: &models.SecretPluginSettings{}}
+	res := (&Datasource{}).query(context.Background(), jira.NewClient(server.URL, "user", "token", ""), config, query)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 1 || frame.Fields[0].At(0) != "A-1" {
+		t.Errorf("expected only the story, got %d rows", frame.Rows())
+	}
+	if excluded := frame.Meta.Custom.(map[string]interface{})["excludedSubtasks"]; excluded != 1 {
+		t.Errorf("expected 1 excluded subtask in the meta, got %v", excluded)
+	}
+}
+
+func TestQuerySubtaskSearchFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			JQL string `json:"jql"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.HasPrefix(body.JQL, "parent in") {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{"issuetype":{"name":"Story"},"subtasks":[{"key":"A-2"}]}}]}`)
+	}))
+	defer server.Close()
+	client := jira.NewClient(server.URL, "user", "token", "")
+
+	query := backend.DataQuery{
+		JSON:      []byte(`{"metric":"jql","jqlQuery":"project = A","includeSubtasks":true,"subtaskStoryPoints":true}`),
+		TimeRange: testTimeRange(),
+	}
+	for field, want := range map[string]backend.Status{"": backend.StatusBadRequest, "customfield_10016": backend.StatusInternal} {
+		config := &models.PluginSettings{StoryPointsField: field, Secrets: &models.SecretPluginSettings{}}
+		if res := (&Datasource{}).query(context.Background(), client, config, query); res.Status != want {
+			t.Errorf("story points field %q: expected status %d, got %d (%v)", field, want, res.Status, res.Error)
+		}
+	}
+}
+
+func TestQueryDataStopsBeforeDeadline(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		time.Sleep(60 * time.Millisecond)
+		fmt.Fprintf(w, `{"issues":[{"key":"A-%d","fields":{}}],"nextPageToken":"page-%d"}`, n, n)
+	}))
+	defer server.Close()
+
+	// Grafana gives up shortly after the buffer, leaving time for about two pages.
+	ctx, cancel := context.WithTimeout(context.Background(), queryDeadlineBuffer+150*time.Millisecond)
+	defer cancel()
+	settings := backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"url":"` + server.URL + `", "username":"user"}`),
+		DecryptedSecureJSONData: map[string]string{"token": "token"},
+	}
+	resp, err := (&Datasource{}).QueryData(ctx, &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+		Queries: []backend.DataQuery{{
+			RefID:     "A",
+			JSON:      []byte(`{"metric":"jql","jqlQuery":"project = A"}`),
+			TimeRange: testTimeRange(),
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("expected a partial result, got %v", res.Error)
+	}
+	if rows := res.Frames[0].Rows(); rows == 0 || rows != int(requests.Load()) {
+		t.Errorf("expected the issues of the %d pages fetched, got %d", requests.Load(), rows)
+	}
+	var warned bool
+	for _, notice := range res.Frames[0].Meta.Notices {
+		warned = warned || strings.Contains(notice.Text, "before the query times out")
+	}
+	if !warned {
+		t.Errorf("expected a notice that the result is partial, got %v", res.Frames[0].Meta.Notices)
+	}
+}
+
+func TestFindResolutionCycle(t *testing.T) {
+	withResolution := func(issue jira.Issue, changes ...[2]string) jira.Issue {
+		for _, c := range changes {
+			issue.Changelog.Histories = append(issue.Changelog.Histories, jira.History{
+				Created: c[0],
+				Items:   []jira.Item{{Field: "resolution", ToString: c[1]}},
+			})
+		}
+		return issue
+	}
+	started := func(key string) jira.Issue {
+		return newTestIssue(key, "Story", [3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"})
+	}
+
+	// Resolved, reopened and resolved again ends with the latest resolution.
+	reset := withResolution(started("A-1"),
+		[2]string{"2024-01-05T10:00:00.000+0000", "Fixed"},
+		[2]string{"2024-01-06T10:00:00.000+0000", ""},
+		[2]string{"2024-01-08T10:00:00.000+0000", "Fixed"},
+	)
+	// Resolution cleared last: not done.
+	reopened := withResolution(started("A-2"),
+		[2]string{"2024-01-05T10:00:00.000+0000", "Fixed"},
+		[2]string{"2024-01-06T10:00:00.000+0000", ""},
+	)
+	// No resolution change in the changelog: the resolutiondate field.
+	truncated := started("A-3")
+	truncated.Fields["resolutiondate"] = "2024-01-04T10:00:00.000+0000"
+
+	qm := queryModel{StartStatus: "In Progress", EndCondition: endOnResolution}
+	cycles := collectCycles([]jira.Issue{reset, reopened, truncated}, qm, testTimeRange())
+	if len(cycles) != 2 {
+		t.Fatalf("expected 2 cycles, got %d", len(cycles))
+	}
+	for i, want := range []time.Time{
+		time.Date(2024, 1, 8, 10, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 4, 10, 0, 0, 0, time.UTC),
+	} {
+		if !cycles[i].end.Equal(want) {
+			t.Errorf("%s: expected the cycle to end at %v, got %v", cycles[i].issue.Key, want, cycles[i].end)
+		}
+	}
+}
+
+func TestFindCycleNegatedStatuses(t *testing.T) {
+	issue := newTestIssue("A-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "Backlog", "Ready"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "Ready", "In Progress"},
+		[3]string{"2024-01-09T10:00:00.000+0000", "In Progress", "Done"},
+	)
+
+	tests := []struct {
+		start string
+		want  time.Time
+		found bool
+	}{
+		{"!Backlog", time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), true},
+		{"!Ready", time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC), true},
+		{"!Backlog, In Progress", time.Time{}, false},
+		{"!Ready, In Progress", time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC), true},
+		{"!Review", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		c, ok := findCycle(issue, newStatusSet(parseList(tt.start)), newStatusSet([]string{"Done"}), testTimeRange())
+		if ok != tt.found || (ok && !c.start.Equal(tt.want)) {
+			t.Errorf("start %q: expected %v (%v), got %v (%v)", tt.start, tt.want, tt.found, c.start, ok)
+		}
+	}
+
+	for _, raw := range []string{"!", "In Progress, !In Progress"} {
+		if err := validateStatusSet("startStatus", raw); err == nil {
+			t.Errorf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestFindCycleWildcardStatuses(t *testing.T) {
+	issue := newTestIssue("A-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "Backlog", "In Progress"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Review"},
+		[3]string{"2024-01-09T10:00:00.000+0000", "Review", "In Progress"},
+	)
+
+	tests := []struct {
+		start, end string
+		wantStart  time.Time
+		wantEnd    time.Time
+	}{
+		{"In Progress", "*", time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), time.Date(2024, 1, 9, 10, 0, 0, 0, time.UTC)},
+		{"Review", "$__all", time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC), time.Date(2024, 1, 9, 10, 0, 0, 0, time.UTC)},
+		{"*", "Review", time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC), time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, ok := findCycle(issue, newStatusSet(parseList(tt.start)), newStatusSet(parseList(tt.end)), testTimeRange())
+		if !ok || !c.start.Equal(tt.wantStart) || !c.end.Equal(tt.wantEnd) {
+			t.Errorf("%q -> %q: expected %v - %v, got %v - %v (%v)", tt.start, tt.end, tt.wantStart, tt.wantEnd, c.start, c.end, ok)
+		}
+	}
+
+	if err := validateCycleStatuses("startStatus and endStatus", "*", "$__all"); err == nil {
+		t.Error("expected wildcard start and end statuses to be rejected")
+	}
+	if err := validateCycleStatuses("startStatus and endStatus", "*", "Done"); err != nil {
+		t.Errorf("expected a wildcard start status to be accepted, got %v", err)
+	}
+	if err := validateStatusSet("endStatus", "!*"); err == nil {
+		t.Error("expected an excluded wildcard to be rejected")
+	}
+}
+
+func TestFilterByCurrentStatus(t *testing.T) {
+	uat := newTestIssue("A-1", "Story")
+	uat.Fields["status"] = map[string]interface{}{"name": "UAT"}
+	done := newTestIssue("A-2", "Story")
+	done.Fields["status"] = map[string]interface{}{"name": "Done"}
+	issues := []jira.Issue{uat, done, newTestIssue("A-3", "Story")}
+
+	kept := filterByCurrentStatus(issues, []string{"UAT"})
+	if len(kept) != 1 || kept[0].Key != "A-1" {
+		t.Errorf("expected only A-1, got %v", kept)
+	}
+	if kept := filterByCurrentStatus(issues, nil); len(kept) != 3 {
+		t.Errorf("expected all issues without a filter, got %d", len(kept))
+	}
+}
+
+func TestQueryRejectsLineBreaks(t *testing.T) {
+	ds := &Datasource{}
+	query := backend.DataQuery{
+		JSON:      []byte(`{"metric":"cycletime","jqlQuery":"project = A\nORDER BY rank","startStatus":"In Progress\n' OR project = B"}`),
+		TimeRange: testTimeRange(),
+	}
+
+	res := ds.query(context.Background(), jira.NewClient("http://127.0.0.1:0", "user", "token", ""), nil, query)
+	if res.Error == nil || res.Status != backend.StatusBadRequest {
+		t.Fatalf("expected a bad request error, got %v", res.Error)
+	}
+}
+
+func TestQueryRejectsLongTimeRanges(t *testing.T) {
+	ds := &Datasource{}
+	config := &models.PluginSettings{MaxTimeRangeDays: 7, Secrets: &models.SecretPluginSettings{}}
+	query := backend.DataQuery{
+		JSON:      []byte(`{"metric":"cycletime","jqlQuery":"project = A"}`),
+		TimeRange: testTimeRange(),
+	}
+
+	// The client can't connect, so an error other than bad request means Jira was asked.
+	res := ds.query(context.Background(), jira.NewClient("http://127.0.0.1:0", "user", "token", ""), config, query)
+	if res.Error == nil || res.Status != backend.StatusBadRequest || !strings.Contains(res.Error.Error(), "maxTimeRangeDays") {
+		t.Fatalf("expected a bad request error about maxTimeRangeDays, got %v", res.Error)
+	}
+
+	for _, qm := range []queryModel{{Metric: "agingWip"}, {Metric: "cycletime", IssueKeys: []string{"A-1"}}} {
+		if err := checkTimeRange(qm, config, testTimeRange()); err != nil {
+			t.Errorf("expected %+v to be exempt, got %v", qm, err)
+		}
+	}
+	if err := checkTimeRange(queryModel{Metric: "cycletime"}, &models.PluginSettings{MaxTimeRangeDays: 31}, testTimeRange()); err != nil {
+		t.Errorf("expected a range of exactly the limit to pass, got %v", err)
+	}
+}
+
+func TestNameFrames(t *testing.T) {
+	nodes := data.NewFrame("nodes")
+	nodes.SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph})
+	res := backend.DataResponse{Frames: data.Frames{data.NewFrame("response"), data.NewFrame("summary"), nodes}}
+
+	nameFrames(&res, "A", "handovers")
+	for i, want := range []string{"A handovers", "A handovers summary", "nodes"} {
+		if res.Frames[i].Name != want || res.Frames[i].RefID != "A" {
+			t.Errorf("frame %d: expected %q with RefID A, got %q with RefID %q", i, want, res.Frames[i].Name, res.Frames[i].RefID)
+		}
+	}
+}
+
+func TestSettingsChangeReplacesInstance(t *testing.T) {
+	var authHeaders []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeaders = append(authHeaders, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}}]}`)
+	}))
+	defer server.Close()
+
+	pluginContext := func(token string, updated time.Time) backend.PluginContext {
+		return backend.PluginContext{DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+			ID:                      1,
+			JSONData:                []byte(fmt.Sprintf(`{"url":%q,"username":"user"}`, server.URL)),
+			DecryptedSecureJSONData: map[string]string{"token": token},
+			Updated:                 updated,
+		}}
+	}
+	query := func(ds *Datasource, pc backend.PluginContext) {
+		t.Helper()
+		res, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+			PluginContext: pc,
+			Queries:       []backend.DataQuery{{RefID: "A", JSON: []byte(`{"metric":"jql","jqlQuery":"project = A"}`), TimeRange: testTimeRange()}},
+		})
+		if err != nil || res.Responses["A"].Error != nil {
+			t.Fatalf("query failed: %v %v", err, res.Responses["A"].Error)
+		}
+	}
+
+	im := datasource.NewInstanceManager(NewDatasource)
+	oldContext := pluginContext("old-token", time.Unix(1, 0))
+	instance, err := im.Get(context.Background(), oldContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldDS := instance.(*Datasource)
+	query(oldDS, oldContext)
+	if oldDS.cache.Len() == 0 {
+		t.Fatal("expected the response to be cached")
+	}
+
+	// Saving the datasource with a rotated token bumps Updated.
+	newContext := pluginContext("new-token", time.Unix(2, 0))
+	instance, err = im.Get(context.Background(), newContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newDS := instance.(*Datasource)
+	if newDS == oldDS {
+		t.Fatal("expected a new instance after the settings changed")
+	}
+	if newDS.cache.Len() != 0 {
+		t.Errorf("expected the new instance to start with an empty cache")
+	}
+	query(newDS, newContext)
+	want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:new-token"))
+	if len(authHeaders) != 2 || authHeaders[1] != want {
+		t.Errorf("expected the second request to use the new token, got %v", authHeaders)
+	}
+
+	// The manager disposes the old instance after a grace period, see Dispose.
+	oldDS.Dispose()
+	if oldDS.cache.Len() != 0 {
+		t.Errorf("expected Dispose to clear the cache")
+	}
+}
+
+func TestChangelogFields(t *testing.T) {
+	if fields := searchOptions(queryModel{Metric: "changelogRaw"}).ChangelogFields; fields != nil {
+		t.Errorf("expected changelogRaw to keep the whole changelog, got %v", fields)
+	}
+	fields := searchOptions(queryModel{Metric: "cycletime", EndCondition: endOnResolution}).ChangelogFields
+	for _, field := range []string{"status", keyField, "resolution"} {
+		if !containsString(fields, field) {
+			t.Errorf("expected cycletime to keep %s changes, got %v", field, fields)
+		}
+	}
+	if fields := changelogFields(queryModel{Metric: "handovers"}); !containsString(fields, "assignee") {
+		t.Errorf("expected handovers to keep assignee changes, got %v", fields)
+	}
+}
+
+func TestChangelogRawEventIndex(t *testing.T) {
+	// A bulk transition created two histories at the same time, and Jira returned
+	// the histories newest first.
+	issue := jira.Issue{
+		Key:    "T-1",
+		Fields: map[string]interface{}{"issuetype": map[string]interface{}{"name": "Story"}},
+		Changelog: &jira.Changelog{Histories: []jira.History{
+			{ID: "10", Created: "2024-01-03T10:00:00.000+0000", Items: []jira.Item{{Field: "status", FromString: "In Progress", ToString: "Done"}}},
+			{ID: "9", Created: "2024-01-03T10:00:00.000+0000", Items: []jira.Item{
+				{Field: "status", FromString: "To Do", ToString: "In Progress"},
+				{Field: "assignee", ToString: "Ana"},
+			}},
+			{ID: "3", Created: "2024-01-02T10:00:00.000+0000", Items: []jira.Item{{Field: "labels", ToString: "backend"}}},
+		}},
+	}
+
+	rows := func(qm queryModel) string {
+		t.Helper()
+		frame := (&Datasource{}).getChangelogRawData([]jira.Issue{issue}, qm).Frames[0]
+		historyIDs, _ := frame.FieldByName("HistoryID")
+		indexes, _ := frame.FieldByName("EventIndex")
+		var got []string
+		for i := 0; i < frame.Rows(); i++ {
+			field, _ := frame.FieldByName("field")
+			got = append(got, fmt.Sprintf("%d:%s:%s", indexes.At(i), historyIDs.At(i), field.At(i)))
+		}
+		return strings.Join(got, " ")
+	}
+	want := "1:3:labels 2:9:status 3:9:assignee 4:10:status"
+	if got := rows(queryModel{MaxRows: 10}); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	// The index stays with the change when the rows are sorted otherwise.
+	if got := rows(queryModel{MaxRows: 10, SortBy: "EventIndex", SortOrder: "desc"}); got != "4:10:status 3:9:assignee 2:9:status 1:3:labels" {
+		t.Errorf("expected the changes newest first, got %s", got)
+	}
+}
@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+// defaultDefectTypes are the issue types counted as defects when neither the
+// query nor the datasource configures them.
+var defaultDefectTypes = []string{"Bug"}
+
+// defectTypes returns the defect types of the query, falling back to the
+// datasource setting and then to defaultDefectTypes.
+func defectTypes(qm queryModel, config *models.PluginSettings) []string {
+	if types := parseList(qm.DefectTypes); len(types) > 0 {
+		return types
+	}
+	if config != nil && len(config.DefectTypes) > 0 {
+		return config.DefectTypes
+	}
+	return defaultDefectTypes
+}
+
+// getDefectRatioData emits, per bucket, how many issues were completed (entered an
+// end status, counted once at the latest such transition in the range), how many
+// of them were defects and their ratio, null for buckets without completions. A
+// "summary" frame has the same numbers for the whole range.
+func (d *Datasource) getDefectRatioData(issues []jira.Issue, qm queryModel, config *models.PluginSettings, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	size, err := bucketSize(qm, timeRange)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	buckets := bucketStarts(timeRange, size, qm.loc())
+	completed := make([]int64, len(buckets))
+	defects := make([]int64, len(buckets))
+
+	endStatuses := parseList(qm.EndStatus)
+	types := defectTypes(qm, config)
+
+	var totalCompleted, totalDefects int64
+	for _, issue := range issues {
+		var completedAt time.Time
+		for _, change := range statusChanges(issue) {
+			if listHas(endStatuses, change.to) && !change.at.Before(timeRange.From) && !change.at.After(timeRange.To) {
+				completedAt = change.at
+			}
+		}
+		i, ok := bucketIndex(timeRange, buckets, completedAt)
+		if !ok {
+			continue
+		}
+
+		completed[i]++
+		totalCompleted++
+		if listHas(types, issueTypeName(issue)) {
+			defects[i]++
+			totalDefects++
+		}
+	}
+
+	ratios := make([]*float64, len(buckets))
+	for i := range buckets {
+		ratios[i] = defectRatio(defects[i], completed[i])
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Time", nil, buckets),
+		data.NewField("Completed", nil, completed),
+		data.NewField("Defects", nil, defects),
+		data.NewField("DefectRatio", nil, ratios),
+	)
+	frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesWide})
+	setBucketInterval(frame, size)
+
+	summary := data.NewFrame("summary",
+		data.NewField("Completed", nil, []int64{totalCompleted}),
+		data.NewField("Defects", nil, []int64{totalDefects}),
+		data.NewField("DefectRatio", nil, []*float64{defectRatio(totalDefects, totalCompleted)}),
+	)
+
+	response.Frames = append(response.Frames, frame, summary)
+	return response
+}
+
+// defectRatio is defects/completed, nil when nothing was completed.
+func defectRatio(defects, completed int64) *float64 {
+	if completed == 0 {
+		return nil
+	}
+	ratio := float64(defects) / float64(completed)
+	return &ratio
+}
@@ -0,0 +1,43 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestDefectRatio(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Bug", [3]string{"2024-01-01T10:00:00.000+0000", "In Progress", "Done"}),
+		newTestIssue("T-2", "Story", [3]string{"2024-01-01T11:00:00.000+0000", "In Progress", "Done"}),
+		newTestIssue("T-3", "Incident", [3]string{"2024-01-02T11:00:00.000+0000", "In Progress", "Done"}),
+		// Not completed.
+		newTestIssue("T-4", "Bug", [3]string{"2024-01-02T11:00:00.000+0000", "To Do", "In Progress"}),
+	}
+	qm := queryModel{EndStatus: "Done", Interval: "1d"}
+
+	res := ds.getDefectRatioData(issues, qm, nil, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	ratio := res.Frames[0].Fields[3]
+	if got := ratio.At(0).(*float64); got == nil || *got != 0.5 {
+		t.Errorf("expected a ratio of 0.5 on the 1st, got %v", got)
+	}
+	if got := ratio.At(1).(*float64); got == nil || *got != 0 {
+		t.Errorf("expected a ratio of 0 on the 2nd, got %v", got)
+	}
+	if got := ratio.At(2).(*float64); got != nil {
+		t.Errorf("expected no ratio without completions, got %v", *got)
+	}
+
+	// The datasource default applies when the query has no defect types.
+	config := &models.PluginSettings{DefectTypes: []string{"Bug", "Incident"}}
+	res = ds.getDefectRatioData(issues, qm, config, testTimeRange())
+	summary := res.Frames[1]
+	if completed, defects := summary.At(0, 0), summary.At(1, 0); completed != int64(3) || defects != int64(2) {
+		t.Errorf("expected 2 defects out of 3 completed issues, got %v out of %v", defects, completed)
+	}
+}
@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// getDryRunData previews the search of the query without fetching any issues:
+// the JQL it would run, limited to the time range, the approximate number of
+// issues it matches and whether their change logs would be expanded, which is
+// what makes searches expensive.
+func (d *Datasource) getDryRunData(ctx context.Context, client *jira.Client, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	if len(qm.IssueKeys) > 0 {
+		return backend.ErrDataResponse(backend.StatusBadRequest, "dryRun previews the JQL search and doesn't apply to issueKeys")
+	}
+
+	jql, jqlNotice := finalJQL(qm, timeRange)
+	count, err := client.CountIssues(ctx, jql)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("jira issue count failed: %v", err.Error()))
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("FinalJQL", nil, []string{jql}),
+		data.NewField("EstimatedIssues", nil, []int64{int64(count)}),
+		data.NewField("WouldExpandChangelog", nil, []bool{!searchOptions(qm).SkipChangelog}),
+	)
+	if jqlNotice != nil {
+		frame.AppendNotices(*jqlNotice)
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestDryRun(t *testing.T) {
+	var countedJQL string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/search/approximate-count" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		var body struct {
+			JQL string `json:"jql"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		countedJQL = body.JQL
+		fmt.Fprint(w, `{"count":1234}`)
+	}))
+	defer server.Close()
+
+	query := backend.DataQuery{
+		RefID:     "A",
+		JSON:      []byte(`{"metric":"cycletime","jqlQuery":"project = A","dryRun":true}`),
+		TimeRange: testTimeRange(),
+	}
+	config := &models.PluginSettings{Secrets: &models.SecretPluginSettings{}}
+	res := (&Datasource{}).query(context.Background(), jira.NewClient(server.URL, "user", "token", ""), config, query)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	frame := res.Frames[0]
+	if frame.Rows() != 1 {
+		t.Fatalf("expected a single row, got %d", frame.Rows())
+	}
+	jql, _ := frame.Fields[0].ConcreteAt(0)
+	if jql != countedJQL || !strings.Contains(countedJQL, "updated >=") {
+		t.Errorf("expected the counted JQL with the time range, got %q (counted %q)", jql, countedJQL)
+	}
+	if count, _ := frame.Fields[1].ConcreteAt(0); count != int64(1234) {
+		t.Errorf("expected the estimate of 1234, got %v", count)
+	}
+	if expand, _ := frame.Fields[2].ConcreteAt(0); expand != true {
+		t.Errorf("expected cycletime to expand change logs, got %v", expand)
+	}
+}
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+// frameSchemas describes the frames of res as "name(visualization): Field:type ...",
+// with the frame type and its version for time series.
+func frameSchemas(res backend.DataResponse) []string {
+	var schemas []string
+	for _, frame := range res.Frames {
+		var vis string
+		if frame.Meta != nil {
+			vis = string(frame.Meta.PreferredVisualization)
+			if !frame.Meta.TypeVersion.IsZero() {
+				vis += " " + string(frame.Meta.Type) + " " + frame.Meta.TypeVersion.String()
+			}
+		}
+		fields := make([]string, len(frame.Fields))
+		for i, field := range frame.Fields {
+			fields[i] = field.Name + ":" + field.Type().ItemTypeString()
+		}
+		schemas = append(schemas, frame.Name+"("+vis+"): "+strings.Join(fields, " "))
+	}
+	return schemas
+}
+
+func TestEmptyResultsKeepSchema(t *testing.T) {
+	ds := &Datasource{}
+	const cycleColumns = "IssueKey:string IssueType:*string Project:*string StartStatus:string EndStatus:string EndStatusCreated:time.Time CycleTime:float64 Quantile:float64 Created:*time.Time Resolved:*time.Time OriginalKey:string CurrentKey:string"
+	const summaryColumns = "Count:int64 Mean:*float64 Median:*float64 P85:*float64 P95:*float64 Min:*float64 Max:*float64"
+
+	tests := []struct {
+		qm   queryModel
+		want []string
+	}{
+		{queryModel{Metric: "changelogRaw"}, []string{"response(table): IssueKey:string IssueType:*string Created:time.Time field:string fromValue:*string toValue:*string HistoryID:string EventIndex:int64"}},
+		{queryModel{Metric: "changelogRaw", Format: formatStatusTimestamps}, []string{"response(table): IssueKey:string IssueType:*string"}},
+		{queryModel{Metric: "changelogRaw", Format: formatLabeled}, []string{"response(table): IssueKey:string Created:time.Time FromValue:*string ToValue:*string"}},
+		{queryModel{Metric: "cycletime", IncludeSummary: true, AggregateBy: aggregateByProject, GroupBy: groupByLabels}, []string{
+			"response(table): " + cycleColumns,
+			"summary(table): " + summaryColumns,
+			"rollup(table): Project:*string Count:int64 MedianCycle:float64 P85Cycle:float64 Throughput:*float64 LowSample:bool",
+			"labels(table): Label:*string Count:int64 MedianCycle:float64 P85Cycle:float64 Throughput:*float64 LowSample:bool",
+		}},
+		{queryModel{Metric: "cycletime", Format: formatTimeSeries, Interval: "1d", SplitByIssueType: true}, []string{"response(graph timeseries-multi 0.1): Time:time.Time P0:*float64"}},
+		{queryModel{Metric: "cycletime", Segments: []segment{{Name: "Queue", StartStatuses: "Ready", EndStatuses: "In Progress"}}}, []string{
+			"response(table): IssueKey:string IssueType:*string Project:*string Queue:*float64",
+			"summary(table): Segment:string Count:int64 Median:*float64 Quantile:*float64",
+		}},
+		{queryModel{Metric: "jql"}, []string{"response(table): Key:string Summary:*string Status:*string IssueType:*string Project:*string Watchers:*int64 Votes:*int64"}},
+		{queryModel{Metric: "transitionMatrix"}, []string{"response(table): FromStatus:string ToStatus:string Count:int64"}},
+		{queryModel{Metric: "annotations"}, []string{"response(table): time:time.Time title:string text:string tags:string"}},
+		{queryModel{Metric: "timeToFirstTransition"}, []string{"response(table): IssueKey:string IssueType:*string Project:*string Created:time.Time FirstTransitionAt:*time.Time HoursToFirstTransition:float64 StillUntouched:bool Quantile:float64"}},
+		{queryModel{Metric: "handovers"}, []string{
+			"response(table): IssueKey:string IssueType:*string Project:*string HandoverCount:int64 Assignees:int64",
+			"summary(table): HandoverCount:int64 Issues:int64",
+		}},
+		{queryModel{Metric: "cycletimeTrend", SplitByIssueType: true}, []string{"response(graph timeseries-multi 0.1): Time:time.Time P0:float64"}},
+		{queryModel{Metric: "defectRatio", Interval: "1d"}, []string{
+			"response(graph timeseries-wide 0.1): Time:time.Time Completed:int64 Defects:int64 DefectRatio:*float64",
+			"summary(table): Completed:int64 Defects:int64 DefectRatio:*float64",
+		}},
+		{queryModel{Metric: "mttr", Format: formatTimeSeries, Interval: "1d"}, []string{
+			"response(table): Priority:string Count:int64 MeanHours:float64 MedianHours:float64 P90Hours:float64",
+			"series(graph timeseries-multi 0.1): Time:time.Time MeanHours:*float64",
+		}},
+		{queryModel{Metric: "reviewLatency", ReviewStatus: "Review"}, []string{
+			"response(table): IssueKey:string IssueType:*string Project:*string EnteredAt:time.Time ReviewedAt:*time.Time Reviewer:*string LatencyHours:*float64",
+			"summary(table): " + summaryColumns,
+		}},
+		{queryModel{Metric: "statusFlow", FlowStatus: "Review", Interval: "1d"}, []string{"response(graph timeseries-wide 0.1): Time:time.Time EnteredCount:int64 ExitedCount:int64"}},
+		{queryModel{Metric: "agingWip"}, []string{"response(table): IssueKey:string IssueType:*string Project:*string Status:*string Started:time.Time Age:float64"}},
+		{queryModel{Metric: "wip", Interval: "1d", SplitByIssueType: true}, []string{"response(graph timeseries-multi 0.1): Time:time.Time WIP:int64"}},
+		{queryModel{Metric: "links"}, []string{"response(table): SourceKey:string LinkType:string Direction:string Relation:string TargetKey:string TargetStatus:*string"}},
+		{queryModel{Metric: "links", Format: formatNodeGraph}, []string{
+			"nodes(nodeGraph): id:string title:string subtitle:string detail__summary:string color:string mainstat:string",
+			"edges(nodeGraph): id:string source:string target:string mainstat:string",
+		}},
+		{queryModel{Metric: "backlogGrowth", Interval: "1d"}, []string{"response(graph timeseries-wide 0.1): Time:time.Time Created:int64 Resolved:int64 Backlog:int64"}},
+		{queryModel{Metric: "cohort"}, []string{"response(table): CreatedWeek:string ResolvedWeek:string Count:int64"}},
+	}
+	for _, tt := range tests {
+		if !containsString(metricNames, tt.qm.Metric) {
+			t.Errorf("%s: missing from the metricNames of the query duration", tt.qm.Metric)
+		}
+		res := ds.buildFrames(context.Background(), nil, &models.PluginSettings{}, tt.qm, testTimeRange(), nil)
+		if res.Error != nil {
+			t.Errorf("%s %s: %v", tt.qm.Metric, tt.qm.Format, res.Error)
+			continue
+		}
+		setFrameHints(&res)
+		if got := frameSchemas(res); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s %s: expected\n%s\ngot\n%s", tt.qm.Metric, tt.qm.Format, strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+
+	sprint := &jira.Sprint{ID: 7, Name: "Sprint 7", StartDate: "2024-01-08T09:00:00.000Z", EndDate: "2024-01-12T17:00:00.000Z"}
+	now := time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)
+	for name, tt := range map[string]struct {
+		res  backend.DataResponse
+		want []string
+	}{
+		"sprintChurn": {sprintChurnFrames(nil, nil, sprint, queryModel{}, now), []string{
+			"response(table): IssueKey:string Summary:*string IssueType:*string Scope:string AddedAt:*time.Time Removed:bool RemovedAt:*time.Time StoryPoints:*float64",
+			"summary(table): Committed:int64 Added:int64 Removed:int64 CommittedPoints:float64 AddedPoints:float64 RemovedPoints:float64",
+		}},
+		"burndown": {burndownFrame(nil, nil, sprint, queryModel{}, burndownIssueCount, now), []string{"response(graph timeseries-wide 0.1): Time:time.Time Remaining:*float64 Ideal:float64"}},
+	} {
+		setFrameHints(&tt.res)
+		if got := frameSchemas(tt.res); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: expected\n%s\ngot\n%s", name, strings.Join(tt.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// maxEnumValues is the most distinct values a column may have to be sent as an
+// enum, beyond that the lookup table outweighs the savings.
+const maxEnumValues = 1000
+
+// enumColumns are the columns of the large tables that repeat a handful of
+// values, e.g. the same status names in thousands of change log rows.
+var enumColumns = map[string][]string{
+	"jql":          {"Status", "IssueType", "Project"},
+	"changelogRaw": {"IssueType", "field", "fromValue", "toValue", "Author"},
+	"cycletime":    {"IssueType", "Project", "StartStatus", "EndStatus"},
+}
+
+// compactColumns sends the enumColumns of the metric's table as enum fields:
+// every row holds the index of its value, and the values are listed once in
+// the field config, which Grafana resolves when displaying them. Columns with
+// more than maxEnumValues distinct values, or whose values mostly don't repeat,
+// stay strings.
+func compactColumns(res *backend.DataResponse, metric string) {
+	for _, frame := range res.Frames {
+		if frame.Name != "response" {
+			continue
+		}
+		for i, field := range frame.Fields {
+			if containsString(enumColumns[metric], field.Name) {
+				if enum := enumField(field); enum != nil {
+					frame.Fields[i] = enum
+				}
+			}
+		}
+	}
+}
+
+// enumField returns field as an enum field, nil if it isn't a low-cardinality
+// string column. Null values stay null.
+func enumField(field *data.Field) *data.Field {
+	if field.Type() != data.FieldTypeString && field.Type() != data.FieldTypeNullableString {
+		return nil
+	}
+	values := map[string]bool{}
+	for i := 0; i < field.Len(); i++ {
+		if v, ok := field.ConcreteAt(i); ok {
+			values[v.(string)] = true
+		}
+	}
+	if len(values) > maxEnumValues || 2*len(values) > field.Len() {
+		return nil
+	}
+
+	text := make([]string, 0, len(values))
+	for v := range values {
+		text = append(text, v)
+	}
+	sort.Strings(text)
+	index := make(map[string]data.EnumItemIndex, len(text))
+	for i, v := range text {
+		index[v] = data.EnumItemIndex(i)
+	}
+
+	var enum *data.Field
+	if field.Type() == data.FieldTypeString {
+		indexes := make([]data.EnumItemIndex, field.Len())
+		for i := range indexes {
+			indexes[i] = index[field.At(i).(string)]
+		}
+		enum = data.NewField(field.Name, field.Labels, indexes)
+	} else {
+		indexes := make([]*data.EnumItemIndex, field.Len())
+		for i := range indexes {
+			if v, ok := field.ConcreteAt(i); ok {
+				idx := index[v.(string)]
+				indexes[i] = &idx
+			}
+		}
+		enum = data.NewField(field.Name, field.Labels, indexes)
+	}
+
+	config := data.FieldConfig{}
+	if field.Config != nil {
+		config = *field.Config
+	}
+	config.TypeConfig = &data.FieldTypeConfig{Enum: &data.EnumFieldConfig{Text: text}}
+	enum.Config = &config
+	return enum
+}
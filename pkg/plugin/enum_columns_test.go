@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestCompactColumns(t *testing.T) {
+	// 2000 issues with 10 status changes each, 20k change log rows.
+	statuses := []string{"To Do", "In Progress", "Code Review", "QA", "Ready for Release", "Done"}
+	var issues []jira.Issue
+	for i := 0; i < 2000; i++ {
+		var transitions [][3]string
+		for j := 0; j < 10; j++ {
+			transitions = append(transitions, [3]string{
+				fmt.Sprintf("2024-01-%02dT%02d:00:00.000+0000", 2+j, i%24),
+				statuses[j%len(statuses)], statuses[(j+1)%len(statuses)],
+			})
+		}
+		issues = append(issues, newTestIssue(fmt.Sprintf("PLAT-%d", i+1), []string{"Story", "Bug", "Task"}[i%3], transitions...))
+	}
+	qm := queryModel{Metric: "changelogRaw", MaxRows: 50000}
+	res := (&Datasource{}).getChangelogRawData(issues, qm)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	original := res.Frames[0]
+	if original.Rows() != 20000 {
+		t.Fatalf("expected 20000 rows, got %d", original.Rows())
+	}
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalArrow, err := original.MarshalArrow()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compact := backend.DataResponse{Frames: data.Frames{copyResponse(res).Frames[0]}}
+	compactColumns(&compact, qm.Metric)
+	frame := compact.Frames[0]
+	compactJSON, err := json.Marshal(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compactArrow, err := frame.MarshalArrow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("JSON %d -> %d bytes, Arrow %d -> %d bytes", len(originalJSON), len(compactJSON), len(originalArrow), len(compactArrow))
+	if 10*len(compactJSON) > 6*len(originalJSON) || 10*len(compactArrow) > 6*len(originalArrow) {
+		t.Errorf("expected the frame to shrink by 40%% at least, got JSON %d -> %d bytes, Arrow %d -> %d bytes", len(originalJSON), len(compactJSON), len(originalArrow), len(compactArrow))
+	}
+
+	// Unique keys stay strings, the repeated columns resolve to the same values.
+	if frame.Fields[0].Type() != data.FieldTypeString {
+		t.Errorf("expected IssueKey to stay a string column, got %s", frame.Fields[0].Type())
+	}
+	for _, name := range []string{"IssueType", "field", "fromValue", "toValue"} {
+		field, idx := frame.FieldByName(name)
+		if field.Type().NullableType() != data.FieldTypeNullableEnum {
+			t.Errorf("expected %s to be an enum, got %s", name, field.Type())
+			continue
+		}
+		text := field.Config.TypeConfig.Enum.Text
+		for row := 0; row < frame.Rows(); row++ {
+			want, _ := original.Fields[idx].ConcreteAt(row)
+			got, ok := field.ConcreteAt(row)
+			if !ok || text[got.(data.EnumItemIndex)] != want {
+				t.Fatalf("%s row %d: expected %v, got %v", name, row, want, got)
+			}
+		}
+	}
+
+	// The JSON round trip keeps the enum, as Grafana reads it.
+	var decoded data.Frame
+	if err := json.Unmarshal(compactJSON, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if field, _ := decoded.FieldByName("toValue"); field.Type().NullableType() != data.FieldTypeNullableEnum || len(field.Config.TypeConfig.Enum.Text) != len(statuses) {
+		t.Errorf("expected toValue to decode as an enum of %d statuses", len(statuses))
+	}
+}
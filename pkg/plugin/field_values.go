@@ -0,0 +1,135 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// maxFieldValueIssues caps how many issues /field-values scans for distinct values.
+const maxFieldValueIssues = 1000
+
+type fieldValuesResponse struct {
+	Values []string `json:"values"`
+	// Scanned is the number of issues looked at, 0 when a dedicated endpoint was used.
+	Scanned   int  `json:"scanned"`
+	Truncated bool `json:"truncated"`
+}
+
+// handleFieldValues returns the sorted distinct values of a field, e.g.
+// /field-values?field=labels&jql=project=PLAT. Labels (without a JQL scope) and
+// the components and versions of a single ?project come from their dedicated
+// endpoints; any other field is collected by scanning the matching issues.
+func (d *Datasource) handleFieldValues(w http.ResponseWriter, r *http.Request) {
+	field := r.URL.Query().Get("field")
+	if field == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing field parameter"))
+		return
+	}
+	jql := r.URL.Query().Get("jql")
+	project := r.URL.Query().Get("project")
+
+	client, _, err := d.clientFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	var res fieldValuesResponse
+	switch {
+	case field == "labels" && jql == "":
+		res.Values, err = client.GetLabels(r.Context())
+	case (field == "components" || field == "fixVersions" || field == "versions") && project != "" && jql == "":
+		var values []jira.NamedValue
+		if field == "components" {
+			values, err = client.GetProjectComponents(r.Context(), project)
+		} else {
+			values, err = client.GetProjectVersions(r.Context(), project)
+		}
+		for _, v := range values {
+			res.Values = append(res.Values, v.Name)
+		}
+	default:
+		res, err = scanFieldValues(r.Context(), client, field, jql, project)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	res.Values = distinctSorted(res.Values)
+	writeJSON(w, http.StatusOK, res)
+}
+
+// scanFieldValues pages through the issues matching jql, requesting only field.
+func scanFieldValues(ctx context.Context, client *jira.Client, field, jql, project string) (fieldValuesResponse, error) {
+	if jql == "" && project != "" {
+		jql = "project = " + jira.QuoteJQL(project)
+	}
+	if jql == "" {
+		// The search endpoint refuses unbounded queries.
+		jql = "created is not EMPTY ORDER BY created DESC"
+	}
+
+	issues, _, err := client.SearchChangelogs(ctx, jql, jira.SearchOptions{
+		Fields:        []string{field},
+		SkipChangelog: true,
+		MaxIssues:     maxFieldValueIssues + 1,
+	})
+	if err != nil {
+		return fieldValuesResponse{}, err
+	}
+
+	res := fieldValuesResponse{Truncated: len(issues) > maxFieldValueIssues}
+	if res.Truncated {
+		issues = issues[:maxFieldValueIssues]
+	}
+	res.Scanned = len(issues)
+
+	for _, issue := range issues {
+		res.Values = append(res.Values, fieldValueStrings(issue.Fields[field])...)
+	}
+	return res, nil
+}
+
+// fieldValueStrings flattens a field value into display strings: plain values as
+// they are, objects by their name/value/displayName/key, and arrays element-wise.
+func fieldValueStrings(v interface{}) []string {
+	switch value := v.(type) {
+	case string:
+		return []string{value}
+	case float64:
+		return []string{fmt.Sprintf("%g", value)}
+	case bool:
+		return []string{fmt.Sprintf("%t", value)}
+	case []interface{}:
+		var values []string
+		for _, element := range value {
+			values = append(values, fieldValueStrings(element)...)
+		}
+		return values
+	case map[string]interface{}:
+		for _, key := range []string{"name", "value", "displayName", "key"} {
+			if s, ok := value[key].(string); ok {
+				return []string{s}
+			}
+		}
+	}
+	return nil
+}
+
+func distinctSorted(values []string) []string {
+	seen := map[string]bool{}
+	distinct := []string{}
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			distinct = append(distinct, v)
+		}
+	}
+	sort.Strings(distinct)
+	return distinct
+}
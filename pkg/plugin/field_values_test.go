@@ -0,0 +1,55 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// newResourceRequest builds a resource request whose datasource points at jiraURL.
+func newResourceRequest(t *testing.T, jiraURL, target string) *http.Request {
+	t.Helper()
+	settings := &backend.DataSourceInstanceSettings{
+		JSONData:                []byte(fmt.Sprintf(`{"url":%q,"username":"user"}`, jiraURL)),
+		DecryptedSecureJSONData: map[string]string{"token": "token"},
+	}
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	ctx := backend.WithPluginContext(req.Context(), backend.PluginContext{DataSourceInstanceSettings: settings})
+	return req.WithContext(ctx)
+}
+
+func TestFieldValuesScan(t *testing.T) {
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/search/jql" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"issues":[
+			{"key":"A-1","fields":{"components":[{"name":"web"},{"name":"api"}]}},
+			{"key":"A-2","fields":{"components":[{"name":"api"}]}},
+			{"key":"A-3","fields":{}}
+		]}`)
+	}))
+	defer jiraServer.Close()
+
+	ds := &Datasource{}
+	rec := httptest.NewRecorder()
+	ds.newResourceMux().ServeHTTP(rec, newResourceRequest(t, jiraServer.URL, "/field-values?field=components&jql=project%3DA"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res fieldValuesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Values) != 2 || res.Values[0] != "api" || res.Values[1] != "web" {
+		t.Errorf("expected [api web], got %v", res.Values)
+	}
+	if res.Scanned != 3 || res.Truncated {
+		t.Errorf("expected 3 scanned issues without truncation, got %d/%v", res.Scanned, res.Truncated)
+	}
+}
@@ -0,0 +1,241 @@
+package plugin
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// jiraTimeLayout is the timestamp format Jira uses for changelog and date-time fields.
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// jqlTimeLayout is the date-time format accepted in JQL clauses.
+const jqlTimeLayout = "2006-01-02 15:04"
+
+// parseJiraTime parses a Jira timestamp such as "2024-01-31T10:15:00.000+0000".
+func parseJiraTime(value string) (time.Time, error) {
+	return time.Parse(jiraTimeLayout, value)
+}
+
+// containsString reports whether value is one of values.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// optionalString returns value for a nullable string field, nil when it is empty.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// timeField returns a date-time field of the issue such as resolutiondate, or
+// nil when it is missing or empty.
+func timeField(issue jira.Issue, field string) *time.Time {
+	raw, _ := issue.Fields[field].(string)
+	t, err := parseJiraTime(raw)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+// issueTypeName returns the name of the issue type, or "" if the field is missing.
+func issueTypeName(issue jira.Issue) string {
+	if it, ok := issue.Fields["issuetype"].(map[string]interface{}); ok {
+		if name, ok := it["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// isSubtask reports whether the issue type of the issue is a subtask type.
+func isSubtask(issue jira.Issue) bool {
+	if it, ok := issue.Fields["issuetype"].(map[string]interface{}); ok {
+		subtask, _ := it["subtask"].(bool)
+		return subtask
+	}
+	return false
+}
+
+// withoutSubtasks returns the issues that aren't subtasks and how many were left out.
+func withoutSubtasks(issues []jira.Issue) ([]jira.Issue, int) {
+	var kept []jira.Issue
+	for _, issue := range issues {
+		if !isSubtask(issue) {
+			kept = append(kept, issue)
+		}
+	}
+	return kept, len(issues) - len(kept)
+}
+
+// issueStatus returns the name of the current status, or "" if the field is missing.
+func issueStatus(issue jira.Issue) string {
+	if st, ok := issue.Fields["status"].(map[string]interface{}); ok {
+		if name, ok := st["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// filterByCurrentStatus keeps the issues currently in one of statuses, all of
+// them if statuses is empty.
+func filterByCurrentStatus(issues []jira.Issue, statuses []string) []jira.Issue {
+	if len(statuses) == 0 {
+		return issues
+	}
+	var kept []jira.Issue
+	for _, issue := range issues {
+		if listHas(statuses, issueStatus(issue)) {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+// projectKey returns the project key of the issue, falling back to the project name.
+func projectKey(issue jira.Issue) string {
+	if p, ok := issue.Fields["project"].(map[string]interface{}); ok {
+		if key, ok := p["key"].(string); ok {
+			return key
+		} else if name, ok := p["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// issueLabels returns the labels of the issue.
+func issueLabels(issue jira.Issue) []string {
+	var labels []string
+	values, _ := issue.Fields["labels"].([]interface{})
+	for _, v := range values {
+		if label, ok := v.(string); ok && label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+// statusSince returns when the issue entered its current status: the last
+// status change in its changelog, or when it was created if it never moved.
+func statusSince(issue jira.Issue) *time.Time {
+	if changes := statusChanges(issue); len(changes) > 0 {
+		since := changes[len(changes)-1].at
+		return &since
+	}
+	return timeField(issue, "created")
+}
+
+// affectsVersions returns the names of the affects versions of the issue.
+func affectsVersions(issue jira.Issue) []string {
+	var names []string
+	versions, _ := issue.Fields["versions"].([]interface{})
+	for _, v := range versions {
+		if version, ok := v.(map[string]interface{}); ok {
+			if name, ok := version["name"].(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// issueEnvironment returns the environment of the issue as plain text: the
+// rendered field when renderedFields was expanded, since Jira Cloud returns the
+// field itself as a document, or the plain field of Jira Server / Data Center.
+func issueEnvironment(issue jira.Issue) string {
+	if html, ok := issue.RenderedFields["environment"].(string); ok {
+		return htmlToText(html)
+	}
+	environment, _ := issue.Fields["environment"].(string)
+	return environment
+}
+
+// countField returns a numeric property of an object field, such as
+// watches.watchCount, or nil when the field is missing.
+func countField(issue jira.Issue, field, property string) *int64 {
+	if obj, ok := issue.Fields[field].(map[string]interface{}); ok {
+		if n, ok := obj[property].(float64); ok {
+			count := int64(n)
+			return &count
+		}
+	}
+	return nil
+}
+
+// datedHistory is a changelog history with its parsed creation time.
+type datedHistory struct {
+	at      time.Time
+	history jira.History
+}
+
+// chronologicalHistories returns the changelog histories of the issue oldest
+// first. Histories created at the same time, e.g. by bulk transitions, are
+// ordered by their ids, which Jira assigns in increasing order. Histories with
+// an unparseable time are left out.
+func chronologicalHistories(issue jira.Issue) []datedHistory {
+	if issue.Changelog == nil {
+		return nil
+	}
+	var histories []datedHistory
+	for _, history := range issue.Changelog.Histories {
+		createdTime, err := parseJiraTime(history.Created)
+		if err != nil {
+			continue
+		}
+		histories = append(histories, datedHistory{at: createdTime, history: history})
+	}
+	sort.SliceStable(histories, func(i, j int) bool {
+		if !histories[i].at.Equal(histories[j].at) {
+			return histories[i].at.Before(histories[j].at)
+		}
+		return historyIDLess(histories[i].history.ID, histories[j].history.ID)
+	})
+	return histories
+}
+
+// historyIDLess orders changelog history ids numerically, or as strings if
+// they aren't numbers.
+func historyIDLess(a, b string) bool {
+	na, errA := strconv.ParseInt(a, 10, 64)
+	nb, errB := strconv.ParseInt(b, 10, 64)
+	if errA == nil && errB == nil {
+		return na < nb
+	}
+	return a < b
+}
+
+// statusChange is a single status transition taken from an issue's changelog.
+type statusChange struct {
+	at   time.Time
+	from string
+	to   string
+	// author made the change, nil when Jira doesn't say.
+	author *jira.User
+}
+
+// statusChanges returns every status transition of the issue in chronological order,
+// regardless of the order Jira returned the changelog histories in, see
+// chronologicalHistories.
+func statusChanges(issue jira.Issue) []statusChange {
+	var changes []statusChange
+	for _, dated := range chronologicalHistories(issue) {
+		for _, item := range dated.history.Items {
+			if item.Field == "status" {
+				changes = append(changes, statusChange{at: dated.at, from: item.FromString, to: item.ToString, author: dated.history.Author})
+			}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,177 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// statusOrder maps statuses to their position in the workflow. Statuses of the
+// same board column share a position, moving between them is not going back.
+type statusOrder map[string]int
+
+// firstTimeRightOrder returns the workflow order of the firstTimeRight metric:
+// the statusOrder option, or the columns of the board of the boardId option.
+// Failed requests to Jira are returned as a *jiraRequestError.
+func (d *Datasource) firstTimeRightOrder(ctx context.Context, client *jira.Client, qm queryModel) (statusOrder, error) {
+	if statuses := parseList(qm.StatusOrder); len(statuses) > 0 {
+		order := statusOrder{}
+		for i, status := range statuses {
+			order[status] = i
+		}
+		return order, nil
+	}
+	if qm.BoardID <= 0 {
+		return nil, fmt.Errorf("firstTimeRight requires a statusOrder or a boardId")
+	}
+
+	columns, err := client.GetBoardColumns(ctx, qm.BoardID)
+	if err != nil {
+		return nil, &jiraRequestError{prefix: fmt.Sprintf("jira board %d fetch failed", qm.BoardID), err: err}
+	}
+	statuses, err := d.statuses(ctx, client)
+	if err != nil {
+		return nil, &jiraRequestError{prefix: "jira status fetch failed", err: err}
+	}
+	names := map[string]string{}
+	for _, status := range statuses {
+		names[status.ID] = status.Name
+	}
+	order := statusOrder{}
+	for i, column := range columns {
+		for _, status := range column.Statuses {
+			if name, ok := names[status.ID]; ok {
+				order[name] = i
+			}
+		}
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("board %d has no statuses mapped to its columns", qm.BoardID)
+	}
+	return order, nil
+}
+
+// endStatuses are the statuses that complete an issue: the endStatus option, or
+// the last position of the order.
+func (o statusOrder) endStatuses(qm queryModel) []string {
+	if statuses := parseList(qm.EndStatus); len(statuses) > 0 {
+		return statuses
+	}
+	last := -1
+	for _, i := range o {
+		last = max(last, i)
+	}
+	var statuses []string
+	for status, i := range o {
+		if i == last {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// reentered returns the first status of the order that changes up to until went
+// back to, after the issue had been further along, or "" for issues that only
+// moved forward. Statuses outside the order are ignored.
+func (o statusOrder) reentered(changes []statusChange, until time.Time) string {
+	furthest := -1
+	for _, change := range changes {
+		if change.at.After(until) {
+			break
+		}
+		if i, ok := o[change.from]; ok {
+			furthest = max(furthest, i)
+		}
+		if i, ok := o[change.to]; ok {
+			if i < furthest {
+				return change.to
+			}
+			furthest = max(furthest, i)
+		}
+	}
+	return ""
+}
+
+// getFirstTimeRightData lists the issues completed in the time range (counted
+// once at the latest transition into an end status) with whether they got there
+// first time right, without ever going back to an earlier status of the order,
+// and the status they went back to otherwise. A "summary" frame has the
+// completed and first time right issues per bucket, and their percentage, null
+// for buckets without completions.
+func (d *Datasource) getFirstTimeRightData(ctx context.Context, client *jira.Client, issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	order, err := d.firstTimeRightOrder(ctx, client, qm)
+	if err != nil {
+		return queryErrorResponse(err)
+	}
+	return firstTimeRightFrames(issues, order, qm, timeRange)
+}
+
+// firstTimeRightFrames builds the frames of getFirstTimeRightData for the
+// workflow order.
+func firstTimeRightFrames(issues []jira.Issue, order statusOrder, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	size, err := bucketSize(qm, timeRange)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	buckets := bucketStarts(timeRange, size, qm.loc())
+	completed := make([]int64, len(buckets))
+	clean := make([]int64, len(buckets))
+
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Completed", nil, []time.Time{}),
+		data.NewField("FirstTimeRight", nil, []bool{}),
+		data.NewField("ReenteredStatus", nil, []*string{}),
+	)
+
+	endStatuses := order.endStatuses(qm)
+	for _, issue := range issues {
+		changes := statusChanges(issue)
+		var completedAt time.Time
+		for _, change := range changes {
+			if listHas(endStatuses, change.to) && !change.at.Before(timeRange.From) && !change.at.After(timeRange.To) {
+				completedAt = change.at
+			}
+		}
+		i, ok := bucketIndex(timeRange, buckets, completedAt)
+		if !ok {
+			continue
+		}
+
+		reentered := order.reentered(changes, completedAt)
+		completed[i]++
+		if reentered == "" {
+			clean[i]++
+		}
+		frame.AppendRow(issue.Key, optionalString(issueTypeName(issue)), completedAt, reentered == "", optionalString(reentered))
+	}
+	if err := sortFrame(frame, qm, "Completed", sortDesc, "IssueKey"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	percents := make([]*float64, len(buckets))
+	for i := range buckets {
+		if completed[i] > 0 {
+			percent := float64(clean[i]) / float64(completed[i]) * 100
+			percents[i] = &percent
+		}
+	}
+	summary := data.NewFrame("summary",
+		data.NewField("Time", nil, buckets),
+		data.NewField("Completed", nil, completed),
+		data.NewField("FirstTimeRight", nil, clean),
+		data.NewField("PercentFirstTimeRight", nil, percents),
+	)
+	summary.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesWide})
+	setBucketInterval(summary, size)
+
+	response.Frames = append(response.Frames, frame, summary)
+	return response
+}
@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestFirstTimeRight(t *testing.T) {
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Review"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "Review", "Done"},
+		),
+		// Sent back from review.
+		newTestIssue("T-2", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Review"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "Review", "In Progress"},
+			[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		// Skipping statuses and passing through one outside the order is fine.
+		newTestIssue("T-3", "Bug",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "Blocked"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "Blocked", "Review"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "Review", "Done"},
+		),
+		// Not completed.
+		newTestIssue("T-4", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		),
+	}
+
+	qm := queryModel{Metric: "firstTimeRight", StatusOrder: "To Do,In Progress,Review,Done", Interval: "1w"}
+	order, err := (&Datasource{}).firstTimeRightOrder(context.Background(), nil, qm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := firstTimeRightFrames(issues, order, qm, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+
+	table := res.Frames[0]
+	if table.Rows() != 3 {
+		t.Fatalf("expected the 3 completed issues, got %d", table.Rows())
+	}
+	for i := 0; i < table.Rows(); i++ {
+		key := table.Fields[0].At(i).(string)
+		clean := table.Fields[3].At(i).(bool)
+		reentered := table.Fields[4].At(i).(*string)
+		if clean != (key != "T-2") || (key == "T-2") != (reentered != nil && *reentered == "In Progress") {
+			t.Errorf("%s: unexpected FirstTimeRight %v, reentered %v", key, clean, reentered)
+		}
+	}
+
+	// All three were completed in the first week of 2024.
+	summary := res.Frames[1]
+	percent := summary.Fields[3].At(0).(*float64)
+	if summary.Fields[1].At(0).(int64) != 3 || percent == nil || *percent < 66.6 || *percent > 66.7 {
+		t.Errorf("expected 2 of 3 first time right in the first bucket, got %v", percent)
+	}
+	if summary.Fields[3].At(1).(*float64) != nil {
+		t.Error("expected no percentage for a bucket without completions")
+	}
+
+	if _, err := (&Datasource{}).firstTimeRightOrder(context.Background(), nil, queryModel{Metric: "firstTimeRight"}); err == nil {
+		t.Error("expected an error without statusOrder and boardId")
+	}
+}
+
+func TestFirstTimeRightBoardOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/agile/1.0/board/7/configuration":
+			fmt.Fprint(w, `{"columnConfig":{"columns":[
+				{"name":"Backlog","statuses":[{"id":"1"}]},
+				{"name":"Doing","statuses":[{"id":"3"},{"id":"4"}]},
+				{"name":"Done","statuses":[{"id":"5"}]}]}}`)
+		case "/rest/api/3/status":
+			fmt.Fprint(w, `[{"id":"1","name":"To Do"},{"id":"3","name":"In Progress"},{"id":"4","name":"Review"},{"id":"5","name":"Done"}]`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	qm := queryModel{Metric: "firstTimeRight", BoardID: 7}
+	order, err := (&Datasource{}).firstTimeRightOrder(context.Background(), jira.NewClient(server.URL, "user", "token", ""), qm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Statuses of a column share its position, and the last column completes issues.
+	if order["In Progress"] != 1 || order["Review"] != 1 || order["Done"] != 2 {
+		t.Errorf("unexpected order %v", order)
+	}
+	if ends := order.endStatuses(qm); len(ends) != 1 || ends[0] != "Done" {
+		t.Errorf("expected the last column to complete issues, got %v", ends)
+	}
+	issue := newTestIssue("T-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "Review"},
+		[3]string{"2024-01-03T10:00:00.000+0000", "Review", "In Progress"},
+	)
+	if got := order.reentered(statusChanges(issue), testTimeRange().To); got != "" {
+		t.Errorf("expected moving within a column to be first time right, got %q", got)
+	}
+}
+
+func TestFirstTimeRightOrderErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	client := jira.NewClient(server.URL, "user", "token", "")
+
+	for _, tc := range []struct {
+		qm   queryModel
+		want backend.Status
+	}{
+		{queryModel{Metric: "firstTimeRight"}, backend.StatusBadRequest},
+		{queryModel{Metric: "firstTimeRight", BoardID: 7}, backend.StatusInternal},
+	} {
+		if res := (&Datasource{}).getFirstTimeRightData(context.Background(), client, nil, tc.qm, testTimeRange()); res.Status != tc.want {
+			t.Errorf("board %d: expected status %d, got %d (%v)", tc.qm.BoardID, tc.want, res.Status, res.Error)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// timeNow is swapped out in tests that depend on the current time.
+var timeNow = time.Now
+
+// getTimeToFirstTransitionData measures how long each issue created in the time
+// range sat in its initial status, i.e. the delta between `created` and its first
+// status change. Issues that were never transitioned are still listed with their
+// age so far and StillUntouched set, so they count towards the quantile as well.
+func (d *Datasource) getTimeToFirstTransitionData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Project", nil, []*string{}),
+		data.NewField("Created", nil, []time.Time{}),
+		data.NewField("FirstTransitionAt", nil, []*time.Time{}),
+		data.NewField("HoursToFirstTransition", nil, []float64{}),
+		data.NewField("StillUntouched", nil, []bool{}),
+		data.NewField("Quantile", nil, []float64{}),
+	)
+
+	now := timeNow()
+	var hours []float64
+
+	for _, issue := range issues {
+		createdRaw, _ := issue.Fields["created"].(string)
+		created, err := parseJiraTime(createdRaw)
+		if err != nil {
+			continue
+		}
+		if created.Before(timeRange.From) || created.After(timeRange.To) {
+			continue
+		}
+
+		var firstTransition *time.Time
+		if issue.Changelog != nil {
+			for _, history := range issue.Changelog.Histories {
+				historyCreated, err := parseJiraTime(history.Created)
+				if err != nil {
+					continue
+				}
+				for _, item := range history.Items {
+					if item.Field != "status" {
+						continue
+					}
+					// Don't rely on the history order, just keep the earliest one.
+					if firstTransition == nil || historyCreated.Before(*firstTransition) {
+						t := historyCreated
+						firstTransition = &t
+					}
+				}
+			}
+		}
+
+		end := now
+		if firstTransition != nil {
+			end = *firstTransition
+		}
+		waited := end.Sub(created).Hours()
+
+		frame.AppendRow(
+			issue.Key,
+			optionalString(issueTypeName(issue)),
+			optionalString(projectKey(issue)),
+			created,
+			firstTransition,
+			waited,
+			firstTransition == nil,
+			0.0,
+		)
+		hours = append(hours, waited)
+	}
+
+	quantileValue := quantile(hours, qm.Quantile, qm.QuantileMethod)
+	for i := 0; i < frame.Rows(); i++ {
+		frame.Fields[7].Set(i, quantileValue)
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
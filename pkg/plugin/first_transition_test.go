@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestTimeToFirstTransition(t *testing.T) {
+	now := time.Date(2024, 1, 20, 10, 0, 0, 0, time.UTC)
+	defer func(original func() time.Time) { timeNow = original }(timeNow)
+	timeNow = func() time.Time { return now }
+
+	created := func(issue jira.Issue, at string) jira.Issue {
+		issue.Fields["created"] = at
+		return issue
+	}
+	issues := []jira.Issue{
+		// The histories aren't in order, the earliest one counts.
+		created(newTestIssue("T-1", "Story",
+			[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "To Do", "In Progress"},
+		), "2024-01-02T10:00:00.000+0000"),
+		// Still in its first status, aged until now.
+		created(newTestIssue("T-2", "Story"), "2024-01-18T10:00:00.000+0000"),
+		// Created before the time range.
+		created(newTestIssue("T-3", "Story"), "2023-12-01T10:00:00.000+0000"),
+	}
+
+	res := (&Datasource{}).getTimeToFirstTransitionData(issues, queryModel{Quantile: 100}, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("expected the 2 issues created in the range, got %d", frame.Rows())
+	}
+
+	hours, _ := frame.FieldByName("HoursToFirstTransition")
+	untouched, _ := frame.FieldByName("StillUntouched")
+	at, _ := frame.FieldByName("FirstTransitionAt")
+	if got := hours.At(0).(float64); got != 24 || untouched.At(0).(bool) {
+		t.Errorf("expected T-1 to wait 24 hours, got %v", got)
+	}
+	if got := at.At(0).(*time.Time); got == nil || got.Day() != 3 {
+		t.Errorf("expected T-1's first transition on the 3rd, got %v", got)
+	}
+	if got := hours.At(1).(float64); got != 48 || !untouched.At(1).(bool) {
+		t.Errorf("expected T-2 to be untouched for 48 hours until now, got %v", got)
+	}
+	if got := at.At(1).(*time.Time); got != nil {
+		t.Errorf("expected no first transition for T-2, got %v", got)
+	}
+
+	q, _ := frame.FieldByName("Quantile")
+	if got := q.At(0).(float64); got != 48 {
+		t.Errorf("expected the untouched issue to count towards the quantile, got %v", got)
+	}
+}
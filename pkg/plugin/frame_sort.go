@@ -0,0 +1,149 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	sortAsc  = "asc"
+	sortDesc = "desc"
+)
+
+// sortFrame sorts the rows of frame by the sortBy column, falling back to
+// defaultSortBy/defaultOrder when the query doesn't request a sort. Ties are
+// broken on keyField so that the order is stable between refreshes.
+func sortFrame(frame *data.Frame, qm queryModel, defaultSortBy, defaultOrder, keyField string) error {
+	sortBy, order := qm.SortBy, qm.SortOrder
+	if sortBy == "" {
+		sortBy = defaultSortBy
+		if order == "" {
+			order = defaultOrder
+		}
+	}
+	if sortBy == "" {
+		return nil
+	}
+
+	switch strings.ToLower(order) {
+	case "", sortAsc:
+		order = sortAsc
+	case sortDesc:
+		order = sortDesc
+	default:
+		return fmt.Errorf("unknown sort order: %s", qm.SortOrder)
+	}
+
+	sortField, sortIdx := frame.FieldByName(sortBy)
+	if sortIdx == -1 {
+		return fmt.Errorf("unknown sort column: %s", sortBy)
+	}
+	keys, _ := frame.FieldByName(keyField)
+
+	rows := make([]int, frame.Rows())
+	for i := range rows {
+		rows[i] = i
+	}
+	sort.SliceStable(rows, func(a, b int) bool {
+		c := compareValues(sortField.At(rows[a]), sortField.At(rows[b]))
+		if order == sortDesc {
+			c = -c
+		}
+		if c == 0 && keys != nil {
+			c = compareValues(keys.At(rows[a]), keys.At(rows[b]))
+		}
+		return c < 0
+	})
+
+	for i, field := range frame.Fields {
+		sorted := data.NewFieldFromFieldType(field.Type(), len(rows))
+		sorted.Name = field.Name
+		sorted.Labels = field.Labels
+		sorted.Config = field.Config
+		for to, from := range rows {
+			sorted.Set(to, field.At(from))
+		}
+		frame.Fields[i] = sorted
+	}
+	return nil
+}
+
+// compareValues orders two values of the same frame field. Null values of
+// nullable fields sort after everything else.
+func compareValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		return strings.Compare(av, b.(string))
+	case *string:
+		if bv := b.(*string); av != nil && bv != nil {
+			return strings.Compare(*av, *bv)
+		}
+	case float64:
+		return compareOrdered(av, b.(float64))
+	case *float64:
+		if bv := b.(*float64); av != nil && bv != nil {
+			return compareOrdered(*av, *bv)
+		}
+	case int64:
+		return compareOrdered(av, b.(int64))
+	case *int64:
+		if bv := b.(*int64); av != nil && bv != nil {
+			return compareOrdered(*av, *bv)
+		}
+	case time.Time:
+		return av.Compare(b.(time.Time))
+	case *time.Time:
+		if bv := b.(*time.Time); av != nil && bv != nil {
+			return av.Compare(*bv)
+		}
+	case bool:
+		bv := b.(bool)
+		if av == bv {
+			return 0
+		} else if !av {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+
+	// One of the nullable values is nil.
+	aNil, bNil := isNilValue(a), isNilValue(b)
+	switch {
+	case aNil && bNil:
+		return 0
+	case aNil:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func isNilValue(v interface{}) bool {
+	switch p := v.(type) {
+	case *string:
+		return p == nil
+	case *float64:
+		return p == nil
+	case *int64:
+		return p == nil
+	case *time.Time:
+		return p == nil
+	}
+	return v == nil
+}
@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func newSortTestFrame() *data.Frame {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{"T-3", "T-1", "T-2"}),
+		data.NewField("Created", nil, []time.Time{t0, t0.Add(time.Hour), t0}),
+		data.NewField("CycleTime", nil, []float64{10, 2, 10}),
+	)
+}
+
+func TestSortFrame(t *testing.T) {
+	tests := []struct {
+		name     string
+		qm       queryModel
+		expected []string
+	}{
+		{"default keeps order", queryModel{}, []string{"T-3", "T-1", "T-2"}},
+		{"string asc", queryModel{SortBy: "IssueKey"}, []string{"T-1", "T-2", "T-3"}},
+		{"time desc", queryModel{SortBy: "Created", SortOrder: "desc"}, []string{"T-1", "T-2", "T-3"}},
+		{"numeric asc ties on key", queryModel{SortBy: "CycleTime", SortOrder: "asc"}, []string{"T-1", "T-2", "T-3"}},
+		{"numeric desc ties on key", queryModel{SortBy: "CycleTime", SortOrder: "DESC"}, []string{"T-2", "T-3", "T-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame := newSortTestFrame()
+			if err := sortFrame(frame, tt.qm, "", "", "IssueKey"); err != nil {
+				t.Fatal(err)
+			}
+			for i, key := range tt.expected {
+				if got := frame.Fields[0].At(i); got != key {
+					t.Errorf("row %d: expected %s, got %v", i, key, got)
+				}
+			}
+		})
+	}
+
+	if err := sortFrame(newSortTestFrame(), queryModel{SortBy: "Nope"}, "", "", "IssueKey"); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+}
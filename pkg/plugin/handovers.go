@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// getHandoversData counts assignee changes per issue. With qm.HandoversInCycle
+// only changes between the earliest start status and the latest end status are
+// counted (issues that never reached a start status are skipped), with the
+// statuses matched like those of cycletime, see statusSet. With
+// qm.IgnoreUnassign changes to an empty assignee are not counted as handovers.
+//
+// Two frames are returned: one row per issue, and the distribution of issues
+// per handover count.
+func (d *Datasource) getHandoversData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Project", nil, []*string{}),
+		data.NewField("HandoverCount", nil, []int64{}),
+		data.NewField("Assignees", nil, []int64{}),
+	)
+
+	starts, ends := qm.statusSet(qm.StartStatus), qm.statusSet(qm.EndStatus)
+
+	distribution := map[int64]int64{}
+
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+
+		windowFrom, windowTo := timeRange.From, timeRange.To
+		if qm.HandoversInCycle {
+			var startAt, endAt time.Time
+			var foundStart, foundEnd bool
+			for _, history := range issue.Changelog.Histories {
+				createdTime, err := parseJiraTime(history.Created)
+				if err != nil {
+					continue
+				}
+				for _, item := range history.Items {
+					if item.Field != "status" {
+						continue
+					}
+					if starts.matches(item) && (!foundStart || createdTime.Before(startAt)) {
+						startAt, foundStart = createdTime, true
+					}
+					if ends.matches(item) && (!foundEnd || createdTime.After(endAt)) {
+						endAt, foundEnd = createdTime, true
+					}
+				}
+			}
+			if !foundStart {
+				continue
+			}
+			if startAt.After(windowFrom) {
+				windowFrom = startAt
+			}
+			if foundEnd && endAt.Before(windowTo) {
+				windowTo = endAt
+			}
+		}
+
+		var handovers int64
+		assignees := map[string]bool{}
+
+		for _, history := range issue.Changelog.Histories {
+			createdTime, err := parseJiraTime(history.Created)
+			if err != nil {
+				continue
+			}
+			if createdTime.Before(windowFrom) || createdTime.After(windowTo) {
+				continue
+			}
+
+			for _, item := range history.Items {
+				if item.Field != "assignee" {
+					continue
+				}
+				if from := assigneeID(item.From, item.FromString); from != "" {
+					assignees[from] = true
+				}
+				to := assigneeID(item.To, item.ToString)
+				if to == "" && qm.IgnoreUnassign {
+					continue
+				}
+				if to != "" {
+					assignees[to] = true
+				}
+				handovers++
+			}
+		}
+
+		frame.AppendRow(
+			issue.Key,
+			optionalString(issueTypeName(issue)),
+			optionalString(projectKey(issue)),
+			handovers,
+			int64(len(assignees)),
+		)
+		distribution[handovers]++
+	}
+
+	counts := make([]int64, 0, len(distribution))
+	for count := range distribution {
+		counts = append(counts, count)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i] < counts[j] })
+
+	summary := data.NewFrame("summary",
+		data.NewField("HandoverCount", nil, []int64{}),
+		data.NewField("Issues", nil, []int64{}),
+	)
+	for _, count := range counts {
+		summary.AppendRow(count, distribution[count])
+	}
+
+	response.Frames = append(response.Frames, frame, summary)
+	return response
+}
+
+// assigneeID prefers the account id of a changelog assignee value and falls back
+// to the display name for instances that don't populate it.
+func assigneeID(id, name string) string {
+	if id != "" {
+		return id
+	}
+	return name
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestHandovers(t *testing.T) {
+	assign := func(at, from, to string) jira.History {
+		return jira.History{Created: at, Items: []jira.Item{{Field: "assignee", From: from, FromString: from, To: to, ToString: to}}}
+	}
+	status := func(at, from, to string) jira.History {
+		return jira.History{Created: at, Items: []jira.Item{{Field: "status", FromString: from, ToString: to}}}
+	}
+	issue := newTestIssue("T-1", "Story")
+	issue.Changelog.Histories = []jira.History{
+		assign("2024-01-02T10:00:00.000+0000", "", "alice"),
+		status("2024-01-03T10:00:00.000+0000", "To Do", "In Progress"),
+		assign("2024-01-04T10:00:00.000+0000", "alice", "bob"),
+		assign("2024-01-05T10:00:00.000+0000", "bob", ""),
+		assign("2024-01-06T10:00:00.000+0000", "", "carol"),
+		status("2024-01-08T10:00:00.000+0000", "In Progress", "Done"),
+		assign("2024-01-09T10:00:00.000+0000", "carol", "dave"),
+	}
+	// Never started, so it has no cycle to count handovers in.
+	unstarted := newTestIssue("T-2", "Story")
+	unstarted.Changelog.Histories = []jira.History{assign("2024-01-02T10:00:00.000+0000", "", "alice")}
+	issues := []jira.Issue{issue, unstarted}
+
+	tests := []struct {
+		name      string
+		qm        queryModel
+		rows      int
+		handovers int64
+		assignees int64
+	}{
+		{"all changes", queryModel{}, 2, 5, 4},
+		{"in cycle", queryModel{HandoversInCycle: true, StartStatus: "In Progress", EndStatus: "Done"}, 1, 3, 3},
+		{"in cycle without unassigns", queryModel{HandoversInCycle: true, IgnoreUnassign: true, StartStatus: "In Progress", EndStatus: "Done"}, 1, 2, 3},
+		{"excluded start status", queryModel{HandoversInCycle: true, StartStatus: "!To Do", EndStatus: "Done"}, 1, 3, 3},
+		{"without unassigns", queryModel{IgnoreUnassign: true}, 2, 4, 4},
+	}
+	for _, tt := range tests {
+		res := (&Datasource{}).getHandoversData(issues, tt.qm, testTimeRange())
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		frame := res.Frames[0]
+		if frame.Rows() != tt.rows {
+			t.Errorf("%s: expected %d issues, got %d", tt.name, tt.rows, frame.Rows())
+			continue
+		}
+		handovers, _ := frame.FieldByName("HandoverCount")
+		assignees, _ := frame.FieldByName("Assignees")
+		if got := handovers.At(0).(int64); got != tt.handovers {
+			t.Errorf("%s: expected %d handovers, got %d", tt.name, tt.handovers, got)
+		}
+		if got := assignees.At(0).(int64); got != tt.assignees {
+			t.Errorf("%s: expected %d assignees, got %d", tt.name, tt.assignees, got)
+		}
+	}
+}
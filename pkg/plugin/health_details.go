@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// probeTimeout bounds every request of a /health-details check.
+const probeTimeout = 5 * time.Second
+
+// probeJQL finds a recent issue; Jira rejects unbounded searches.
+const probeJQL = "created >= -365d ORDER BY created DESC"
+
+// healthCheck is the outcome of a single /health-details probe. Status is "ok",
+// "warning" or "error".
+type healthCheck struct {
+	Name      string      `json:"name"`
+	Status    string      `json:"status"`
+	LatencyMs int64       `json:"latencyMs"`
+	Message   string      `json:"message,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+}
+
+type healthReport struct {
+	Status string        `json:"status"`
+	Checks []healthCheck `json:"checks"`
+}
+
+// probeWarning is returned by probes that worked but found something worth a look.
+type probeWarning string
+
+func (w probeWarning) Error() string { return string(w) }
+
+// movedWarning asks to update the datasource URL after Jira permanently
+// redirected to movedTo.
+func movedWarning(movedTo string) string {
+	return fmt.Sprintf("Jira redirected to %s — update the datasource URL", movedTo)
+}
+
+// probe checks one capability and returns details for the report.
+type probe struct {
+	name string
+	run  func(ctx context.Context, client *jira.Client) (interface{}, error)
+}
+
+var probes = []probe{
+	{"connection", func(ctx context.Context, client *jira.Client) (interface{}, error) {
+		if err := client.Myself(ctx); err != nil {
+			return nil, err
+		}
+		if movedTo := client.MovedTo(); movedTo != "" {
+			return map[string]string{"movedTo": movedTo}, probeWarning(movedWarning(movedTo))
+		}
+		return nil, nil
+	}},
+	{"serverInfo", func(ctx context.Context, client *jira.Client) (interface{}, error) {
+		return client.ServerInfo(ctx)
+	}},
+	{"search", func(ctx context.Context, client *jira.Client) (interface{}, error) {
+		issues, _, err := client.SearchChangelogs(ctx, probeJQL, jira.SearchOptions{SkipChangelog: true, MaxIssues: 1})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{"issues": len(issues)}, nil
+	}},
+	{"changelog", func(ctx context.Context, client *jira.Client) (interface{}, error) {
+		issues, _, err := client.SearchChangelogs(ctx, probeJQL, jira.SearchOptions{MaxIssues: 1})
+		if err != nil {
+			return nil, err
+		}
+		if len(issues) == 0 {
+			return nil, probeWarning("no issue created in the last year to check the changelog expansion with")
+		}
+		if issues[0].Changelog == nil {
+			return nil, probeWarning(fmt.Sprintf("Jira returned %s without its changelog, cycle time metrics won't work", issues[0].Key))
+		}
+		return map[string]int{"histories": len(issues[0].Changelog.Histories)}, nil
+	}},
+	{"agile", func(ctx context.Context, client *jira.Client) (interface{}, error) {
+		boards, err := client.CountBoards(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{"boards": boards}, nil
+	}},
+}
+
+// handleHealthDetails runs every probe concurrently against Jira and reports
+// their status and latency along with the rate limit headers Jira sent, for
+// diagnosing connection and permission problems. Unlike CheckHealth it doesn't
+// stop at the first failure.
+func (d *Datasource) handleHealthDetails(w http.ResponseWriter, r *http.Request) {
+	config, pluginContext, err := settingsFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Neither the cache nor the circuit breaker: every probe has to reach Jira.
+	client := d.newClient(config, pluginContext)
+	transport := http.DefaultTransport
+	if d.httpClient != nil && d.httpClient.Transport != nil {
+		transport = d.httpClient.Transport
+	}
+	client.SetHTTPClient(&http.Client{Transport: transport, Timeout: probeTimeout})
+
+	report := healthReport{Status: "ok", Checks: make([]healthCheck, len(probes))}
+	var wg sync.WaitGroup
+	for i, p := range probes {
+		wg.Add(1)
+		go func(i int, p probe) {
+			defer wg.Done()
+			report.Checks[i] = runProbe(r.Context(), client, p)
+		}(i, p)
+	}
+	wg.Wait()
+
+	rateLimit := healthCheck{Name: "rateLimit", Status: "ok"}
+	if limit := client.RateLimit(); limit != nil {
+		rateLimit.Details = limit
+		if limit.Low() {
+			rateLimit.Status = "warning"
+			rateLimit.Message = "The rate limit budget is running low"
+		}
+	} else {
+		rateLimit.Message = "Jira sent no rate limit headers"
+	}
+	report.Checks = append(report.Checks, rateLimit)
+
+	for _, check := range report.Checks {
+		if check.Status == "error" || (check.Status == "warning" && report.Status == "ok") {
+			report.Status = check.Status
+		}
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func runProbe(ctx context.Context, client *jira.Client, p probe) healthCheck {
+	start := time.Now()
+	details, err := p.run(ctx, client)
+	check := healthCheck{Name: p.name, Status: "ok", LatencyMs: time.Since(start).Milliseconds(), Details: details}
+
+	var warning probeWarning
+	switch {
+	case errors.As(err, &warning):
+		check.Status = "warning"
+		check.Message = warning.Error()
+	case err != nil:
+		check.Status = "error"
+		check.Message = err.Error()
+	}
+	return check
+}
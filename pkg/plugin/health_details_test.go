@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthDetails(t *testing.T) {
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		switch r.URL.Path {
+		case "/rest/api/3/myself":
+			fmt.Fprint(w, `{}`)
+		case "/rest/api/3/serverInfo":
+			fmt.Fprint(w, `{"version":"1001.0.0","deploymentType":"Cloud"}`)
+		case "/rest/api/3/search/jql":
+			fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}}]}`)
+		case "/rest/agile/1.0/board":
+			http.NotFound(w, r)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer jiraServer.Close()
+
+	rec := httptest.NewRecorder()
+	(&Datasource{}).newResourceMux().ServeHTTP(rec, newResourceRequest(t, jiraServer.URL, "/health-details"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var report struct {
+		Status string `json:"status"`
+		Checks []struct {
+			Name    string                 `json:"name"`
+			Status  string                 `json:"status"`
+			Details map[string]interface{} `json:"details"`
+		} `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := map[string]string{}
+	for _, check := range report.Checks {
+		statuses[check.Name] = check.Status
+		if check.Name == "rateLimit" && check.Details["X-RateLimit-Remaining"] != "42" {
+			t.Errorf("expected the rate limit headers, got %v", check.Details)
+		}
+	}
+	expected := map[string]string{
+		"connection": "ok",
+		"serverInfo": "ok",
+		"search":     "ok",
+		// The search response carries no changelog.
+		"changelog": "warning",
+		"agile":     "error",
+		"rateLimit": "ok",
+	}
+	for name, status := range expected {
+		if statuses[name] != status {
+			t.Errorf("expected %s to be %s, got %q", name, status, statuses[name])
+		}
+	}
+	if report.Status != "error" {
+		t.Errorf("expected the overall status to be error, got %s", report.Status)
+	}
+}
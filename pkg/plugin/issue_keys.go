@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// issueKeyPattern matches issue keys like "PLAT-123".
+var issueKeyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*-[0-9]+$`)
+
+// fetchIssuesByKey fetches the issues listed in issueKeys instead of searching
+// with the JQL. Keys that are malformed or that Jira reports as issue errors come
+// back as warnings, the other issues are still returned. Issues that moved to
+// another project come back under their new key, so the issue errors, rather
+// than the keys returned, tell which issues are missing.
+func fetchIssuesByKey(ctx context.Context, client *jira.Client, qm queryModel) ([]jira.Issue, []data.Notice, error) {
+	var keys []string
+	var notices []data.Notice
+	skip := func(key, reason string) {
+		notices = append(notices, data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Issue %s was skipped: %s.", key, reason),
+		})
+	}
+
+	seen := map[string]bool{}
+	for _, raw := range qm.IssueKeys {
+		raw = strings.TrimSpace(raw)
+		key := strings.ToUpper(raw)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !issueKeyPattern.MatchString(key) {
+			skip(fmt.Sprintf("%q", raw), "not a valid issue key")
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return []jira.Issue{}, notices, nil
+	}
+
+	issues, issueErrors, err := client.FetchIssues(ctx, keys, searchOptions(qm))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, issueErr := range issueErrors {
+		skip(issueErr.ID, strings.TrimSuffix(issueErr.ErrorMessage, "."))
+	}
+	return issues, notices, nil
+}
@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestFetchIssuesByKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/issue/bulkfetch" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		var body struct {
+			IssueIdsOrKeys []string `json:"issueIdsOrKeys"`
+			Expand         []string `json:"expand"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if strings.Join(body.IssueIdsOrKeys, ",") != "A-1,A-2,B-9" {
+			t.Errorf("unexpected keys %v", body.IssueIdsOrKeys)
+		}
+		if strings.Join(body.Expand, ",") != "changelog" {
+			t.Errorf("expected the changelog to be expanded, got %v", body.Expand)
+		}
+		// B-9 moved to project C.
+		fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}},{"key":"C-3","fields":{}}],
+			"issueErrors":[{"id":"A-2","errorMessage":"Issue does not exist or you do not have permission to see it."}]}`)
+	}))
+	defer server.Close()
+
+	client := jira.NewClient(server.URL, "user", "token", "")
+	qm := queryModel{Metric: "cycletime", IssueKeys: []string{"a-1", " A-2", "A-1", "not a key", "B-9", ""}}
+	issues, notices, err := fetchIssuesByKey(context.Background(), client, qm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 2 || issues[0].Key != "A-1" || issues[1].Key != "C-3" {
+		t.Errorf("unexpected issues %+v", issues)
+	}
+
+	var texts []string
+	for _, notice := range notices {
+		texts = append(texts, notice.Text)
+	}
+	expected := []string{
+		`Issue "not a key" was skipped: not a valid issue key.`,
+		"Issue A-2 was skipped: Issue does not exist or you do not have permission to see it.",
+	}
+	if strings.Join(texts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected notices:\n%s", strings.Join(texts, "\n"))
+	}
+}
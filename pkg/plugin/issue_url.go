@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// issueURL returns the link to the issue with the key in the Jira at jiraURL.
+// Context paths are kept, e.g. https://example.com/jira/browse/ABC-1.
+func issueURL(jiraURL, key string) string {
+	return strings.TrimRight(jiraURL, "/") + "/browse/" + key
+}
+
+// urlMetrics are the metrics whose main frame gets a URL column with includeURL.
+var urlMetrics = []string{"jql", "cycletime", "changelogRaw"}
+
+// appendIssueURLs appends a URL column with the link to the issue of every row
+// to the main frame of the urlMetrics, for panels that need the URL as data
+// rather than as a data link. Frames without an issue key column, such as the
+// cycletime series, are left alone.
+func appendIssueURLs(res *backend.DataResponse, metric, jiraURL string) {
+	if !containsString(urlMetrics, metric) {
+		return
+	}
+	for _, frame := range res.Frames {
+		if frame.Name != "response" {
+			continue
+		}
+		keys, _ := frame.FieldByName("IssueKey")
+		if keys == nil {
+			keys, _ = frame.FieldByName("Key")
+		}
+		if keys == nil || keys.Type() != data.FieldTypeString {
+			continue
+		}
+		urls := make([]string, keys.Len())
+		for i := range urls {
+			urls[i] = issueURL(jiraURL, keys.At(i).(string))
+		}
+		frame.Fields = append(frame.Fields, data.NewField("URL", nil, urls))
+	}
+}
@@ -0,0 +1,55 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestIssueURL(t *testing.T) {
+	for jiraURL, want := range map[string]string{
+		"https://jira.example.com":       "https://jira.example.com/browse/T-1",
+		"https://example.com/jira/":      "https://example.com/jira/browse/T-1",
+		"https://example.com/tools/jira": "https://example.com/tools/jira/browse/T-1",
+	} {
+		if got := issueURL(jiraURL, "T-1"); got != want {
+			t.Errorf("%s: expected %s, got %s", jiraURL, want, got)
+		}
+	}
+}
+
+func TestAppendIssueURLs(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "In Progress", "Done"},
+		),
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done"}
+
+	for _, metric := range []string{"jql", "cycletime", "changelogRaw"} {
+		qm.Metric = metric
+		res := ds.buildFrames(context.Background(), nil, &models.PluginSettings{}, qm, testTimeRange(), issues)
+		appendIssueURLs(&res, metric, "https://example.com/jira/")
+		frame := res.Frames[0]
+		field, _ := frame.FieldByName("URL")
+		if field == nil || field.Len() != frame.Rows() || field.Len() == 0 {
+			t.Errorf("%s: expected a URL for every row", metric)
+			continue
+		}
+		if got := field.At(0); got != "https://example.com/jira/browse/T-1" {
+			t.Errorf("%s: unexpected URL %v", metric, got)
+		}
+	}
+
+	qm.Metric, qm.Format, qm.Interval = "cycletime", formatTimeSeries, "1d"
+	res := ds.buildFrames(context.Background(), nil, &models.PluginSettings{}, qm, testTimeRange(), issues)
+	fields := len(res.Frames[0].Fields)
+	appendIssueURLs(&res, qm.Metric, "https://jira.example.com")
+	if len(res.Frames[0].Fields) != fields {
+		t.Error("expected series without issue keys to be left alone")
+	}
+}
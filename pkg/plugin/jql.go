@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+var (
+	orderByPattern = regexp.MustCompile(`(?i)(^|[\s)])order\s+by\b`)
+	// timeClausePattern finds clauses that already restrict the JQL in time.
+	timeClausePattern = regexp.MustCompile(`(?i)\b(updated|updatedDate)\b|\b(startOf|endOf)(Day|Week|Month|Year)\s*\(|\bnow\s*\(`)
+)
+
+// blankQuoted replaces quoted strings with spaces, keeping byte offsets intact,
+// so that values like summary ~ "order by" aren't mistaken for JQL syntax.
+func blankQuoted(jql string) string {
+	b := []byte(jql)
+	var quote byte
+	for i := 0; i < len(b); i++ {
+		switch {
+		case quote == 0 && (b[i] == '\'' || b[i] == '"'):
+			quote = b[i]
+		case quote != 0 && b[i] == '\\':
+			b[i] = ' '
+			if i+1 < len(b) {
+				i++
+				b[i] = ' '
+			}
+		case quote != 0 && b[i] == quote:
+			quote = 0
+		case quote != 0:
+			b[i] = ' '
+		}
+	}
+	return string(b)
+}
+
+// splitOrderBy splits jql into its filter and its ORDER BY clause, if any.
+func splitOrderBy(jql string) (filter, orderBy string) {
+	loc := orderByPattern.FindStringIndex(blankQuoted(jql))
+	if loc == nil {
+		return strings.TrimSpace(jql), ""
+	}
+	start := loc[0]
+	if jql[start] == ')' {
+		start++
+	}
+	return strings.TrimSpace(jql[:start]), strings.TrimSpace(jql[start:])
+}
+
+// addFilter restricts jql with clause. The user's filter is parenthesized so
+// that its OR clauses keep their meaning, and an ORDER BY clause stays last. The
+// returned notice states what was added; it is a warning when the JQL already
+// filters on time, since both filters then apply.
+func addFilter(jql, clause, reason string) (string, data.Notice) {
+	filter, orderBy := splitOrderBy(jql)
+
+	result := clause
+	added := clause
+	if filter != "" {
+		result = fmt.Sprintf("(%s) AND %s", filter, clause)
+		added = "AND " + clause
+	}
+	if orderBy != "" {
+		result += " " + orderBy
+	}
+
+	text := fmt.Sprintf("`%s` was added to the JQL %s.", added, reason)
+	if orderBy != "" {
+		text = fmt.Sprintf("`%s` was added to the JQL before its ORDER BY clause %s.", added, reason)
+	}
+
+	notice := data.Notice{Severity: data.NoticeSeverityInfo, Text: text}
+	if timeClausePattern.MatchString(blankQuoted(filter)) {
+		notice.Severity = data.NoticeSeverityWarning
+		notice.Text += " The JQL already filters on time, so results may be filtered twice."
+	}
+	return result, notice
+}
+
+// setExecutedQuery shows the JQL the query searched with, including the filters
+// the plugin added, in the query inspector of every frame of res.
+func setExecutedQuery(res *backend.DataResponse, jql string) {
+	for _, frame := range res.Frames {
+		if frame.Meta == nil {
+			frame.SetMeta(&data.FrameMeta{})
+		}
+		frame.Meta.ExecutedQueryString = jql
+	}
+}
+
+// appendNotice adds notice to every frame of res.
+func appendNotice(res *backend.DataResponse, notice data.Notice) {
+	for _, frame := range res.Frames {
+		frame.AppendNotices(notice)
+	}
+}
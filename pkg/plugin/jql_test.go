@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestAddFilter(t *testing.T) {
+	clause := "updated >= '2024-01-01 00:00'"
+	tests := []struct {
+		jql      string
+		want     string
+		severity data.NoticeSeverity
+	}{
+		{"project = A OR project = B", "(project = A OR project = B) AND " + clause, data.NoticeSeverityInfo},
+		{"project = A ORDER BY created DESC", "(project = A) AND " + clause + " ORDER BY created DESC", data.NoticeSeverityInfo},
+		{"(project = A)order by rank", "((project = A)) AND " + clause + " order by rank", data.NoticeSeverityInfo},
+		{`summary ~ "order by updated"`, `(summary ~ "order by updated") AND ` + clause, data.NoticeSeverityInfo},
+		{"ORDER BY created", clause + " ORDER BY created", data.NoticeSeverityInfo},
+		{"project = A AND updated >= -7d", "(project = A AND updated >= -7d) AND " + clause, data.NoticeSeverityWarning},
+		{"resolved >= startOfMonth()", "(resolved >= startOfMonth()) AND " + clause, data.NoticeSeverityWarning},
+	}
+	for _, tt := range tests {
+		got, notice := addFilter(tt.jql, clause, "to limit it to the dashboard time range")
+		if got != tt.want {
+			t.Errorf("addFilter(%q) = %q, want %q", tt.jql, got, tt.want)
+		}
+		if notice.Severity != tt.severity {
+			t.Errorf("addFilter(%q) notice severity = %v, want %v (%q)", tt.jql, notice.Severity, tt.severity, notice.Text)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// keyField is the changelog field of issue key changes, which happen when an
+// issue moves to another project.
+const keyField = "Key"
+
+// Values of the projectAttribution query option.
+const (
+	attributeAtCompletion = "completion"
+	attributeAtStart      = "start"
+)
+
+// keyChange is a change of an issue's key.
+type keyChange struct {
+	at   time.Time
+	from string
+	to   string
+}
+
+// keyChanges returns the key changes of the issue in chronological order.
+func keyChanges(issue jira.Issue) []keyChange {
+	if issue.Changelog == nil {
+		return nil
+	}
+
+	var changes []keyChange
+	for _, history := range issue.Changelog.Histories {
+		createdTime, err := parseJiraTime(history.Created)
+		if err != nil {
+			continue
+		}
+		for _, item := range history.Items {
+			if item.Field == keyField {
+				changes = append(changes, keyChange{at: createdTime, from: item.FromString, to: item.ToString})
+			}
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].at.Before(changes[j].at) })
+	return changes
+}
+
+// originalKey returns the key the issue was created with.
+func originalKey(issue jira.Issue, changes []keyChange) string {
+	if len(changes) > 0 && changes[0].from != "" {
+		return changes[0].from
+	}
+	return issue.Key
+}
+
+// keyAt returns the key of the issue at t.
+func keyAt(issue jira.Issue, changes []keyChange, t time.Time) string {
+	key := originalKey(issue, changes)
+	for _, c := range changes {
+		if c.at.After(t) {
+			break
+		}
+		key = c.to
+	}
+	return key
+}
+
+// keyProject returns the project key part of an issue key.
+func keyProject(key string) string {
+	if i := strings.LastIndex(key, "-"); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// cycleProject returns the project the cycle counts towards: the one the issue
+// was in when the cycle was completed, or when it started with
+// projectAttribution "start". Issues that never moved stay in their project.
+func (qm queryModel) cycleProject(c cycle) string {
+	changes := keyChanges(c.issue)
+	if len(changes) == 0 {
+		return projectKey(c.issue)
+	}
+	at := c.end
+	if qm.ProjectAttribution == attributeAtStart {
+		at = c.start
+	}
+	if project := keyProject(keyAt(c.issue, changes, at)); project != "" {
+		return project
+	}
+	return projectKey(c.issue)
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// defaultMaxRows caps the row count of table metrics when the query doesn't set maxRows.
+const defaultMaxRows = 10000
+
+// rowLimit returns the maximum number of rows a table metric may return.
+func rowLimit(qm queryModel) int {
+	if qm.MaxRows > 0 {
+		return qm.MaxRows
+	}
+	return defaultMaxRows
+}
+
+// addTruncationNotice warns on the frame that dropped rows were not returned.
+func addTruncationNotice(frame *data.Frame, dropped, limit int) {
+	if dropped <= 0 {
+		return
+	}
+	frame.AppendNotices(data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("Result truncated to %d rows, %d rows were dropped. Narrow down the JQL or raise maxRows.", limit, dropped),
+	})
+}
+
+// truncateFrame keeps the first limit rows of frame and returns how many were dropped.
+// Builders that can stop early should do so instead; this is for frames that are
+// sorted after construction.
+func truncateFrame(frame *data.Frame, limit int) int {
+	rows := frame.Rows()
+	if rows <= limit {
+		return 0
+	}
+
+	for i, field := range frame.Fields {
+		truncated := data.NewFieldFromFieldType(field.Type(), limit)
+		truncated.Name = field.Name
+		truncated.Labels = field.Labels
+		truncated.Config = field.Config
+		for row := 0; row < limit; row++ {
+			truncated.Set(row, field.At(row))
+		}
+		frame.Fields[i] = truncated
+	}
+	return rows - limit
+}
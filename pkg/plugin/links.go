@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	linkOutward = "outward"
+	linkInward  = "inward"
+)
+
+// issueLink is one entry of an issue's issuelinks field, seen from sourceKey.
+type issueLink struct {
+	id             string
+	sourceKey      string
+	typeName       string
+	description    string // e.g. "blocks" or "is blocked by"
+	direction      string
+	targetKey      string
+	targetStatus   string
+	targetCategory string
+}
+
+// issueLinks parses the issuelinks field of an issue.
+func issueLinks(issue jira.Issue) []issueLink {
+	raw, _ := issue.Fields["issuelinks"].([]interface{})
+
+	var links []issueLink
+	for _, entry := range raw {
+		l, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		link := issueLink{sourceKey: issue.Key}
+		link.id, _ = l["id"].(string)
+
+		linkType, _ := l["type"].(map[string]interface{})
+		link.typeName, _ = linkType["name"].(string)
+
+		var target map[string]interface{}
+		if t, ok := l["outwardIssue"].(map[string]interface{}); ok {
+			target = t
+			link.direction = linkOutward
+			link.description, _ = linkType["outward"].(string)
+		} else if t, ok := l["inwardIssue"].(map[string]interface{}); ok {
+			target = t
+			link.direction = linkInward
+			link.description, _ = linkType["inward"].(string)
+		} else {
+			continue
+		}
+
+		link.targetKey, _ = target["key"].(string)
+		if fields, ok := target["fields"].(map[string]interface{}); ok {
+			if status, ok := fields["status"].(map[string]interface{}); ok {
+				link.targetStatus, _ = status["name"].(string)
+			}
+			link.targetCategory = statusCategoryKey(fields["status"])
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// collectLinks returns the links of all issues matching the linkTypes filter
+// (link type names or their inward/outward descriptions, case-insensitive).
+// A link between two issues of the result set is listed by both of them; those
+// duplicates are collapsed into their outward representation.
+func collectLinks(issues []jira.Issue, linkTypes []string) []issueLink {
+	var links []issueLink
+	byID := map[string]int{}
+
+	for _, issue := range issues {
+		for _, link := range issueLinks(issue) {
+			if len(linkTypes) > 0 && !matchesLinkType(link, linkTypes) {
+				continue
+			}
+
+			if link.id != "" {
+				if i, ok := byID[link.id]; ok {
+					if links[i].direction == linkInward && link.direction == linkOutward {
+						links[i] = link
+					}
+					continue
+				}
+				byID[link.id] = len(links)
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func matchesLinkType(link issueLink, linkTypes []string) bool {
+	for _, t := range linkTypes {
+		if t == allValues || strings.EqualFold(t, link.typeName) || strings.EqualFold(t, link.description) {
+			return true
+		}
+	}
+	return false
+}
+
+// getLinksData emits one row per issue link, for dependency tables and graphs.
+func (d *Datasource) getLinksData(issues []jira.Issue, qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	frame := data.NewFrame("response",
+		data.NewField("SourceKey", nil, []string{}),
+		data.NewField("LinkType", nil, []string{}),
+		data.NewField("Direction", nil, []string{}),
+		data.NewField("Relation", nil, []string{}),
+		data.NewField("TargetKey", nil, []string{}),
+		data.NewField("TargetStatus", nil, []*string{}),
+	)
+
+	for _, link := range collectLinks(issues, parseList(qm.LinkTypes)) {
+		frame.AppendRow(link.sourceKey, link.typeName, link.direction, link.description, link.targetKey, optionalString(link.targetStatus))
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func linkedIssue(key string, links ...map[string]interface{}) jira.Issue {
+	raw := make([]interface{}, len(links))
+	for i, l := range links {
+		raw[i] = l
+	}
+	return jira.Issue{Key: key, Fields: map[string]interface{}{"issuelinks": raw}}
+}
+
+func blocksLink(id, direction, target string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":   id,
+		"type": map[string]interface{}{"name": "Blocks", "inward": "is blocked by", "outward": "blocks"},
+		direction + "Issue": map[string]interface{}{
+			"key":    target,
+			"fields": map[string]interface{}{"status": map[string]interface{}{"name": "In Progress"}},
+		},
+	}
+}
+
+func TestLinksDeduplicated(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		// B lists the link first, as inward; A lists it as outward.
+		linkedIssue("B", blocksLink("1", "inward", "A")),
+		linkedIssue("A", blocksLink("1", "outward", "B"), map[string]interface{}{
+			"id":           "2",
+			"type":         map[string]interface{}{"name": "Relates", "inward": "relates to", "outward": "relates to"},
+			"outwardIssue": map[string]interface{}{"key": "C"},
+		}),
+	}
+
+	res := ds.getLinksData(issues, queryModel{})
+	frame := res.Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("expected 2 links, got %d", frame.Rows())
+	}
+	if src, dir := frame.Fields[0].At(0), frame.Fields[2].At(0); src != "A" || dir != linkOutward {
+		t.Errorf("expected the outward representation A -> B, got %v %v", src, dir)
+	}
+
+	res = ds.getLinksData(issues, queryModel{LinkTypes: "is blocked by"})
+	if rows := res.Frames[0].Rows(); rows != 1 {
+		t.Errorf("expected 1 link for the blocks filter, got %d", rows)
+	}
+}
+
+func TestNodeGraphFrames(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		linkedIssue("A", blocksLink("1", "outward", "B")),
+		linkedIssue("C", blocksLink("2", "inward", "A")),
+	}
+
+	res := ds.getNodeGraphData(issues, queryModel{Format: formatNodeGraph}, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	nodes, edges := res.Frames[0], res.Frames[1]
+	if nodes.Name != "nodes" || edges.Name != "edges" {
+		t.Fatalf("unexpected frame names %q, %q", nodes.Name, edges.Name)
+	}
+	if nodes.Rows() != 3 {
+		t.Errorf("expected nodes A, C and the linked B, got %d", nodes.Rows())
+	}
+	// C "is blocked by" A, so the edge points from A to C.
+	if src, dst := edges.Fields[1].At(1), edges.Fields[2].At(1); src != "A" || dst != "C" {
+		t.Errorf("expected edge A -> C, got %v -> %v", src, dst)
+	}
+}
@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+)
+
+// flightCall is a metadata load in flight, done is closed when it is.
+type flightCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// metadataFlight makes concurrent loads of the same metadata share a single
+// request to Jira, e.g. when the panels of a dashboard all miss the cold cache
+// at once. The zero value is ready to use; close stops it for good.
+type metadataFlight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+	// ctx is cancelled by close, along with the loads in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// loads tracks the loads in flight, which close waits for.
+	loads sync.WaitGroup
+	// waiting, if set, is called whenever a caller starts waiting for a load, so
+	// that tests can tell when callers joined one.
+	waiting func()
+}
+
+// init creates the context of the loads if the flight has none yet. f.mu must
+// be held.
+func (f *metadataFlight) init() {
+	if f.ctx == nil {
+		f.ctx, f.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// do returns the result of fetch for key, or of the load of key that is already
+// in flight. The load runs on its own: a caller whose ctx is done stops waiting
+// with the error of its ctx, while the load goes on for the other callers until
+// the flight is closed. The result is shared and must not be modified.
+func (f *metadataFlight) do(ctx context.Context, key string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	f.mu.Lock()
+	f.init()
+	if err := f.ctx.Err(); err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
+	if f.calls == nil {
+		f.calls = map[string]*flightCall{}
+	}
+	call, ok := f.calls[key]
+	if !ok {
+		call = &flightCall{done: make(chan struct{})}
+		f.calls[key] = call
+		f.loads.Add(1)
+		go func() {
+			defer f.loads.Done()
+			call.value, call.err = fetch(f.ctx)
+			f.mu.Lock()
+			delete(f.calls, key)
+			f.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	f.mu.Unlock()
+	if f.waiting != nil {
+		f.waiting()
+	}
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// close cancels the loads in flight and waits for them. Later loads fail.
+func (f *metadataFlight) close() {
+	f.mu.Lock()
+	f.init()
+	f.cancel()
+	f.mu.Unlock()
+	f.loads.Wait()
+}
+
+// loadMetadata loads the metadata of key with fetch, from the metadata snapshots
+// if they are enabled, sharing concurrent loads of the same key.
+func loadMetadata[T any](ctx context.Context, d *Datasource, key string, fetch func(context.Context) (T, error)) (T, error) {
+	value, err := d.flight.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		if d.metadata == nil {
+			return fetch(ctx)
+		}
+		var v T
+		err := d.metadata.get(ctx, key, &v, func(ctx context.Context) (interface{}, error) { return fetch(ctx) })
+		return v, err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return value.(T), nil
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestMetadataSingleFlight(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		fmt.Fprint(w, `[{"id":"1","name":"To Do"},{"id":"3","name":"Done"}]`)
+	}))
+	defer server.Close()
+
+	for name, ds := range map[string]*Datasource{
+		"direct":    {},
+		"snapshots": {metadata: newMetadataStore(filepath.Join(t.TempDir(), "ds.json"))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			requests.Store(0)
+			release = make(chan struct{})
+			waiting := make(chan struct{}, 50)
+			ds.flight.waiting = func() { waiting <- struct{}{} }
+			client := jira.NewClient(server.URL, "user", "token", "")
+
+			// A dashboard load: 50 panels miss the cold cache at once, and one of
+			// them is cancelled while waiting.
+			cancelled, cancel := context.WithCancel(context.Background())
+			cancelledErr := make(chan error, 1)
+			go func() {
+				_, err := ds.statuses(cancelled, client)
+				cancelledErr <- err
+			}()
+			var finished sync.WaitGroup
+			results := make([][]jira.NamedValue, 49)
+			errs := make([]error, 49)
+			for i := range results {
+				finished.Add(1)
+				go func(i int) {
+					defer finished.Done()
+					results[i], errs[i] = ds.statuses(context.Background(), client)
+				}(i)
+			}
+			for i := 0; i < 50; i++ {
+				<-waiting
+			}
+			cancel()
+			if err := <-cancelledErr; !errors.Is(err, context.Canceled) {
+				t.Errorf("expected the cancelled waiter to stop waiting, got %v", err)
+			}
+			close(release)
+			finished.Wait()
+
+			if n := requests.Load(); n != 1 {
+				t.Errorf("expected exactly one request to Jira, got %d", n)
+			}
+			for i := range results {
+				if errs[i] != nil || len(results[i]) != 2 {
+					t.Fatalf("waiter %d: expected the shared statuses, got %v (%v)", i, results[i], errs[i])
+				}
+			}
+
+			// Once done, the next load fetches again unless the snapshots serve it.
+			if _, err := ds.statuses(context.Background(), client); err != nil {
+				t.Fatal(err)
+			}
+			want := int32(2)
+			if ds.metadata != nil {
+				want = 1
+			}
+			if n := requests.Load(); n != want {
+				t.Errorf("expected %d requests after the load, got %d", want, n)
+			}
+		})
+	}
+}
+
+func TestMetadataFlightClose(t *testing.T) {
+	var flight metadataFlight
+	started := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := flight.do(context.Background(), metadataStatuses, func(ctx context.Context) (interface{}, error) {
+			close(started)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		done <- err
+	}()
+	<-started
+	flight.close()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the load to be cancelled, got %v", err)
+	}
+	if _, err := flight.do(context.Background(), metadataStatuses, func(context.Context) (interface{}, error) {
+		t.Error("expected a closed flight not to load")
+		return nil, nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected loads after close to fail, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+const (
+	// metadataMaxAge is how old a snapshot can get before it is refreshed in the
+	// background. Until the refresh is done, the stale snapshot is served.
+	metadataMaxAge = time.Hour
+	// metadataDirName is the directory of the snapshot files in Grafana's data
+	// directory.
+	metadataDirName = "plugins-data/achan-grafanajira-datasource"
+)
+
+// Keys of the metadata snapshots.
+const (
+	metadataStatuses         = "statuses"
+	metadataProjects         = "projects"
+	metadataArchivedProjects = "projectsWithArchived"
+)
+
+// metadataSnapshot is a metadata response as stored in the snapshot file.
+type metadataSnapshot struct {
+	FetchedAt time.Time       `json:"fetchedAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// metadataStore keeps metadata lookups like the statuses and projects of a
+// datasource instance in a file, so that they survive plugin restarts. Stale
+// snapshots are served while they are refreshed in the background; missing or
+// corrupt ones are fetched right away. close stops the store for good.
+type metadataStore struct {
+	path string
+	// ctx is cancelled by close, along with the fetches in the background.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	snapshots  map[string]metadataSnapshot
+	refreshing map[string]bool
+	// refreshes tracks the background refreshes, which close waits for.
+	refreshes sync.WaitGroup
+}
+
+// metadataPath returns the snapshot file of the datasource with the given uid in
+// Grafana's data directory (GF_PATHS_DATA), "" if that isn't known. The file is
+// specific to the Jira URL, so that pointing the datasource elsewhere starts over.
+func metadataPath(uid, jiraURL string) string {
+	dir := os.Getenv("GF_PATHS_DATA")
+	if dir == "" || uid == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(jiraURL))
+	return filepath.Join(dir, metadataDirName, uid+"-"+hex.EncodeToString(sum[:4])+".json")
+}
+
+// newMetadataStore loads the snapshots stored at path. A file that is missing or
+// can't be read starts an empty store.
+func newMetadataStore(path string) *metadataStore {
+	s := &metadataStore{path: path, snapshots: map[string]metadataSnapshot{}, refreshing: map[string]bool{}}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.DefaultLogger.Warn("failed to read the metadata snapshots", "path", path, "error", err)
+		}
+		return s
+	}
+	if err := json.Unmarshal(raw, &s.snapshots); err != nil {
+		log.DefaultLogger.Warn("ignoring corrupt metadata snapshots", "path", path, "error", err)
+		s.snapshots = map[string]metadataSnapshot{}
+	}
+	return s
+}
+
+// get decodes the snapshot of key into v, fetching it first with ctx if there is
+// none or it can't be decoded. A stale snapshot is refreshed in the background.
+func (s *metadataStore) get(ctx context.Context, key string, v interface{}, fetch func(context.Context) (interface{}, error)) error {
+	s.mu.Lock()
+	snapshot, ok := s.snapshots[key]
+	s.mu.Unlock()
+
+	if ok && json.Unmarshal(snapshot.Data, v) == nil {
+		if time.Since(snapshot.FetchedAt) > metadataMaxAge {
+			s.refreshAsync(key, fetch)
+		}
+		return nil
+	}
+
+	data, err := s.refresh(ctx, key, fetch)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// refreshAsync refreshes the snapshot of key in the background unless that is
+// already happening or the store is closed.
+func (s *metadataStore) refreshAsync(key string, fetch func(context.Context) (interface{}, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshing[key] || s.ctx.Err() != nil {
+		return
+	}
+	s.refreshing[key] = true
+	s.refreshes.Add(1)
+
+	go func() {
+		defer s.refreshes.Done()
+		if _, err := s.refresh(s.ctx, key, fetch); err != nil {
+			log.DefaultLogger.Warn("failed to refresh metadata", "key", key, "error", err)
+		}
+		s.mu.Lock()
+		delete(s.refreshing, key)
+		s.mu.Unlock()
+	}()
+}
+
+// close cancels the background refreshes and waits for them, so that nothing
+// fetches from Jira or writes the snapshot file once the instance is disposed.
+func (s *metadataStore) close() {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	s.refreshes.Wait()
+}
+
+// refresh fetches the metadata of key with ctx and stores the snapshot, unless
+// the store was closed meanwhile.
+func (s *metadataStore) refresh(ctx context.Context, key string, fetch func(context.Context) (interface{}, error)) (json.RawMessage, error) {
+	value, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return data, nil
+	}
+	s.snapshots[key] = metadataSnapshot{FetchedAt: time.Now(), Data: data}
+	raw, err := json.Marshal(s.snapshots)
+	s.mu.Unlock()
+	if err == nil {
+		err = writeFileAtomic(s.path, raw)
+	}
+	if err != nil {
+		// The snapshot still serves this process.
+		log.DefaultLogger.Warn("failed to save the metadata snapshots", "path", s.path, "error", err)
+	}
+	return data, nil
+}
+
+// writeFileAtomic replaces the file at path with data, so that a crash never
+// leaves a half-written file behind.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// statuses returns the statuses of the Jira instance, from the metadata
+// snapshots if they are enabled.
+func (d *Datasource) statuses(ctx context.Context, client *jira.Client) ([]jira.NamedValue, error) {
+	return loadMetadata(ctx, d, metadataStatuses, client.GetStatuses)
+}
+
+// projects returns the projects of the Jira instance, from the metadata
+// snapshots if they are enabled.
+func (d *Datasource) projects(ctx context.Context, client *jira.Client, includeArchived bool) ([]jira.Project, error) {
+	key := metadataProjects
+	if includeArchived {
+		key = metadataArchivedProjects
+	}
+	return loadMetadata(ctx, d, key, func(ctx context.Context) ([]jira.Project, error) {
+		return client.GetProjects(ctx, includeArchived)
+	})
+}
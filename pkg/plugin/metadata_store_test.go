@@ -0,0 +1,100 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMetadataStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshots", "ds.json")
+	fetches := 0
+	fetch := func(context.Context) (interface{}, error) {
+		fetches++
+		return []string{fmt.Sprintf("fetch %d", fetches)}, nil
+	}
+
+	var values []string
+	if err := newMetadataStore(path).get(context.Background(), metadataStatuses, &values, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 1 || len(values) != 1 || values[0] != "fetch 1" {
+		t.Fatalf("expected the first fetch, got %v after %d fetches", values, fetches)
+	}
+
+	// A restarted plugin serves the snapshot from the file.
+	restarted := newMetadataStore(path)
+	values = nil
+	if err := restarted.get(context.Background(), metadataStatuses, &values, fetch); err != nil {
+		t.Fatal(err)
+	}
+	if fetches != 1 || values[0] != "fetch 1" {
+		t.Errorf("expected the stored snapshot, got %v after %d fetches", values, fetches)
+	}
+
+	// A stale snapshot is served while it is refreshed in the background.
+	restarted.mu.Lock()
+	stale := restarted.snapshots[metadataStatuses]
+	stale.FetchedAt = time.Now().Add(-2 * metadataMaxAge)
+	restarted.snapshots[metadataStatuses] = stale
+	restarted.mu.Unlock()
+	values = nil
+	if err := restarted.get(context.Background(), metadataStatuses, &values, fetch); err != nil {
+		t.Fatal(err)
+	}
+	restarted.refreshes.Wait()
+	if values[0] != "fetch 1" || fetches != 2 {
+		t.Errorf("expected the stale snapshot and a refresh, got %v after %d fetches", values, fetches)
+	}
+	values = nil
+	if err := newMetadataStore(path).get(context.Background(), metadataStatuses, &values, fetch); err != nil || values[0] != "fetch 2" {
+		t.Errorf("expected the refreshed snapshot to be saved, got %v (%v)", values, err)
+	}
+}
+
+func TestMetadataStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ds.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var values []string
+	err := newMetadataStore(path).get(context.Background(), metadataProjects, &values, func(context.Context) (interface{}, error) {
+		return []string{"live"}, nil
+	})
+	if err != nil || len(values) != 1 || values[0] != "live" {
+		t.Errorf("expected a live fetch, got %v (%v)", values, err)
+	}
+}
+
+func TestMetadataStoreClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ds.json")
+	store := newMetadataStore(path)
+	store.snapshots[metadataStatuses] = metadataSnapshot{FetchedAt: time.Now().Add(-2 * metadataMaxAge), Data: []byte(`["stale"]`)}
+
+	// The background refresh of the stale snapshot blocks until it is cancelled.
+	started := make(chan struct{})
+	var values []string
+	err := store.get(context.Background(), metadataStatuses, &values, func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return []string{"fresh"}, nil
+	})
+	if err != nil || values[0] != "stale" {
+		t.Fatalf("expected the stale snapshot, got %v (%v)", values, err)
+	}
+	<-started
+	store.close()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the closed store not to write the snapshot, got %v", err)
+	}
+	store.refreshAsync(metadataStatuses, func(context.Context) (interface{}, error) {
+		t.Error("expected a closed store not to refresh")
+		return nil, nil
+	})
+	store.refreshes.Wait()
+}
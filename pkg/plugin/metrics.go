@@ -0,0 +1,33 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// metricNames are the metrics the query model accepts. Anything else is counted
+// as "unknown" to keep the label cardinality bounded.
+var metricNames = []string{
+	"changelogRaw", "cycletime", "jql", "transitionMatrix", "timeToFirstTransition",
+	"handovers", "cycletimeTrend", "wip", "links", "backlogGrowth", "projects",
+	"annotations", "agingWip", "statusFlow", "defectRatio", "mttr",
+	"sprintChurn", "burndown", "reviewLatency", "firstTimeRight",
+	"weightedCount", "cohort",
+}
+
+var queryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "grafana_jira_datasource",
+	Name:      "query_duration_seconds",
+	Help:      "Duration of queries by metric, including the requests to Jira.",
+	Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+}, []string{"metric"})
+
+// observeQuery records the duration of a query started at start.
+func observeQuery(metric string, start time.Time) {
+	if !containsString(metricNames, metric) {
+		metric = "unknown"
+	}
+	queryDuration.WithLabelValues(metric).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// defaultIncidentTypes are the issue types mttr looks at when the query doesn't
+// configure any.
+var defaultIncidentTypes = []string{"Incident"}
+
+// noPriority groups the incidents that have no priority.
+const noPriority = "None"
+
+// incident is an incident-type issue that was resolved in the time range.
+type incident struct {
+	resolved time.Time
+	hours    float64
+}
+
+// issuePriority returns the name and the rank (Jira's priority id, lowest is
+// most urgent) of the issue's priority, noPriority when it has none.
+func issuePriority(issue jira.Issue) (string, int) {
+	if p, ok := issue.Fields["priority"].(map[string]interface{}); ok {
+		if name, ok := p["name"].(string); ok && name != "" {
+			id, _ := p["id"].(string)
+			rank, err := strconv.Atoi(id)
+			if err != nil {
+				rank = -1
+			}
+			return name, rank
+		}
+	}
+	return noPriority, -1
+}
+
+// sortPriorities sorts priorities by their rank, known priorities in Jira's
+// order and the others (and None) by name after them.
+func sortPriorities(priorities []string, ranks map[string]int) {
+	sort.Slice(priorities, func(i, j int) bool {
+		a, b := ranks[priorities[i]], ranks[priorities[j]]
+		if (a < 0) != (b < 0) {
+			return b < 0
+		}
+		if a != b {
+			return a < b
+		}
+		return priorities[i] < priorities[j]
+	})
+}
+
+// getMTTRData computes the hours from creation to resolution of the incidents
+// (issues of the incidentTypes, default Incident) resolved in the time range and
+// emits one row per priority with Count, MeanHours, MedianHours and P90Hours,
+// the most urgent priority first. Issues without a resolution date are ignored.
+// With format "timeseries", one frame per priority with the mean per bucket
+// follows, null for buckets without resolutions.
+func (d *Datasource) getMTTRData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	types := parseList(qm.IncidentTypes)
+	if len(types) == 0 {
+		types = defaultIncidentTypes
+	}
+
+	byPriority := map[string][]incident{}
+	ranks := map[string]int{}
+	for _, issue := range issues {
+		if !listHas(types, issueTypeName(issue)) {
+			continue
+		}
+		createdRaw, _ := issue.Fields["created"].(string)
+		resolvedRaw, _ := issue.Fields["resolutiondate"].(string)
+		created, err := parseJiraTime(createdRaw)
+		if err != nil {
+			continue
+		}
+		resolved, err := parseJiraTime(resolvedRaw)
+		if err != nil || resolved.Before(timeRange.From) || resolved.After(timeRange.To) {
+			continue
+		}
+
+		priority, rank := issuePriority(issue)
+		ranks[priority] = rank
+		byPriority[priority] = append(byPriority[priority], incident{
+			resolved: resolved,
+			hours:    resolved.Sub(created).Hours(),
+		})
+	}
+
+	// Known priorities in Jira's order, the others (and None) by name after them.
+	priorities := make([]string, 0, len(byPriority))
+	for priority := range byPriority {
+		priorities = append(priorities, priority)
+	}
+	sortPriorities(priorities, ranks)
+
+	frame := data.NewFrame("response",
+		data.NewField("Priority", nil, []string{}),
+		data.NewField("Count", nil, []int64{}),
+		data.NewField("MeanHours", nil, []float64{}),
+		data.NewField("MedianHours", nil, []float64{}),
+		data.NewField("P90Hours", nil, []float64{}),
+	)
+	for _, priority := range priorities {
+		hours := incidentHours(byPriority[priority])
+		frame.AppendRow(
+			priority,
+			int64(len(hours)),
+			mean(hours),
+			quantile(hours, 50, qm.QuantileMethod),
+			quantile(hours, 90, qm.QuantileMethod),
+		)
+	}
+	if err := sortFrame(frame, qm, "", "", "Priority"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	response.Frames = append(response.Frames, frame)
+
+	if qm.Format != formatTimeSeries {
+		return response
+	}
+
+	size, err := bucketSize(qm, timeRange)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	buckets := bucketStarts(timeRange, size, qm.loc())
+	seriesPriorities := priorities
+	if len(seriesPriorities) == 0 {
+		// Keeps the schema of the series without any incident.
+		seriesPriorities = []string{""}
+	}
+	for _, priority := range seriesPriorities {
+		perBucket := make([][]float64, len(buckets))
+		for _, inc := range byPriority[priority] {
+			if i, ok := bucketIndex(timeRange, buckets, inc.resolved); ok {
+				perBucket[i] = append(perBucket[i], inc.hours)
+			}
+		}
+		values := make([]*float64, len(buckets))
+		for i, hours := range perBucket {
+			if len(hours) > 0 {
+				v := mean(hours)
+				values[i] = &v
+			}
+		}
+
+		name, labels := priority, data.Labels{"priority": priority}
+		if priority == "" {
+			name, labels = "series", nil
+		}
+		series := data.NewFrame(name,
+			data.NewField("Time", nil, buckets),
+			data.NewField("MeanHours", labels, values),
+		)
+		series.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti})
+		setBucketInterval(series, size)
+		response.Frames = append(response.Frames, series)
+	}
+
+	return response
+}
+
+// incidentHours returns the time to resolve of every incident.
+func incidentHours(incidents []incident) []float64 {
+	hours := make([]float64, len(incidents))
+	for i, inc := range incidents {
+		hours[i] = inc.hours
+	}
+	return hours
+}
+
+// mean returns the average of values, 0 for no values.
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
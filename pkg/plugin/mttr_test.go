@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func newTestIncident(key, issueType, priorityID, priority, created, resolved string) jira.Issue {
+	issue := newTestIssue(key, issueType)
+	issue.Fields["created"] = created
+	if resolved != "" {
+		issue.Fields["resolutiondate"] = resolved
+	}
+	if priority != "" {
+		issue.Fields["priority"] = map[string]interface{}{"id": priorityID, "name": priority}
+	}
+	return issue
+}
+
+func TestMTTR(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIncident("T-1", "Incident", "3", "Medium", "2024-01-01T10:00:00.000+0000", "2024-01-01T12:00:00.000+0000"),
+		newTestIncident("T-2", "Incident", "1", "Highest", "2024-01-02T10:00:00.000+0000", "2024-01-02T11:00:00.000+0000"),
+		newTestIncident("T-3", "Incident", "1", "Highest", "2024-01-02T10:00:00.000+0000", "2024-01-02T13:00:00.000+0000"),
+		newTestIncident("T-4", "Incident", "", "", "2024-01-03T10:00:00.000+0000", "2024-01-03T14:00:00.000+0000"),
+		// Not resolved, resolved before the range and not an incident.
+		newTestIncident("T-5", "Incident", "1", "Highest", "2024-01-02T10:00:00.000+0000", ""),
+		newTestIncident("T-6", "Incident", "1", "Highest", "2023-12-01T10:00:00.000+0000", "2023-12-02T10:00:00.000+0000"),
+		newTestIncident("T-7", "Bug", "1", "Highest", "2024-01-02T10:00:00.000+0000", "2024-01-02T20:00:00.000+0000"),
+	}
+
+	res := ds.getMTTRData(issues, queryModel{}, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 3 {
+		t.Fatalf("expected 3 priorities, got %d", frame.Rows())
+	}
+	for i, want := range []string{"Highest", "Medium", noPriority} {
+		if got := frame.Fields[0].At(i); got != want {
+			t.Errorf("row %d: expected priority %s, got %v", i, want, got)
+		}
+	}
+	if count, mean := frame.Fields[1].At(0), frame.Fields[2].At(0); count != int64(2) || mean != 2.0 {
+		t.Errorf("expected 2 Highest incidents resolved in 2 hours on average, got %v in %v", count, mean)
+	}
+
+	res = ds.getMTTRData(issues, queryModel{IncidentTypes: "Bug", Format: formatTimeSeries, Interval: "1d"}, testTimeRange())
+	if len(res.Frames) != 2 {
+		t.Fatalf("expected the table and one series, got %d frames", len(res.Frames))
+	}
+	series := res.Frames[1].Fields[1]
+	if series.Labels["priority"] != "Highest" {
+		t.Errorf("expected the series to be labelled with the priority, got %v", series.Labels)
+	}
+	if got := series.At(1).(*float64); got == nil || *got != 10 {
+		t.Errorf("expected a mean of 10 hours on the 2nd, got %v", got)
+	}
+	if got := series.At(0).(*float64); got != nil {
+		t.Errorf("expected no mean without resolutions, got %v", *got)
+	}
+}
@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+const (
+	// formatNodeGraph selects the Node Graph panel output of the links metric.
+	formatNodeGraph = "nodeGraph"
+
+	nodeStatStatusCategory = "statusCategory"
+	nodeStatCycletime      = "cycletime"
+)
+
+// statusCategoryColors maps Jira status category keys onto node colors.
+var statusCategoryColors = map[string]string{
+	"new":           "blue",
+	"indeterminate": "yellow",
+	"done":          "green",
+}
+
+// statusCategoryKey returns the status category ("new", "indeterminate", "done")
+// of a status object as returned in issue fields.
+func statusCategoryKey(status interface{}) string {
+	if st, ok := status.(map[string]interface{}); ok {
+		if category, ok := st["statusCategory"].(map[string]interface{}); ok {
+			if key, ok := category["key"].(string); ok {
+				return key
+			}
+		}
+	}
+	return ""
+}
+
+type graphNode struct {
+	id       string
+	subtitle string
+	status   string
+	category string
+	summary  string
+	cycle    *float64
+}
+
+// getNodeGraphData shapes issues and their links into the nodes and edges frames
+// expected by the Node Graph panel. Nodes are colored by status category; their
+// main stat is the status name, or the cycle time in days with qm.NodeStat
+// "cycletime". Linked issues outside of the result set are added as nodes too.
+func (d *Datasource) getNodeGraphData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	nodeStat := qm.NodeStat
+	if nodeStat == "" {
+		nodeStat = nodeStatStatusCategory
+	}
+	if nodeStat != nodeStatStatusCategory && nodeStat != nodeStatCycletime {
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("unknown node stat: %s", nodeStat))
+	}
+
+	cycles := map[string]float64{}
+	if nodeStat == nodeStatCycletime {
+		for _, c := range collectCycles(issues, qm, timeRange) {
+			cycles[c.issue.Key] = c.days
+		}
+	}
+
+	var order []string
+	nodes := map[string]*graphNode{}
+	for _, issue := range issues {
+		node := &graphNode{id: issue.Key, subtitle: issueTypeName(issue)}
+		if st, ok := issue.Fields["status"].(map[string]interface{}); ok {
+			node.status, _ = st["name"].(string)
+		}
+		node.category = statusCategoryKey(issue.Fields["status"])
+		summary, _ := issue.Fields["summary"].(string)
+		node.summary = truncateText(summary, qm.textLength)
+		if days, ok := cycles[issue.Key]; ok {
+			node.cycle = &days
+		}
+		nodes[issue.Key] = node
+		order = append(order, issue.Key)
+	}
+
+	links := collectLinks(issues, parseList(qm.LinkTypes))
+	for _, link := range links {
+		for _, key := range []string{link.sourceKey, link.targetKey} {
+			if _, ok := nodes[key]; !ok {
+				nodes[key] = &graphNode{id: key, status: link.targetStatus, category: link.targetCategory}
+				order = append(order, key)
+			}
+		}
+	}
+
+	nodeFrame := data.NewFrame("nodes",
+		data.NewField("id", nil, []string{}),
+		data.NewField("title", nil, []string{}),
+		data.NewField("subtitle", nil, []string{}),
+		data.NewField("detail__summary", nil, []string{}),
+		data.NewField("color", nil, []string{}),
+	)
+	if nodeStat == nodeStatCycletime {
+		nodeFrame.Fields = append(nodeFrame.Fields, data.NewField("mainstat", nil, []*float64{}))
+	} else {
+		nodeFrame.Fields = append(nodeFrame.Fields, data.NewField("mainstat", nil, []string{}))
+	}
+	nodeFrame.SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph})
+
+	for _, key := range order {
+		node := nodes[key]
+		color, ok := statusCategoryColors[node.category]
+		if !ok {
+			color = "gray"
+		}
+		if nodeStat == nodeStatCycletime {
+			nodeFrame.AppendRow(node.id, node.id, node.subtitle, node.summary, color, node.cycle)
+		} else {
+			nodeFrame.AppendRow(node.id, node.id, node.subtitle, node.summary, color, node.status)
+		}
+	}
+
+	edgeFrame := data.NewFrame("edges",
+		data.NewField("id", nil, []string{}),
+		data.NewField("source", nil, []string{}),
+		data.NewField("target", nil, []string{}),
+		data.NewField("mainstat", nil, []string{}),
+	)
+	edgeFrame.SetMeta(&data.FrameMeta{PreferredVisualization: data.VisTypeNodeGraph})
+
+	for i, link := range links {
+		// Edges always point along the outward description, e.g. blocker -> blocked.
+		source, target := link.sourceKey, link.targetKey
+		if link.direction == linkInward {
+			source, target = target, source
+		}
+		id := link.id
+		if id == "" {
+			id = fmt.Sprintf("%s-%s-%d", source, target, i)
+		}
+		edgeFrame.AppendRow(id, source, target, link.typeName)
+	}
+
+	response.Frames = append(response.Frames, nodeFrame, edgeFrame)
+	return response
+}
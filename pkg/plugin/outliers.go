@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+)
+
+// outlierFilterIQR excludes cycles outside 1.5 interquartile ranges of the
+// quartiles (Tukey's fences).
+const outlierFilterIQR = "iqr"
+
+// filtersOutliers reports whether the query excludes any cycles from aggregates.
+func filtersOutliers(qm queryModel) bool {
+	return qm.MinCycleDays > 0 || qm.MaxCycleDays > 0 || qm.OutlierFilter != ""
+}
+
+// filterCycles splits cycles into those that count towards quantiles and
+// summaries and those excluded as outliers: first by the minCycleDays and
+// maxCycleDays bounds, then, with outlierFilter "iqr", by the interquartile range
+// of the remaining cycles.
+func filterCycles(cycles []cycle, qm queryModel) (kept, excluded []cycle, err error) {
+	if qm.OutlierFilter != "" && qm.OutlierFilter != outlierFilterIQR {
+		return nil, nil, fmt.Errorf("unknown outlier filter: %s", qm.OutlierFilter)
+	}
+
+	for _, c := range cycles {
+		if (qm.MinCycleDays > 0 && c.days < qm.MinCycleDays) || (qm.MaxCycleDays > 0 && c.days > qm.MaxCycleDays) {
+			excluded = append(excluded, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+
+	if qm.OutlierFilter == outlierFilterIQR && len(kept) > 0 {
+		days := cycleDaysOf(kept)
+		sort.Float64s(days)
+		q1, q3 := quantileSorted(days, 25, quantileLinear), quantileSorted(days, 75, quantileLinear)
+		low, high := q1-1.5*(q3-q1), q3+1.5*(q3-q1)
+
+		inside := kept[:0:0]
+		for _, c := range kept {
+			if c.days < low || c.days > high {
+				excluded = append(excluded, c)
+			} else {
+				inside = append(inside, c)
+			}
+		}
+		kept = inside
+	}
+	return kept, excluded, nil
+}
+
+// cycleDaysOf returns the cycle times of cycles.
+func cycleDaysOf(cycles []cycle) []float64 {
+	days := make([]float64, len(cycles))
+	for i, c := range cycles {
+		days[i] = c.days
+	}
+	return days
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func testCycles(days ...float64) []cycle {
+	cycles := make([]cycle, len(days))
+	for i, d := range days {
+		cycles[i] = cycle{issue: jira.Issue{Key: "T-" + string(rune('A'+i))}, days: d}
+	}
+	return cycles
+}
+
+func TestFilterCycles(t *testing.T) {
+	cycles := testCycles(1, 2, 3, 4, 5, 400)
+
+	kept, excluded, err := filterCycles(cycles, queryModel{MinCycleDays: 2, MaxCycleDays: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) != 4 || len(excluded) != 2 {
+		t.Errorf("expected 4 kept and 2 excluded cycles, got %d and %d", len(kept), len(excluded))
+	}
+
+	kept, excluded, _ = filterCycles(cycles, queryModel{OutlierFilter: outlierFilterIQR})
+	if len(excluded) != 1 || excluded[0].days != 400 {
+		t.Errorf("expected only the 400 day cycle to be excluded, got %v", cycleDaysOf(excluded))
+	}
+	if len(kept) != 5 {
+		t.Errorf("expected 5 kept cycles, got %d", len(kept))
+	}
+
+	if _, _, err := filterCycles(cycles, queryModel{OutlierFilter: "zscore"}); err == nil {
+		t.Errorf("expected an error for an unknown filter")
+	}
+}
+
+func TestCycletimeListExcluded(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		newTestIssue("T-2", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-30T10:00:00.000+0000", "In Progress", "Done"},
+		),
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", Quantile: 100, MaxCycleDays: 10, ListExcluded: true}
+
+	frame := ds.getCycletimeData(issues, qm, testTimeRange()).Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("expected both cycles to be listed, got %d rows", frame.Rows())
+	}
+	excluded, _ := frame.FieldByName("Excluded")
+	quantileField, _ := frame.FieldByName("Quantile")
+	for i := 0; i < frame.Rows(); i++ {
+		key := frame.Fields[0].At(i).(string)
+		if got := excluded.At(i).(bool); got != (key == "T-2") {
+			t.Errorf("%s: unexpected Excluded %v", key, got)
+		}
+		// Only the 2 day cycle of T-1 counts towards the quantile.
+		if got := quantileField.At(i).(float64); got != 2 {
+			t.Errorf("expected the quantile to ignore the excluded cycle, got %v", got)
+		}
+	}
+	if custom := frame.Meta.Custom.(map[string]interface{}); custom["excludedRows"] != 1 {
+		t.Errorf("expected excludedRows 1 in the meta, got %v", custom["excludedRows"])
+	}
+}
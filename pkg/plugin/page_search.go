@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+const (
+	// defaultPageSize is the number of issues of a page when a query only sets
+	// a pageToken.
+	defaultPageSize = 50
+	// maxPageSize is the most issues Jira returns in a page with fields.
+	maxPageSize = 100
+)
+
+// paged reports whether the query fetches a single page of issues, for tables
+// that load more rows on demand, instead of all issues.
+func (qm queryModel) paged() bool {
+	return qm.PageSize > 0 || qm.PageToken != ""
+}
+
+// validatePaging rejects paging options outside of the jql metric and page
+// sizes Jira doesn't support.
+func validatePaging(qm queryModel) error {
+	if qm.PageSize < 0 || qm.PageSize > maxPageSize {
+		return fmt.Errorf("pageSize must be between 1 and %d", maxPageSize)
+	}
+	if qm.paged() && qm.Metric != "jql" {
+		return fmt.Errorf("pageToken and pageSize are only supported by the jql metric")
+	}
+	return nil
+}
+
+// searchIssuePage fetches the page of issues matching the JQL of qm that starts
+// at its pageToken, along with the token of the next page, empty after the last
+// one. Pages are never split, the token only continues the search it came from.
+func searchIssuePage(ctx context.Context, client *jira.Client, qm queryModel, timeRange backend.TimeRange) ([]jira.Issue, []data.Notice, string, error) {
+	jql, jqlNotice := finalJQL(qm, timeRange)
+	pageSize := qm.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+	result, err := client.SearchPage(ctx, jql, searchOptions(qm), qm.PageToken, pageSize)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return result.Issues, searchNotices(jqlNotice, result.WarningMessages), result.NextPageToken, nil
+}
+
+// setNextPageToken records the token of the next page on every frame of res
+// under "nextPageToken", empty when there are no more issues.
+func setNextPageToken(res *backend.DataResponse, token string) {
+	for _, frame := range res.Frames {
+		setCustomMeta(frame, "nextPageToken", token)
+	}
+}
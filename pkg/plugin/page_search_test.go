@@ -0,0 +1,91 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestPagedJQLQuery(t *testing.T) {
+	var requests []jira.JQLSearchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/search/jql" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+			return
+		}
+		var req jira.JQLSearchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, req)
+		switch req.NextPageToken {
+		case "":
+			fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}},{"key":"A-2","fields":{}}],"nextPageToken":"page-2"}`)
+		case "page-2":
+			fmt.Fprint(w, `{"issues":[{"key":"A-3","fields":{}}]}`)
+		default:
+			t.Errorf("unexpected page token %q", req.NextPageToken)
+		}
+	}))
+	defer server.Close()
+
+	settings := backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"url":"` + server.URL + `", "username":"user"}`),
+		DecryptedSecureJSONData: map[string]string{"token": "token"},
+	}
+	page := func(query string) backend.DataResponse {
+		t.Helper()
+		resp, err := (&Datasource{}).QueryData(context.Background(), &backend.QueryDataRequest{
+			PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+			Queries:       []backend.DataQuery{{RefID: "A", JSON: []byte(query), TimeRange: testTimeRange()}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Responses["A"]
+	}
+	nextPageToken := func(res backend.DataResponse) interface{} {
+		t.Helper()
+		if res.Error != nil {
+			t.Fatal(res.Error)
+		}
+		custom, _ := res.Frames[0].Meta.Custom.(map[string]interface{})
+		return custom["nextPageToken"]
+	}
+
+	// The first page, and the next one from its token, each fetched with a
+	// single request.
+	first := page(`{"metric":"jql","jqlQuery":"project = A","pageSize":2}`)
+	if token := nextPageToken(first); token != "page-2" || first.Frames[0].Rows() != 2 {
+		t.Errorf("expected 2 issues and the next token, got %d and %v", first.Frames[0].Rows(), token)
+	}
+	last := page(`{"metric":"jql","jqlQuery":"project = A","pageSize":2,"pageToken":"page-2"}`)
+	if token := nextPageToken(last); token != "" || last.Frames[0].Rows() != 1 {
+		t.Errorf("expected the last issue without a next token, got %d and %v", last.Frames[0].Rows(), token)
+	}
+	if len(requests) != 2 || requests[0].MaxResults != 2 || requests[1].JQL != requests[0].JQL {
+		t.Errorf("expected one request of 2 issues per page for the same JQL, got %+v", requests)
+	}
+
+	// Without paging, the table has no token.
+	requests = nil
+	all := page(`{"metric":"jql","jqlQuery":"project = A"}`)
+	if token, ok := all.Frames[0].Meta.Custom.(map[string]interface{})["nextPageToken"]; ok || all.Frames[0].Rows() != 3 {
+		t.Errorf("expected all 3 issues without a token, got %d and %v", all.Frames[0].Rows(), token)
+	}
+
+	for _, qm := range []queryModel{
+		{Metric: "cycletime", PageSize: 10},
+		{Metric: "jql", PageSize: maxPageSize + 1},
+	} {
+		if err := qm.validate(); err == nil {
+			t.Errorf("expected %s with pageSize %d to be rejected", qm.Metric, qm.PageSize)
+		}
+	}
+}
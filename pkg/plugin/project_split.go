@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// projectListClause matches the "project = X" and "project in (X, Y)" clauses
+// of a JQL query, as a multi-value project variable is interpolated.
+var projectListClause = regexp.MustCompile(`(?i)\bproject\s*(?:=|in\b)\s*(\([^)]*\)|"[^"]*"|'[^']*'|[^\s()]+)`)
+
+// jqlProjects returns the projects the JQL query asks for in its "project = X"
+// and "project in (X, Y)" clauses, in order and without repetitions.
+func jqlProjects(jql string) []string {
+	var projects []string
+	for _, clause := range projectListClause.FindAllStringSubmatch(jql, -1) {
+		for _, project := range strings.Split(strings.Trim(clause[1], "()"), ",") {
+			project = strings.Trim(strings.TrimSpace(project), `"'`)
+			if project != "" && !containsString(projects, project) {
+				projects = append(projects, project)
+			}
+		}
+	}
+	return projects
+}
+
+// unsplitMetrics are the metrics that can't be split by project because they
+// make their own requests to Jira for the whole query, e.g. the sprint of
+// sprintChurn, or don't look at issues at all.
+var unsplitMetrics = []string{"backlogGrowth", "sprintChurn", "burndown", "projects", "weightedCount"}
+
+// validateSplitByProject rejects splitByProject for the unsplitMetrics.
+func validateSplitByProject(qm queryModel) error {
+	if qm.SplitByProject && containsString(unsplitMetrics, qm.Metric) {
+		return fmt.Errorf("splitByProject is not supported by the %s metric", qm.Metric)
+	}
+	return nil
+}
+
+// projectGroup is the issues of one project of a query split by project.
+type projectGroup struct {
+	project string
+	issues  []jira.Issue
+}
+
+// groupByProject groups the issues by their project key, adding an empty group
+// for every project of the JQL query without issues, matched by key or name
+// ignoring case. Groups are sorted by project.
+func groupByProject(issues []jira.Issue, jql string) []projectGroup {
+	byKey := map[string]*projectGroup{}
+	names := map[string]bool{}
+	for _, issue := range issues {
+		key := projectKey(issue)
+		if key == "" {
+			key = noLabel
+		}
+		if byKey[key] == nil {
+			byKey[key] = &projectGroup{project: key}
+		}
+		byKey[key].issues = append(byKey[key].issues, issue)
+		names[strings.ToLower(key)] = true
+		if p, ok := issue.Fields["project"].(map[string]interface{}); ok {
+			if name, ok := p["name"].(string); ok {
+				names[strings.ToLower(name)] = true
+			}
+		}
+	}
+	for _, project := range jqlProjects(jql) {
+		if !names[strings.ToLower(project)] {
+			names[strings.ToLower(project)] = true
+			byKey[project] = &projectGroup{project: project}
+		}
+	}
+
+	groups := make([]projectGroup, 0, len(byKey))
+	for _, group := range byKey {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].project < groups[j].project })
+	return groups
+}
+
+// buildProjectFrames builds the frames of the metric separately for the issues of
+// every project, see groupByProject, so that a single panel can show a series
+// per project of a multi-value project variable. It returns the project of
+// every frame; the frames keep their names until nameProjectFrames. The value
+// fields of time series are labelled with the project right away.
+func (d *Datasource) buildProjectFrames(ctx context.Context, client *jira.Client, config *models.PluginSettings, qm queryModel, timeRange backend.TimeRange, issues []jira.Issue) (backend.DataResponse, []string) {
+	var res backend.DataResponse
+	var projects []string
+	for _, group := range groupByProject(issues, qm.JQLQuery) {
+		part := d.buildFrames(ctx, client, config, qm, timeRange, group.issues)
+		if part.Error != nil {
+			return part, nil
+		}
+		for _, frame := range part.Frames {
+			setCustomMeta(frame, "project", group.project)
+			if frame.Meta.Type == data.FrameTypeTimeSeriesWide || frame.Meta.Type == data.FrameTypeTimeSeriesMulti || frame.Meta.Type == data.FrameTypeTimeSeriesLong {
+				labelProject(frame, group.project)
+			}
+			projects = append(projects, group.project)
+		}
+		res.Frames = append(res.Frames, part.Frames...)
+	}
+	return res, projects
+}
+
+// labelProject adds the project label to the value fields of frame.
+func labelProject(frame *data.Frame, project string) {
+	for _, field := range frame.Fields {
+		if field.Type().Time() {
+			continue
+		}
+		labels := data.Labels{}
+		for name, value := range field.Labels {
+			labels[name] = value
+		}
+		labels["project"] = project
+		field.Labels = labels
+	}
+}
+
+// nameProjectFrames names the frames of buildProjectFrames after their project,
+// followed by the name of frames other than the main one, e.g. "PLAT summary".
+// Node graph frames keep the names the panel expects.
+func nameProjectFrames(res *backend.DataResponse, projects []string) {
+	for i, frame := range res.Frames {
+		if i >= len(projects) || frame.Meta != nil && frame.Meta.PreferredVisualization == data.VisTypeNodeGraph {
+			continue
+		}
+		name := projects[i]
+		if frame.Name != "response" && frame.Name != "" {
+			name += " " + frame.Name
+		}
+		frame.Name = name
+	}
+}
@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestJQLProjects(t *testing.T) {
+	tests := map[string]string{
+		`project in (PLAT, "Mobile App") AND status = Done`: "PLAT|Mobile App",
+		`project = OPS ORDER BY created`:                    "OPS",
+		`project IN ('WEB') OR project = WEB`:               "WEB",
+		`project not in (OPS) AND project != WEB`:           "",
+		`assignee = currentUser()`:                          "",
+	}
+	for jql, want := range tests {
+		if got := strings.Join(jqlProjects(jql), "|"); got != want {
+			t.Errorf("%s: expected %q, got %q", jql, want, got)
+		}
+	}
+}
+
+func TestSplitByProject(t *testing.T) {
+	inProject := func(key, name string, transitions ...[3]string) jira.Issue {
+		issue := newTestIssue(key+"-1", "Story", transitions...)
+		issue.Fields["project"] = map[string]interface{}{"key": key, "name": name}
+		return issue
+	}
+	issues := []jira.Issue{
+		inProject("PLAT", "Platform",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		inProject("OPS", "Operations",
+			[3]string{"2024-01-03T10:00:00.000+0000", "To Do", "In Progress"},
+		),
+	}
+	ds := &Datasource{}
+	// The variable lists Platform by name, and WEB without any issues.
+	qm := queryModel{JQLQuery: `project in (Platform, OPS, WEB)`, SplitByProject: true, StartStatus: "In Progress", EndStatus: "Done"}
+
+	qm.Metric = "wip"
+	qm.Interval = "1d"
+	res, projects := ds.buildProjectFrames(context.Background(), nil, &models.PluginSettings{}, qm, testTimeRange(), issues)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	setFrameHints(&res)
+	nameProjectFrames(&res, projects)
+	nameFrames(&res, "A", qm.Metric)
+	var got []string
+	for _, frame := range res.Frames {
+		wip := frame.Fields[1]
+		var max int64
+		for i := 0; i < wip.Len(); i++ {
+			if v := wip.At(i).(int64); v > max {
+				max = v
+			}
+		}
+		got = append(got, fmt.Sprintf("%s %s %d", frame.Name, wip.Labels, max))
+	}
+	if want := "A wip OPS project=OPS 1, A wip PLAT project=PLAT 1, A wip WEB project=WEB 0"; strings.Join(got, ", ") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(got, ", "))
+	}
+
+	// Tables are split too, an empty project keeps the columns.
+	qm.Metric = "cycletime"
+	res, projects = ds.buildProjectFrames(context.Background(), nil, &models.PluginSettings{}, qm, testTimeRange(), issues)
+	nameProjectFrames(&res, projects)
+	got = nil
+	for _, frame := range res.Frames {
+		got = append(got, fmt.Sprintf("%s %d", frame.Name, frame.Rows()))
+		if len(frame.Fields) == 0 || frame.Fields[0].Labels != nil {
+			t.Errorf("expected unlabelled table columns in %s", frame.Name)
+		}
+		if custom, _ := frame.Meta.Custom.(map[string]interface{}); custom["project"] == nil {
+			t.Errorf("expected the project in the custom meta of %s", frame.Name)
+		}
+	}
+	if want := "OPS 0, PLAT 1, WEB 0"; strings.Join(got, ", ") != want {
+		t.Errorf("expected %s, got %s", want, strings.Join(got, ", "))
+	}
+}
+
+func TestSplitByProjectUnsupported(t *testing.T) {
+	for _, metric := range unsplitMetrics {
+		if err := (queryModel{Metric: metric, SplitByProject: true}).validate(); err == nil {
+			t.Errorf("expected splitByProject to be rejected for %s", metric)
+		}
+	}
+	if err := (queryModel{Metric: "wip", SplitByProject: true}).validate(); err != nil {
+		t.Errorf("expected splitByProject to be accepted for wip, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+type projectResponse struct {
+	Key             string `json:"key"`
+	Name            string `json:"name"`
+	ProjectCategory string `json:"projectCategory"`
+	Lead            string `json:"lead"`
+	Archived        bool   `json:"archived"`
+}
+
+func toProjectResponse(p jira.Project) projectResponse {
+	res := projectResponse{Key: p.Key, Name: p.Name, Archived: p.Archived}
+	if p.ProjectCategory != nil {
+		res.ProjectCategory = p.ProjectCategory.Name
+	}
+	if p.Lead != nil {
+		res.Lead = p.Lead.DisplayName
+	}
+	return res
+}
+
+// handleProjects lists the projects for project picker variables.
+// Archived projects are left out unless ?includeArchived=true.
+func (d *Datasource) handleProjects(w http.ResponseWriter, r *http.Request) {
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("includeArchived"))
+
+	client, _, err := d.clientFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	projects, err := d.projects(r.Context(), client, includeArchived)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	res := make([]projectResponse, 0, len(projects))
+	for _, p := range projects {
+		res = append(res, toProjectResponse(p))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// getProjectsData returns the projects table, e.g. for a "projects by lead" panel.
+// It doesn't run the JQL search.
+func (d *Datasource) getProjectsData(ctx context.Context, client *jira.Client, qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	projects, err := d.projects(ctx, client, qm.IncludeArchived)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("jira project search failed: %v", err.Error()))
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("Key", nil, []string{}),
+		data.NewField("Name", nil, []string{}),
+		data.NewField("Category", nil, []*string{}),
+		data.NewField("Lead", nil, []*string{}),
+		data.NewField("Archived", nil, []bool{}),
+	)
+	for _, p := range projects {
+		res := toProjectResponse(p)
+		frame.AppendRow(res.Key, res.Name, optionalString(res.ProjectCategory), optionalString(qm.userName(res.Lead)), res.Archived)
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
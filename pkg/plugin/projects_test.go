@@ -0,0 +1,43 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProjectsResource(t *testing.T) {
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/project/search" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		if got := r.URL.Query()["status"]; len(got) != 1 || got[0] != "live" {
+			t.Errorf("expected only live projects, got %v", got)
+		}
+		fmt.Fprint(w, `{"isLast":true,"values":[
+			{"id":"1","key":"PLAT","name":"Platform","projectCategory":{"id":"10","name":"Engineering"},"lead":{"displayName":"Alice"}},
+			{"id":"2","key":"WEB","name":"Web"}
+		]}`)
+	}))
+	defer jiraServer.Close()
+
+	rec := httptest.NewRecorder()
+	(&Datasource{}).newResourceMux().ServeHTTP(rec, newResourceRequest(t, jiraServer.URL, "/projects"))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var res []projectResponse
+	if err := json.NewDecoder(rec.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	want := []projectResponse{
+		{Key: "PLAT", Name: "Platform", ProjectCategory: "Engineering", Lead: "Alice"},
+		{Key: "WEB", Name: "Web"},
+	}
+	if len(res) != len(want) || res[0] != want[0] || res[1] != want[1] {
+		t.Errorf("expected %+v, got %+v", want, res)
+	}
+}
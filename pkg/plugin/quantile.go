@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Percentile methods, named after their numpy counterparts.
+const (
+	// quantileLinear interpolates linearly between the two closest ranks.
+	quantileLinear = "linear"
+	// quantileNearestRank picks the smallest value that at least q percent of the
+	// values are less than or equal to.
+	quantileNearestRank = "nearestRank"
+	// quantileLower and quantileHigher pick the closest rank below or above.
+	quantileLower  = "lower"
+	quantileHigher = "higher"
+)
+
+// validateQuantileMethod rejects unknown percentile methods; "" means linear.
+func validateQuantileMethod(method string) error {
+	switch method {
+	case "", quantileLinear, quantileNearestRank, quantileLower, quantileHigher:
+		return nil
+	}
+	return fmt.Errorf("unknown quantile method: %s", method)
+}
+
+// quantile returns the q-th percentile (0-100) of values using the given method,
+// linear interpolation when it is empty. values is not modified. An empty input
+// yields 0.
+func quantile(values []float64, q float64, method string) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	return quantileSorted(sorted, q, method)
+}
+
+// quantileSorted is quantile for values that are already sorted ascending.
+func quantileSorted(sorted []float64, q float64, method string) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	q = math.Max(0, math.Min(100, q))
+
+	if method == quantileNearestRank {
+		rank := int(math.Ceil(percentOf(q, n)))
+		return sorted[max(rank, 1)-1]
+	}
+
+	// Index = q * (n-1)
+	pos := percentOf(q, n-1)
+	switch method {
+	case quantileLower:
+		return sorted[int(math.Floor(pos))]
+	case quantileHigher:
+		return sorted[int(math.Ceil(pos))]
+	}
+
+	base := int(pos)
+	rest := pos - float64(base)
+	if base+1 < n {
+		return sorted[base] + rest*(sorted[base+1]-sorted[base])
+	}
+	return sorted[base]
+}
+
+// percentOf returns q percent of n. Whole results are exact, so that Floor and
+// Ceil pick the right rank where q/100 has no exact binary representation, e.g.
+// 7% of 100.
+func percentOf(q float64, n int) float64 {
+	pos := q * float64(n) / 100
+	if whole := math.Round(pos); math.Abs(pos-whole) < 1e-9 {
+		return whole
+	}
+	return pos
+}
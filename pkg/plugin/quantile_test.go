@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuantile(t *testing.T) {
+	values := []float64{5, 1, 4, 2, 3}
+	tests := []struct {
+		method string
+		q      float64
+		want   float64
+	}{
+		{quantileLinear, 0, 1},
+		{quantileLinear, 50, 3},
+		{quantileLinear, 85, 4.4},
+		{quantileLinear, 100, 5},
+		{"", 95, 4.8},
+		// Ranks are 1-based: 85% of 5 values is rank 4.25, rounded up to 5.
+		{quantileNearestRank, 85, 5},
+		{quantileNearestRank, 80, 4},
+		{quantileNearestRank, 0, 1},
+		{quantileNearestRank, 100, 5},
+		// 85% lies between index 3 and 4.
+		{quantileLower, 85, 4},
+		{quantileHigher, 85, 5},
+		// Exact index hits don't move.
+		{quantileLower, 50, 3},
+		{quantileHigher, 50, 3},
+		{quantileHigher, 100, 5},
+		// Out of range percentiles are clamped.
+		{quantileLinear, 150, 5},
+	}
+	for _, tt := range tests {
+		if got := quantile(values, tt.q, tt.method); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("quantile(%v, %q) = %v, want %v", tt.q, tt.method, got, tt.want)
+		}
+	}
+	if values[0] != 5 {
+		t.Errorf("expected the input to stay unsorted, got %v", values)
+	}
+
+	for _, method := range []string{quantileLinear, quantileNearestRank, quantileLower, quantileHigher} {
+		if got := quantile(nil, 85, method); got != 0 {
+			t.Errorf("%s: expected 0 for no values, got %v", method, got)
+		}
+		for _, q := range []float64{0, 50, 100} {
+			if got := quantile([]float64{7}, q, method); got != 7 {
+				t.Errorf("%s: expected the single value for q=%v, got %v", method, q, got)
+			}
+		}
+	}
+
+	if err := validateQuantileMethod("median"); err == nil {
+		t.Errorf("expected an error for an unknown method")
+	}
+}
+
+func TestQuantileRanks(t *testing.T) {
+	// Where q/100 isn't exact in floating point, the rank used to be off by one.
+	tests := []struct {
+		method string
+		q      float64
+		n      int
+		index  int
+	}{
+		{quantileNearestRank, 7, 100, 6},
+		{quantileNearestRank, 14, 50, 6},
+		{quantileNearestRank, 28, 25, 6},
+		{quantileHigher, 7, 101, 7},
+		{quantileHigher, 14, 51, 7},
+		{quantileLower, 29, 101, 29},
+		{quantileLinear, 29, 101, 29},
+	}
+	for _, tt := range tests {
+		if got := quantileSorted(indexes(tt.n), tt.q, tt.method); got != float64(tt.index) {
+			t.Errorf("%s of %d values at q=%v: expected index %d, got %v", tt.method, tt.n, tt.q, tt.index, got)
+		}
+	}
+
+	// Every whole percentile of up to 200 values against integer arithmetic.
+	for n := 1; n <= 200; n++ {
+		sorted := indexes(n)
+		for q := 0; q <= 100; q++ {
+			want := map[string]int{
+				quantileNearestRank: max((q*n+99)/100, 1) - 1,
+				quantileLower:       q * (n - 1) / 100,
+				quantileHigher:      (q*(n-1) + 99) / 100,
+			}
+			for method, index := range want {
+				if got := quantileSorted(sorted, float64(q), method); got != float64(index) {
+					t.Fatalf("%s of %d values at q=%d: expected index %d, got %v", method, n, q, index, got)
+				}
+			}
+		}
+	}
+}
+
+// indexes returns the sorted values 0 to n-1, so that quantiles are indexes.
+func indexes(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	return values
+}
+
+func TestSummaryFrame(t *testing.T) {
+	frame := summaryFrame([]float64{5, 1, 4, 2, 3}, quantileLinear)
+	want := map[string]float64{"Mean": 3, "Median": 3, "P85": 4.4, "P95": 4.8, "Min": 1, "Max": 5}
+	for name, v := range want {
+		field, _ := frame.FieldByName(name)
+		if got, ok := field.ConcreteAt(0); !ok || math.Abs(got.(float64)-v) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", name, v, got)
+		}
+	}
+	if count := frame.Fields[0].At(0).(int64); count != 5 {
+		t.Errorf("expected a count of 5, got %d", count)
+	}
+
+	empty := summaryFrame(nil, quantileLinear)
+	if _, ok := empty.Fields[1].ConcreteAt(0); ok || empty.Fields[0].At(0).(int64) != 0 {
+		t.Errorf("expected a zero count and null stats without values")
+	}
+}
@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+const (
+	// recentWindow is how close to now a time range has to end for issues in it
+	// to still be changing.
+	recentWindow = 5 * time.Minute
+	// historicalCacheTTL is the hint for queries over a time range in the past.
+	historicalCacheTTL = time.Hour
+)
+
+// nowMetrics depend on the current time rather than only on the time range.
+var nowMetrics = []string{"wip", "agingWip", "sprintChurn", "burndown", "weightedCount"}
+
+// queryCacheHint is how long the response of a query can be reused, recorded in
+// the custom frame meta under "queryCache". The SDK has no way to pass a TTL to
+// Grafana's query caching, so this is meant for its configuration and for
+// debugging: responses of metrics that depend on now, or of time ranges ending
+// about now, are fresh only as long as the internal response cache (or not at
+// all without it), while historical ranges can be reused for an hour.
+type queryCacheHint struct {
+	TTLSeconds   int  `json:"ttlSeconds"`
+	DependsOnNow bool `json:"dependsOnNow"`
+}
+
+// newQueryCacheHint returns the cache hint of query at now.
+func newQueryCacheHint(config *models.PluginSettings, query backend.DataQuery, now time.Time) queryCacheHint {
+	var qm struct {
+		Metric string `json:"metric"`
+	}
+	_ = json.Unmarshal(query.JSON, &qm)
+
+	if containsString(nowMetrics, qm.Metric) || query.TimeRange.To.After(now.Add(-recentWindow)) {
+		return queryCacheHint{TTLSeconds: int(config.CacheTTL().Seconds()), DependsOnNow: true}
+	}
+	return queryCacheHint{TTLSeconds: int(historicalCacheTTL.Seconds())}
+}
+
+// setQueryCacheMeta records hint on every frame of res.
+func setQueryCacheMeta(res *backend.DataResponse, hint queryCacheHint) {
+	for _, frame := range res.Frames {
+		setCustomMeta(frame, "queryCache", hint)
+	}
+}
@@ -0,0 +1,32 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestQueryCacheHint(t *testing.T) {
+	config := &models.PluginSettings{CacheTTLSeconds: 60}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	past := testTimeRange()
+	recent := backend.TimeRange{From: now.Add(-24 * time.Hour), To: now}
+
+	tests := []struct {
+		metric    string
+		timeRange backend.TimeRange
+		want      queryCacheHint
+	}{
+		{"cycletime", past, queryCacheHint{TTLSeconds: 3600}},
+		{"cycletime", recent, queryCacheHint{TTLSeconds: 60, DependsOnNow: true}},
+		{"agingWip", past, queryCacheHint{TTLSeconds: 60, DependsOnNow: true}},
+	}
+	for _, tt := range tests {
+		query := backend.DataQuery{JSON: []byte(`{"metric":"` + tt.metric + `"}`), TimeRange: tt.timeRange}
+		if got := newQueryCacheHint(config, query, now); got != tt.want {
+			t.Errorf("%s over %v: expected %+v, got %+v", tt.metric, tt.timeRange, tt.want, got)
+		}
+	}
+}
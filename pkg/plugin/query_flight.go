@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// queryFlightSlack is how much further than the time that passed since a query
+// started the time range of an identical query may have moved to share it.
+const queryFlightSlack = time.Second
+
+// queryCall is a query in flight, done is closed when res is ready.
+type queryCall struct {
+	done    chan struct{}
+	res     backend.DataResponse
+	started time.Time
+	to      time.Time
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// covers reports whether a query over timeRange at now can share the call: its
+// range is the same, or the same relative range moved on by the time the call
+// has been running, as when auto-refresh fires again while it is still slow.
+func (c *queryCall) covers(timeRange backend.TimeRange, now time.Time) bool {
+	moved := timeRange.To.Sub(c.to)
+	return moved >= 0 && moved <= now.Sub(c.started)+queryFlightSlack
+}
+
+// queryFlight makes overlapping identical queries share a single run instead of
+// competing for the rate limit of Jira. The zero value is ready to use.
+type queryFlight struct {
+	mu    sync.Mutex
+	calls map[string]*queryCall
+	// waiting, if set, is called whenever a caller starts waiting for a run, so
+	// that tests can tell when queries joined one.
+	waiting func()
+}
+
+// querySignature identifies a query by everything but where its time range
+// ends, which covers compares.
+func querySignature(query backend.DataQuery) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%d\x00%d\x00", query.TimeRange.Duration(), query.MaxDataPoints, query.Interval)
+	h.Write(query.JSON)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// do returns the response of run for query, or of the identical query that is
+// already running. The run goes on as long as anyone waits for it: a caller
+// whose ctx is done stops waiting with an error response, and the run is
+// cancelled when the last caller is gone. Every caller gets its own copy of the
+// frames, the ones that joined a run marked with "sharedQuery" in their custom
+// meta.
+func (f *queryFlight) do(ctx context.Context, query backend.DataQuery, run func(context.Context) backend.DataResponse) backend.DataResponse {
+	key := querySignature(query)
+	now := time.Now()
+
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = map[string]*queryCall{}
+	}
+	call, shared := f.calls[key]
+	if shared && !call.covers(query.TimeRange, now) {
+		// The same query over another range, e.g. a dashboard zoomed out.
+		f.mu.Unlock()
+		return run(ctx)
+	}
+	if !shared {
+		// The run keeps the values of ctx, e.g. the trace and the search
+		// deadline, but is only cancelled once nobody waits for it anymore.
+		runCtx := context.WithoutCancel(ctx)
+		var cancel context.CancelFunc
+		if deadline, ok := ctx.Deadline(); ok {
+			runCtx, cancel = context.WithDeadline(runCtx, deadline)
+		} else {
+			runCtx, cancel = context.WithCancel(runCtx)
+		}
+		call = &queryCall{done: make(chan struct{}), started: now, to: query.TimeRange.To, cancel: cancel}
+		f.calls[key] = call
+		go func() {
+			call.res = run(runCtx)
+			f.forget(key, call)
+			cancel()
+			close(call.done)
+		}()
+	}
+	call.waiters++
+	f.mu.Unlock()
+	if f.waiting != nil {
+		f.waiting()
+	}
+
+	select {
+	case <-call.done:
+		res := copyResponse(call.res)
+		if shared {
+			for _, frame := range res.Frames {
+				setCustomMeta(frame, "sharedQuery", true)
+			}
+		}
+		return res
+	case <-ctx.Done():
+		f.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			delete(f.calls, key)
+			call.cancel()
+		}
+		f.mu.Unlock()
+		status := backend.StatusInternal
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			status = backend.StatusTimeout
+		}
+		return backend.ErrDataResponse(status, fmt.Sprintf("query cancelled: %v", ctx.Err()))
+	}
+}
+
+// forget removes call from the calls in flight, unless it was replaced already.
+func (f *queryFlight) forget(key string, call *queryCall) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.calls[key] == call {
+		delete(f.calls, key)
+	}
+}
+
+// copyResponse copies res down to the frame meta, which QueryData adds to per
+// caller. The fields are shared, they are not modified after the query.
+func copyResponse(res backend.DataResponse) backend.DataResponse {
+	frames := make(data.Frames, len(res.Frames))
+	for i, frame := range res.Frames {
+		copied := *frame
+		if frame.Meta != nil {
+			meta := *frame.Meta
+			meta.Notices = append([]data.Notice(nil), frame.Meta.Notices...)
+			if custom, ok := frame.Meta.Custom.(map[string]interface{}); ok {
+				copiedCustom := make(map[string]interface{}, len(custom))
+				for key, value := range custom {
+					copiedCustom[key] = value
+				}
+				meta.Custom = copiedCustom
+			}
+			copied.Meta = &meta
+		}
+		copied.Fields = append([]*data.Field(nil), frame.Fields...)
+		frames[i] = &copied
+	}
+	res.Frames = frames
+	return res
+}
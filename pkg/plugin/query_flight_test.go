@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestQueryFlight(t *testing.T) {
+	var searches atomic.Int32
+	arrived := make(chan struct{}, 10)
+	release := make(chan struct{})
+	cancelled := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		searches.Add(1)
+		var req jira.JQLSearchRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		arrived <- struct{}{}
+		// Searches for B only end when they are cancelled.
+		block := release
+		if strings.Contains(req.JQL, "project = B") {
+			block = nil
+		}
+		select {
+		case <-block:
+			fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}}]}`)
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		}
+	}))
+	defer server.Close()
+
+	ds := &Datasource{}
+	waiting := make(chan struct{}, 10)
+	ds.queries.waiting = func() { waiting <- struct{}{} }
+	settings := backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"url":"` + server.URL + `", "username":"user"}`),
+		DecryptedSecureJSONData: map[string]string{"token": "token"},
+	}
+	run := func(ctx context.Context, query string, timeRange backend.TimeRange) <-chan backend.DataResponse {
+		result := make(chan backend.DataResponse, 1)
+		go func() {
+			resp, err := ds.QueryData(ctx, &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{DataSourceInstanceSettings: &settings},
+				Queries:       []backend.DataQuery{{RefID: "A", JSON: []byte(query), TimeRange: timeRange}},
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			result <- resp.Responses["A"]
+		}()
+		return result
+	}
+	waitForWaiters := func(n int) {
+		for i := 0; i < n; i++ {
+			<-waiting
+		}
+	}
+
+	query := `{"metric":"jql","jqlQuery":"project = A"}`
+	timeRange := testTimeRange()
+	first := run(context.Background(), query, timeRange)
+	<-arrived
+
+	// The auto-refresh of the same relative range, which moved on meanwhile,
+	// and a refresh that is abandoned while waiting.
+	moved := backend.TimeRange{From: timeRange.From.Add(time.Millisecond), To: timeRange.To.Add(time.Millisecond)}
+	second := run(context.Background(), query, moved)
+	ctx, cancel := context.WithCancel(context.Background())
+	third := run(ctx, query, timeRange)
+	waitForWaiters(3)
+	cancel()
+	if res := <-third; res.Error == nil {
+		t.Error("expected the cancelled waiter to get an error")
+	}
+
+	close(release)
+	for i, res := range []backend.DataResponse{<-first, <-second} {
+		if res.Error != nil {
+			t.Fatalf("query %d: %v", i+1, res.Error)
+		}
+		custom, _ := res.Frames[0].Meta.Custom.(map[string]interface{})
+		if shared := custom["sharedQuery"] == true; shared != (i == 1) || res.Frames[0].Rows() != 1 {
+			t.Errorf("query %d: unexpected rows %d, shared %v", i+1, res.Frames[0].Rows(), shared)
+		}
+	}
+	if n := searches.Load(); n != 1 {
+		t.Errorf("expected the queries to share a single search, got %d", n)
+	}
+
+	// The search is cancelled once nobody waits for it anymore.
+	ctx, cancel = context.WithCancel(context.Background())
+	abandoned := run(ctx, `{"metric":"jql","jqlQuery":"project = B"}`, timeRange)
+	<-arrived
+	cancel()
+	<-abandoned
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the abandoned search to be cancelled")
+	}
+}
@@ -0,0 +1,59 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+// newResourceMux registers the resource routes the frontend uses for template
+// variables and editor helpers.
+func (d *Datasource) newResourceMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/field-values", d.handleFieldValues)
+	mux.HandleFunc("/projects", d.handleProjects)
+	mux.HandleFunc("/users", d.handleUsers)
+	mux.HandleFunc("/health-details", d.handleHealthDetails)
+	mux.HandleFunc("/version", d.handleVersion)
+	return mux
+}
+
+// clientFromRequest builds a Jira client from the datasource settings of a resource call.
+func (d *Datasource) clientFromRequest(r *http.Request) (*jira.Client, *models.PluginSettings, error) {
+	config, pluginContext, err := settingsFromRequest(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d.cachedClient(config, pluginContext), config, nil
+}
+
+// settingsFromRequest loads the datasource settings of a resource call.
+func settingsFromRequest(r *http.Request) (*models.PluginSettings, backend.PluginContext, error) {
+	pluginContext := backend.PluginConfigFromContext(r.Context())
+	if pluginContext.DataSourceInstanceSettings == nil {
+		return nil, pluginContext, fmt.Errorf("missing datasource settings")
+	}
+
+	config, err := models.LoadPluginSettings(*pluginContext.DataSourceInstanceSettings)
+	if err != nil {
+		return nil, pluginContext, fmt.Errorf("failed to load settings: %w", err)
+	}
+	return config, pluginContext, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.DefaultLogger.Error("failed to write resource response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
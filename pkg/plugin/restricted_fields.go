@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// requestedFields returns the issue fields the search of the query requests that
+// every issue has, and that are therefore only missing when they are hidden.
+// The key is no field, and custom fields are missing from issues they don't
+// apply to.
+func requestedFields(qm queryModel) []string {
+	var fields []string
+	for _, field := range append(append([]string{}, jira.DefaultFields...), searchOptions(qm).Fields...) {
+		if field != "key" && !strings.HasPrefix(field, "customfield_") && !containsString(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// restrictedFields counts, per field, the issues missing a requested field. Jira
+// returns the requested fields of an issue, null if they are empty, but leaves
+// out those hidden from the user, e.g. by issue security. Issues without any
+// fields aren't counted, they weren't searched with fields. Frames have nulls
+// for the missing fields, like for empty ones.
+func restrictedFields(issues []jira.Issue, fields []string) map[string]int {
+	counts := map[string]int{}
+	for _, issue := range issues {
+		if len(issue.Fields) == 0 {
+			continue
+		}
+		for _, field := range fields {
+			if _, ok := issue.Fields[field]; !ok {
+				counts[field]++
+			}
+		}
+	}
+	return counts
+}
+
+// setRestrictedFields records on every frame of res how many field values of
+// the issues were hidden, under the "restrictedFields" key of the custom frame
+// meta, with an info notice naming the fields if there were any, so that
+// dashboard owners can tell masked data from missing data.
+func setRestrictedFields(res *backend.DataResponse, counts map[string]int) {
+	total := 0
+	var fields []string
+	for field, count := range counts {
+		total += count
+		fields = append(fields, field)
+	}
+	for _, frame := range res.Frames {
+		setCustomMeta(frame, "restrictedFields", total)
+	}
+	if total == 0 {
+		return
+	}
+	sort.Strings(fields)
+	texts := make([]string, len(fields))
+	for i, field := range fields {
+		texts[i] = fmt.Sprintf("%s (%d)", field, counts[field])
+	}
+	appendNotice(res, data.Notice{
+		Severity: data.NoticeSeverityInfo,
+		Text:     fmt.Sprintf("%d field values are hidden from the Jira user, e.g. by issue security, and are null: %s.", total, strings.Join(texts, ", ")),
+	})
+}
@@ -0,0 +1,98 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestRestrictedFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A-2 has its summary and status hidden by issue security, A-3 just has
+		// no summary.
+		fmt.Fprint(w, `{"issues":[
+			{"key":"A-1","fields":{"summary":"Visible","status":{"name":"Done"},"issuetype":{"name":"Story"},"project":{"key":"A"},"created":"2024-01-02T10:00:00.000+0000","watches":{"watchCount":1},"votes":{"votes":0}}},
+			{"key":"A-2","fields":{"issuetype":{"name":"Story"},"project":{"key":"A"},"created":"2024-01-02T10:00:00.000+0000","watches":{"watchCount":1},"votes":{"votes":0}}},
+			{"key":"A-3","fields":{"summary":null,"status":{"name":"To Do"},"issuetype":{"name":"Story"},"project":{"key":"A"},"created":"2024-01-02T10:00:00.000+0000","watches":{"watchCount":1},"votes":{"votes":0}}}
+		],"isLast":true}`)
+	}))
+	defer server.Close()
+
+	query := backend.DataQuery{
+		RefID:     "A",
+		JSON:      []byte(`{"metric":"jql","jqlQuery":"project = A"}`),
+		TimeRange: testTimeRange(),
+	}
+	config := &models.PluginSettings{Secrets: &models.SecretPluginSettings{}}
+	res := (&Datasource{}).query(context.Background(), jira.NewClient(server.URL, "user", "token", ""), config, query)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if got := frame.Meta.Custom.(map[string]interface{})["restrictedFields"]; got != 2 {
+		t.Errorf("expected 2 restricted fields in the meta, got %v", got)
+	}
+	summary, _ := frame.FieldByName("Summary")
+	status, _ := frame.FieldByName("Status")
+	for i, want := range []string{"Visible", "", ""} {
+		if got, ok := summary.ConcreteAt(i); ok != (want != "") || ok && got != want {
+			t.Errorf("row %d: expected summary %q, got %v", i, want, got)
+		}
+	}
+	if _, ok := status.ConcreteAt(1); ok {
+		t.Error("expected the hidden status to be null")
+	}
+	var notices []string
+	for _, notice := range frame.Meta.Notices {
+		notices = append(notices, notice.Text)
+	}
+	if want := "2 field values are hidden from the Jira user, e.g. by issue security, and are null: status (1), summary (1)."; !containsString(notices, want) {
+		t.Errorf("expected the notice %q, got %v", want, notices)
+	}
+}
+
+func TestPartialIssues(t *testing.T) {
+	// Issues with only some of their fields, or none, must not break any metric.
+	partial := newTestIssue("A-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+	)
+	partial.Fields = map[string]interface{}{"status": map[string]interface{}{"name": "Done"}}
+	empty := newTestIssue("A-2", "Story", [3]string{"2024-01-03T10:00:00.000+0000", "To Do", "Done"})
+	empty.Fields = nil
+	issues := []jira.Issue{partial, empty, {Key: "A-3"}}
+
+	client := jira.NewClient("http://127.0.0.1:0", "user", "token", "")
+	for _, metric := range metricNames {
+		t.Run(metric, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("panicked: %v", r)
+				}
+			}()
+			qm := queryModel{Metric: metric, Interval: "1d", StartStatus: "In Progress", EndStatus: "Done", GroupBy: groupByLabels, IncludeSummary: true}
+			(&Datasource{}).buildFrames(context.Background(), client, &models.PluginSettings{}, qm, testTimeRange(), issues)
+		})
+	}
+
+	if counts := restrictedFields(issues, requestedFields(queryModel{Metric: "jql"})); strings.Join(sortedKeys(counts), ",") != "created,issuetype,project,summary,votes,watches" {
+		t.Errorf("expected the fields missing from the partial issue, got %v", counts)
+	}
+}
+
+func sortedKeys(m map[string]int) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
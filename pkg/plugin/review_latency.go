@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// userID identifies a user by account id on Cloud and by key or name on Server
+// and Data Center, "" for nobody.
+func userID(user *jira.User) string {
+	if user == nil {
+		return ""
+	}
+	if user.AccountID != "" {
+		return user.AccountID
+	}
+	if user.Key != "" {
+		return user.Key
+	}
+	return user.Name
+}
+
+// currentAssignee returns the id of the issue's assignee, "" when unassigned.
+func currentAssignee(issue jira.Issue) string {
+	a, ok := issue.Fields["assignee"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	user := &jira.User{}
+	user.AccountID, _ = a["accountId"].(string)
+	user.Key, _ = a["key"].(string)
+	user.Name, _ = a["name"].(string)
+	return userID(user)
+}
+
+// getReviewLatencyData measures, per issue, the hours between its first entry
+// into a review status (qm.ReviewStatus) in the time range and the next status
+// change made by someone other than the assignee, as a proxy for how long work
+// waits for a reviewer. Changes without an author don't count. Issues still
+// waiting are listed with null latency and left out of the "summary" frame.
+func (d *Datasource) getReviewLatencyData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	reviewStatuses := parseList(qm.ReviewStatus)
+	if len(reviewStatuses) == 0 {
+		return backend.ErrDataResponse(backend.StatusBadRequest, "reviewLatency needs a reviewStatus")
+	}
+	if containsString(reviewStatuses, allValues) {
+		return backend.ErrDataResponse(backend.StatusBadRequest, "reviewLatency needs the review statuses listed, not "+allValues)
+	}
+
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Project", nil, []*string{}),
+		data.NewField("EnteredAt", nil, []time.Time{}),
+		data.NewField("ReviewedAt", nil, []*time.Time{}),
+		data.NewField("Reviewer", nil, []*string{}),
+		data.NewField("LatencyHours", nil, []*float64{}),
+	)
+
+	var latencies []float64
+	for _, issue := range issues {
+		assignee := currentAssignee(issue)
+		changes := statusChanges(issue)
+
+		entered := -1
+		for i, c := range changes {
+			if containsString(reviewStatuses, c.to) && !containsString(reviewStatuses, c.from) && !c.at.Before(timeRange.From) && !c.at.After(timeRange.To) {
+				entered = i
+				break
+			}
+		}
+		if entered < 0 {
+			continue
+		}
+
+		enteredAt := changes[entered].at
+		var reviewedAt *time.Time
+		var reviewer *string
+		var latency *float64
+		for _, c := range changes[entered+1:] {
+			author := userID(c.author)
+			if author == "" || author == assignee {
+				continue
+			}
+			at := c.at
+			hours := at.Sub(enteredAt).Hours()
+			reviewedAt, reviewer, latency = &at, optionalString(qm.userName(c.author.DisplayName)), &hours
+			latencies = append(latencies, hours)
+			break
+		}
+
+		frame.AppendRow(
+			issue.Key,
+			optionalString(issueTypeName(issue)),
+			optionalString(projectKey(issue)),
+			enteredAt,
+			reviewedAt,
+			reviewer,
+			latency,
+		)
+	}
+
+	if err := sortFrame(frame, qm, "EnteredAt", sortDesc, "IssueKey"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	response.Frames = append(response.Frames, frame, summaryFrame(latencies, qm.QuantileMethod))
+	return response
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestReviewLatency(t *testing.T) {
+	ds := &Datasource{}
+	alice := &jira.User{AccountID: "a-1", DisplayName: "Alice"}
+	bob := &jira.User{AccountID: "b-1", DisplayName: "Bob"}
+
+	reviewed := newTestIssue("T-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "In Progress", "Review"},
+		// The assignee moving it around doesn't count as a review.
+		[3]string{"2024-01-02T11:00:00.000+0000", "Review", "In Progress"},
+		[3]string{"2024-01-02T16:00:00.000+0000", "In Progress", "Done"},
+	)
+	reviewed.Changelog.Histories[0].Author = alice
+	reviewed.Changelog.Histories[1].Author = alice
+	reviewed.Changelog.Histories[2].Author = bob
+	reviewed.Fields["assignee"] = map[string]interface{}{"accountId": "a-1"}
+
+	waiting := newTestIssue("T-2", "Story", [3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Review"})
+	waiting.Changelog.Histories[0].Author = alice
+
+	res := ds.getReviewLatencyData([]jira.Issue{reviewed, waiting}, queryModel{ReviewStatus: "Review", SortBy: "IssueKey"}, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("expected 2 issues, got %d", frame.Rows())
+	}
+	latency, _ := frame.FieldByName("LatencyHours")
+	if v, ok := latency.ConcreteAt(0); !ok || v != 6.0 {
+		t.Errorf("expected a review latency of 6 hours, got %v", v)
+	}
+	reviewer, _ := frame.FieldByName("Reviewer")
+	if v, ok := reviewer.ConcreteAt(0); !ok || v != "Bob" {
+		t.Errorf("expected Bob as reviewer, got %v", v)
+	}
+	if _, ok := latency.ConcreteAt(1); ok {
+		t.Error("expected no latency for an issue still waiting")
+	}
+	if count := res.Frames[1].At(0, 0); count != int64(1) {
+		t.Errorf("expected 1 latency in the summary, got %v", count)
+	}
+}
@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// aggregateByProject rolls cycle times up per project.
+const aggregateByProject = "project"
+
+// groupByLabels rolls cycle times up per issue label.
+const groupByLabels = "labels"
+
+// groupByAffectsVersion rolls cycle times up per affects version.
+const groupByAffectsVersion = "affectsVersion"
+
+// noLabel groups the issues without a (matching) label.
+const noLabel = "(none)"
+
+// defaultMinSampleSize is the number of cycles below which a rollup row is
+// flagged as LowSample.
+const defaultMinSampleSize = 5
+
+// projectRollupFrame rolls the cycles up per project, see rollupFrame.
+func projectRollupFrame(cycles []cycle, qm queryModel, timeRange backend.TimeRange) *data.Frame {
+	groups := map[string][]float64{}
+	for _, c := range cycles {
+		project := qm.cycleProject(c)
+		groups[project] = append(groups[project], c.days)
+	}
+	return rollupFrame("rollup", "Project", groups, qm, timeRange)
+}
+
+// labelRollupFrame rolls the cycles up per label. Without qm.LabelAllowlist an
+// issue counts towards each of its labels, so the counts can add up to more
+// than the number of cycles. With an allowlist, an issue counts only towards
+// the first label of the allowlist it has. Issues without a (matching) label
+// are grouped as "(none)".
+func labelRollupFrame(cycles []cycle, qm queryModel, timeRange backend.TimeRange) *data.Frame {
+	allowlist := parseList(qm.LabelAllowlist)
+
+	groups := map[string][]float64{}
+	for _, c := range cycles {
+		labels := issueLabels(c.issue)
+		if len(allowlist) > 0 && !containsString(allowlist, allValues) {
+			var first []string
+			for _, label := range allowlist {
+				if containsString(labels, label) {
+					first = []string{label}
+					break
+				}
+			}
+			labels = first
+		}
+		if len(labels) == 0 {
+			labels = []string{noLabel}
+		}
+		for _, label := range labels {
+			groups[label] = append(groups[label], c.days)
+		}
+	}
+	return rollupFrame("labels", "Label", groups, qm, timeRange)
+}
+
+// versionRollupFrame rolls the cycles up per affects version, e.g. for bug
+// triage. Like labels, an issue counts towards each of its versions, and issues
+// without one are grouped as "(none)".
+func versionRollupFrame(cycles []cycle, qm queryModel, timeRange backend.TimeRange) *data.Frame {
+	groups := map[string][]float64{}
+	for _, c := range cycles {
+		versions := affectsVersions(c.issue)
+		if len(versions) == 0 {
+			versions = []string{noLabel}
+		}
+		for _, version := range versions {
+			groups[version] = append(groups[version], c.days)
+		}
+	}
+	return rollupFrame("versions", "AffectsVersion", groups, qm, timeRange)
+}
+
+// rollupFrame has one row per group with the number of completed cycles, their
+// median and 85th percentile and the throughput in completions per week of the
+// time range. Groups with fewer cycles than qm.MinSampleSize are flagged as
+// LowSample, since their statistics mean little.
+func rollupFrame(name, column string, groups map[string][]float64, qm queryModel, timeRange backend.TimeRange) *data.Frame {
+	minSample := qm.MinSampleSize
+	if minSample <= 0 {
+		minSample = defaultMinSampleSize
+	}
+	weeks := timeRange.Duration().Hours() / (7 * 24)
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	frame := data.NewFrame(name,
+		data.NewField(column, nil, []*string{}),
+		data.NewField("Count", nil, []int64{}),
+		data.NewField("MedianCycle", nil, []float64{}),
+		data.NewField("P85Cycle", nil, []float64{}),
+		data.NewField("Throughput", nil, []*float64{}),
+		data.NewField("LowSample", nil, []bool{}),
+	)
+	for _, key := range keys {
+		days := groups[key]
+		var throughput *float64
+		if weeks > 0 {
+			perWeek := float64(len(days)) / weeks
+			throughput = &perWeek
+		}
+		frame.AppendRow(
+			optionalString(key),
+			int64(len(days)),
+			quantile(days, 50, qm.QuantileMethod),
+			quantile(days, 85, qm.QuantileMethod),
+			throughput,
+			len(days) < minSample,
+		)
+	}
+	return frame
+}
@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestProjectRollup(t *testing.T) {
+	var issues []jira.Issue
+	for i, project := range []string{"API", "API", "WEB"} {
+		issue := newTestIssue(fmt.Sprintf("T-%d", i+1), "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "In Progress", "Done"},
+		)
+		issue.Fields["project"] = map[string]interface{}{"key": project}
+		issues = append(issues, issue)
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", MinSampleSize: 2}
+
+	frame := projectRollupFrame(collectCycles(issues, qm, testTimeRange()), qm, testTimeRange())
+	if frame.Rows() != 2 {
+		t.Fatalf("expected 2 projects, got %d", frame.Rows())
+	}
+	if project, count, median := frame.At(0, 0).(*string), frame.At(1, 0), frame.At(2, 0); *project != "API" || count != int64(2) || median != 3.0 {
+		t.Errorf("expected 2 API cycles with a median of 3 days, got %v with %v", count, median)
+	}
+	if lowSample := []interface{}{frame.At(5, 0), frame.At(5, 1)}; lowSample[0] != false || lowSample[1] != true {
+		t.Errorf("expected only WEB to be flagged as low sample, got %v", lowSample)
+	}
+	if throughput := frame.At(4, 1).(*float64); throughput == nil || *throughput <= 0 {
+		t.Errorf("expected a throughput per week, got %v", throughput)
+	}
+}
+
+func TestLabelRollup(t *testing.T) {
+	var issues []jira.Issue
+	for i, labels := range [][]interface{}{{"team-alpha", "urgent"}, {"urgent", "team-beta"}, {}} {
+		issue := newTestIssue(fmt.Sprintf("T-%d", i+1), "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "In Progress", "Done"},
+		)
+		issue.Fields["labels"] = labels
+		issues = append(issues, issue)
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done"}
+	cycles := collectCycles(issues, qm, testTimeRange())
+
+	counts := func(frame *data.Frame) map[string]int64 {
+		counts := map[string]int64{}
+		for i := 0; i < frame.Rows(); i++ {
+			counts[*frame.At(0, i).(*string)] = frame.At(1, i).(int64)
+		}
+		return counts
+	}
+
+	// Issues count towards each of their labels.
+	got := counts(labelRollupFrame(cycles, qm, testTimeRange()))
+	want := map[string]int64{"team-alpha": 1, "team-beta": 1, "urgent": 2, noLabel: 1}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// Issues only count towards the first allowlisted label they have.
+	qm.LabelAllowlist = "team-beta, team-alpha"
+	got = counts(labelRollupFrame(cycles, qm, testTimeRange()))
+	want = map[string]int64{"team-alpha": 1, "team-beta": 1, noLabel: 1}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v with an allowlist, got %v", want, got)
+	}
+}
+
+func TestAffectsVersionRollup(t *testing.T) {
+	version := func(name string) map[string]interface{} { return map[string]interface{}{"id": "1", "name": name} }
+	var issues []jira.Issue
+	for i, versions := range [][]interface{}{{version("1.0"), version("1.1")}, {version("1.1")}, nil} {
+		issue := newTestIssue(fmt.Sprintf("T-%d", i+1), "Bug",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "In Progress", "Done"},
+		)
+		if versions != nil {
+			issue.Fields["versions"] = versions
+		}
+		issue.Fields["environment"] = "Firefox on Windows"
+		issues = append(issues, issue)
+	}
+	qm := queryModel{Metric: "cycletime", StartStatus: "In Progress", EndStatus: "Done", GroupBy: groupByAffectsVersion}
+	if err := qm.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if opts := searchOptions(qm); !containsString(opts.Fields, "versions") {
+		t.Errorf("expected the versions to be fetched, got %v", opts.Fields)
+	}
+
+	// Issues count towards each of their versions.
+	frame := versionRollupFrame(collectCycles(issues, qm, testTimeRange()), qm, testTimeRange())
+	got := map[string]int64{}
+	for i := 0; i < frame.Rows(); i++ {
+		got[*frame.At(0, i).(*string)] = frame.At(1, i).(int64)
+	}
+	want := map[string]int64{"1.0": 1, "1.1": 2, noLabel: 1}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	// The jql table joins the versions, missing ones are null.
+	issues[1].RenderedFields = map[string]interface{}{"environment": "<p>Safari &amp; iOS</p>"}
+	table := (&Datasource{}).getJQLData(issues, queryModel{Metric: "jql", IncludeBugFields: true}, nil).Frames[0]
+	versions, _ := table.FieldByName("AffectsVersions")
+	environments, _ := table.FieldByName("Environment")
+	if versions == nil || environments == nil {
+		t.Fatal("expected AffectsVersions and Environment columns")
+	}
+	wantVersions := []string{"1.0, 1.1", "1.1", ""}
+	wantEnvironments := []string{"Firefox on Windows", "Safari & iOS", "Firefox on Windows"}
+	for i := 0; i < table.Rows(); i++ {
+		var gotVersion string
+		if v := versions.At(i).(*string); v != nil {
+			gotVersion = *v
+		}
+		if gotVersion != wantVersions[i] || *environments.At(i).(*string) != wantEnvironments[i] {
+			t.Errorf("%s: unexpected versions %q, environment %q", table.Fields[0].At(i), gotVersion, *environments.At(i).(*string))
+		}
+	}
+}
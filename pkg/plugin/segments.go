@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// segment is a measured part of the workflow, e.g. the queue time from Ready to
+// In Progress. Statuses are comma-separated like startStatus and endStatus.
+type segment struct {
+	Name          string `json:"name"`
+	StartStatuses string `json:"startStatuses"`
+	EndStatuses   string `json:"endStatuses"`
+}
+
+// segmentColumns are the columns of the segments frame next to the segments,
+// including the URL column of includeURL, which segments can't be named like.
+var segmentColumns = []string{"IssueKey", "IssueType", "Project", "URL"}
+
+// validateSegments rejects segments without a name, with a duplicate name or the
+// name of another column, without statuses, and values containing line breaks.
+func validateSegments(segments []segment) error {
+	names := map[string]bool{}
+	for i, s := range segments {
+		if strings.TrimSpace(s.Name) == "" {
+			return fmt.Errorf("segment %d needs a name", i+1)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicate segment name: %s", s.Name)
+		}
+		if containsFold(segmentColumns, s.Name) {
+			return fmt.Errorf("segment name %s is taken by a column of the segments frame", s.Name)
+		}
+		names[s.Name] = true
+		if len(parseList(s.StartStatuses)) == 0 || len(parseList(s.EndStatuses)) == 0 {
+			return fmt.Errorf("segment %s needs start and end statuses", s.Name)
+		}
+		if strings.ContainsAny(s.Name+s.StartStatuses+s.EndStatuses, "\r\n") {
+			return fmt.Errorf("segment %s must not contain line breaks", s.Name)
+		}
+		if err := validateStatusSet("segment "+s.Name+" startStatuses", s.StartStatuses); err != nil {
+			return err
+		}
+		if err := validateStatusSet("segment "+s.Name+" endStatuses", s.EndStatuses); err != nil {
+			return err
+		}
+		if err := validateCycleStatuses("segment "+s.Name+" startStatuses and endStatuses", s.StartStatuses, s.EndStatuses); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSegmentOptions rejects the cycletime options that segments don't
+// support together with segments, rather than silently ignoring them. The
+// summary frame of segments has their count, median and quantile already, and
+// segments are measured with findCycle, by their own statuses only.
+func validateSegmentOptions(qm queryModel) error {
+	if qm.Metric != "cycletime" || len(qm.Segments) == 0 {
+		return nil
+	}
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"includeSummary", qm.IncludeSummary},
+		{"targetDays", qm.TargetDays != nil},
+		{"aggregateBy", qm.AggregateBy != ""},
+		{"groupBy", qm.GroupBy != ""},
+		{"format timeseries", qm.Format == formatTimeSeries},
+		{"endCondition", qm.EndCondition != ""},
+		{"statusMappings", len(qm.StatusMappings) > 0},
+		{"minCycleDays", qm.MinCycleDays != 0},
+		{"maxCycleDays", qm.MaxCycleDays != 0},
+		{"outlierFilter", qm.OutlierFilter != ""},
+		{"listExcluded", qm.ListExcluded},
+		{"hideQuantile", qm.HideQuantile},
+	} {
+		if option.set {
+			return fmt.Errorf("segments can't be combined with %s", option.name)
+		}
+	}
+	return nil
+}
+
+// getSegmentsData measures every segment of the query per issue, using the
+// earliest transition into a start status and the latest into an end status
+// within the time range like cycletime. Issues get one row with a column per
+// segment, null where the issue didn't pass through the segment; issues without
+// any segment are left out. A "summary" frame has the count, median and the
+// configured quantile of every segment.
+func (d *Datasource) getSegmentsData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	segments := qm.Segments
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Project", nil, []*string{}),
+	)
+	for _, s := range segments {
+		frame.Fields = append(frame.Fields, data.NewField(s.Name, nil, []*float64{}))
+	}
+
+	perSegment := make([][]float64, len(segments))
+	for _, issue := range issues {
+		row := []interface{}{issue.Key, optionalString(issueTypeName(issue)), optionalString(projectKey(issue))}
+		found := false
+		for i, s := range segments {
+			c, ok := findCycle(issue, qm.statusSet(s.StartStatuses), qm.statusSet(s.EndStatuses), timeRange)
+			if !ok {
+				row = append(row, nil)
+				continue
+			}
+			if qm.AgeUnit != "" {
+				c.days = qm.age(c.start, c.end)
+			}
+			days := c.days
+			row = append(row, &days)
+			perSegment[i] = append(perSegment[i], days)
+			found = true
+		}
+		if found {
+			frame.AppendRow(row...)
+		}
+	}
+
+	if err := sortFrame(frame, qm, "", "", "IssueKey"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	summary := data.NewFrame("summary",
+		data.NewField("Segment", nil, []string{}),
+		data.NewField("Count", nil, []int64{}),
+		data.NewField("Median", nil, []*float64{}),
+		data.NewField("Quantile", nil, []*float64{}),
+	)
+	for i, s := range segments {
+		var median, q *float64
+		if days := perSegment[i]; len(days) > 0 {
+			m, v := quantile(days, 50, qm.QuantileMethod), quantile(days, qm.Quantile, qm.QuantileMethod)
+			median, q = &m, &v
+		}
+		summary.AppendRow(s.Name, int64(len(perSegment[i])), median, q)
+	}
+
+	response.Frames = append(response.Frames, frame, summary)
+	return response
+}
@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestSegments(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "Ready"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "Ready", "In Progress"},
+			[3]string{"2024-01-10T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		// Skipped the queue.
+		newTestIssue("T-2", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		newTestIssue("T-3", "Story", [3]string{"2024-01-02T10:00:00.000+0000", "To Do", "Ready"}),
+	}
+	qm := queryModel{Quantile: 50, SortBy: "IssueKey", Segments: []segment{
+		{Name: "Queue", StartStatuses: "Ready", EndStatuses: "In Progress"},
+		{Name: "Touch", StartStatuses: "In Progress", EndStatuses: "Done"},
+	}}
+
+	res := ds.getSegmentsData(issues, qm, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != 2 {
+		t.Fatalf("expected 2 issues with segments, got %d", frame.Rows())
+	}
+	queue, _ := frame.FieldByName("Queue")
+	touch, _ := frame.FieldByName("Touch")
+	if v, ok := queue.ConcreteAt(0); !ok || v != 3.0 {
+		t.Errorf("expected a queue time of 3 days, got %v", v)
+	}
+	if _, ok := queue.ConcreteAt(1); ok {
+		t.Error("expected no queue time for an issue that skipped the queue")
+	}
+	if v, ok := touch.ConcreteAt(1); !ok || v != 2.0 {
+		t.Errorf("expected a touch time of 2 days, got %v", v)
+	}
+
+	summary := res.Frames[1]
+	if name, count := summary.At(0, 1), summary.At(1, 1); name != "Touch" || count != int64(2) {
+		t.Errorf("expected 2 touch times, got %v for %v", count, name)
+	}
+
+	if err := validateSegments([]segment{{Name: "Queue", StartStatuses: "Ready", EndStatuses: "Done"}, {Name: "Queue", StartStatuses: "A", EndStatuses: "B"}}); err == nil {
+		t.Error("expected duplicate segment names to be rejected")
+	}
+	if err := validateSegments([]segment{{Name: "Project", StartStatuses: "Ready", EndStatuses: "Done"}}); err == nil {
+		t.Error("expected a segment named like a fixed column to be rejected")
+	}
+	days := 5.0
+	for _, qm := range []queryModel{
+		{IncludeSummary: true},
+		{TargetDays: &days},
+		{AggregateBy: aggregateByProject},
+		{GroupBy: groupByLabels},
+		{Format: formatTimeSeries},
+		{EndCondition: endOnResolution},
+		{StatusMappings: map[string]statusMapping{"PLAT": {Start: []string{"Ready"}, End: []string{"Done"}}}},
+		{MinCycleDays: 1},
+		{MaxCycleDays: 30},
+		{OutlierFilter: outlierFilterIQR},
+		{ListExcluded: true},
+		{HideQuantile: true},
+	} {
+		qm.Metric, qm.Segments = "cycletime", []segment{{Name: "Queue", StartStatuses: "Ready", EndStatuses: "In Progress"}}
+		if err := qm.validate(); err == nil {
+			t.Errorf("expected segments to be rejected with %+v", qm)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// defaultMaxSplits caps the parts of a split search when the datasource doesn't.
+const defaultMaxSplits = 10
+
+// splitBoundaries decides whether the search for jql is split because Jira
+// estimates more than the split threshold of issues for it, and returns where
+// the parts of the time range start after the first. The parts are about
+// equally long and start at full minutes, the precision of JQL dates. Searches
+// that aren't limited by "updated >= from" alone, like wip, or not by the time
+// range at all, like weightedCount, are never split.
+func splitBoundaries(ctx context.Context, client *jira.Client, qm queryModel, timeRange backend.TimeRange, jql string) []time.Time {
+	if qm.splitThreshold <= 0 || qm.JQLQuery == "" || !filtersByTimeRange(qm) || qm.Metric == "wip" || qm.Metric == "agingWip" {
+		return nil
+	}
+	count, err := client.CountIssues(ctx, jql)
+	if err != nil {
+		log.DefaultLogger.Debug("not splitting the search, the issue count failed", "error", err)
+		return nil
+	}
+	if count <= qm.splitThreshold {
+		return nil
+	}
+
+	maxSplits := qm.maxSplits
+	if maxSplits <= 0 {
+		maxSplits = defaultMaxSplits
+	}
+	parts := int(math.Min(math.Ceil(float64(count)/float64(qm.splitThreshold)), float64(maxSplits)))
+
+	from := timeRange.From.In(qm.loc()).Truncate(time.Minute)
+	step := timeRange.To.Sub(from) / time.Duration(parts)
+	var boundaries []time.Time
+	for i := 1; i < parts; i++ {
+		b := from.Add(time.Duration(i) * step).Truncate(time.Minute)
+		if b.After(from) && (len(boundaries) == 0 || b.After(boundaries[len(boundaries)-1])) {
+			boundaries = append(boundaries, b)
+		}
+	}
+	log.DefaultLogger.Debug("splitting the search", "estimatedIssues", count, "parts", len(boundaries)+1)
+	return boundaries
+}
+
+// searchSplit searches the parts of the time range one after the other: from
+// the start of the time range to the first boundary, between the boundaries,
+// and from the last boundary on without an end like a single search. Issues
+// found in several parts, because they were updated meanwhile, are kept once
+// in their latest version. A part that is cut short ends the search with the
+// issues so far and its *jira.PaginationError, as does reaching the search
+// deadline between parts.
+func searchSplit(ctx context.Context, client *jira.Client, qm queryModel, timeRange backend.TimeRange, boundaries []time.Time) ([]jira.Issue, []string, error) {
+	starts := append([]time.Time{timeRange.From}, boundaries...)
+
+	var issues []jira.Issue
+	var warnings []string
+	index := map[string]int{}
+	for i, start := range starts {
+		if i > 0 && jira.SearchDeadlineReached(ctx) {
+			return issues, warnings, &jira.PaginationError{Deadline: true}
+		}
+		clause := fmt.Sprintf("updated >= %s", jira.QuoteJQL(start.In(qm.loc()).Format(jqlTimeLayout)))
+		if i+1 < len(starts) {
+			clause += fmt.Sprintf(" AND updated < %s", jira.QuoteJQL(starts[i+1].In(qm.loc()).Format(jqlTimeLayout)))
+		}
+		for _, created := range createdClauses(qm) {
+			clause += " AND " + created
+		}
+		jql, _ := addFilter(qm.JQLQuery, clause, "")
+
+		part, partWarnings, err := client.SearchChangelogs(ctx, jql, searchOptions(qm))
+		for _, issue := range part {
+			if j, ok := index[issue.Key]; ok {
+				issues[j] = issue
+				continue
+			}
+			index[issue.Key] = len(issues)
+			issues = append(issues, issue)
+		}
+		for _, w := range partWarnings {
+			if !containsString(warnings, w) {
+				warnings = append(warnings, w)
+			}
+		}
+		log.DefaultLogger.Debug("split search progress", "part", i+1, "parts", len(starts), "issues", len(issues))
+		if err != nil {
+			return issues, warnings, err
+		}
+	}
+	return issues, warnings, nil
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestSplitSearch(t *testing.T) {
+	var searched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			JQL string `json:"jql"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		switch r.URL.Path {
+		case "/rest/api/3/search/approximate-count":
+			fmt.Fprint(w, `{"count":250}`)
+		case "/rest/api/3/search/jql":
+			searched = append(searched, body.JQL)
+			// A-1 turns up in every part, as if it was updated during the search.
+			fmt.Fprintf(w, `{"issues":[{"key":"A-1","fields":{"summary":"part %d"}},{"key":"B-%d","fields":{}}]}`, len(searched), len(searched))
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := jira.NewClient(server.URL, "user", "token", "")
+	qm := queryModel{Metric: "cycletime", JQLQuery: "project = A ORDER BY created", splitThreshold: 100}
+	issues, _, _, err := (&Datasource{}).searchIssues(context.Background(), client, qm, testTimeRange())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		`(project = A) AND updated >= '2024-01-01 00:00' AND updated < '2024-01-11 08:00' ORDER BY created`,
+		`(project = A) AND updated >= '2024-01-11 08:00' AND updated < '2024-01-21 16:00' ORDER BY created`,
+		`(project = A) AND updated >= '2024-01-21 16:00' ORDER BY created`,
+	}
+	if strings.Join(searched, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected the searches\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(searched, "\n"))
+	}
+
+	var keys []string
+	for _, issue := range issues {
+		keys = append(keys, issue.Key)
+	}
+	if strings.Join(keys, ",") != "A-1,B-1,B-2,B-3" {
+		t.Errorf("expected each issue once, got %v", keys)
+	}
+	if issues[0].Fields["summary"] != "part 3" {
+		t.Errorf("expected the latest version of A-1, got %v", issues[0].Fields["summary"])
+	}
+
+	// Below the threshold, the search isn't split.
+	searched = nil
+	qm.splitThreshold = 1000
+	if _, _, _, err := (&Datasource{}).searchIssues(context.Background(), client, qm, testTimeRange()); err != nil {
+		t.Fatal(err)
+	}
+	if len(searched) != 1 {
+		t.Errorf("expected a single search, got %d", len(searched))
+	}
+
+	// weightedCount doesn't filter by the time range, so splitting by it would
+	// lose the issues that didn't change in it.
+	searched = nil
+	qm.Metric, qm.splitThreshold = "weightedCount", 100
+	if _, _, _, err := (&Datasource{}).searchIssues(context.Background(), client, qm, testTimeRange()); err != nil {
+		t.Fatal(err)
+	}
+	if len(searched) != 1 || strings.Contains(searched[0], "updated") {
+		t.Errorf("expected a single search without the time range, got %v", searched)
+	}
+}
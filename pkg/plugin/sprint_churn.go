@@ -0,0 +1,326 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// sprintField is the changelog field of sprint membership changes. Its from and
+// to values are the comma-separated ids of the sprints, the strings their names.
+const sprintField = "Sprint"
+
+// Scopes of sprintChurn rows.
+const (
+	scopeCommitted = "committed"
+	scopeAdded     = "added"
+)
+
+// sprintChange is a change of the sprints an issue belongs to.
+type sprintChange struct {
+	at   time.Time
+	from []string
+	to   []string
+}
+
+// sprintChanges returns the sprint membership changes of the issue in
+// chronological order.
+func sprintChanges(issue jira.Issue) []sprintChange {
+	if issue.Changelog == nil {
+		return nil
+	}
+
+	var changes []sprintChange
+	for _, history := range issue.Changelog.Histories {
+		createdTime, err := parseJiraTime(history.Created)
+		if err != nil {
+			continue
+		}
+		for _, item := range history.Items {
+			if item.Field == sprintField {
+				changes = append(changes, sprintChange{at: createdTime, from: splitSprints(item.From), to: splitSprints(item.To)})
+			}
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].at.Before(changes[j].at) })
+	return changes
+}
+
+// splitSprints splits the comma-separated sprint ids or names of a sprint
+// change.
+func splitSprints(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	values := strings.Split(raw, ",")
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+// resolveSprintID returns the id of the sprint given by id or name. Names are
+// looked up in the sprint changes of the issues, where ids and names are listed
+// in the same order.
+func resolveSprintID(sprint string, issues []jira.Issue) (int, error) {
+	if id, err := strconv.Atoi(sprint); err == nil {
+		return id, nil
+	}
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+		for _, history := range issue.Changelog.Histories {
+			for _, item := range history.Items {
+				if item.Field != sprintField {
+					continue
+				}
+				for _, pair := range [][2]string{{item.From, item.FromString}, {item.To, item.ToString}} {
+					ids, names := splitSprints(pair[0]), splitSprints(pair[1])
+					if len(ids) != len(names) {
+						continue
+					}
+					for i, name := range names {
+						if strings.EqualFold(name, sprint) {
+							if id, err := strconv.Atoi(ids[i]); err == nil {
+								return id, nil
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("sprint %q not found in the change log of the issues, use its id instead", sprint)
+}
+
+// sprintMembership tells when an issue belonged to a sprint.
+type sprintMembership struct {
+	changes []sprintChange
+	sprint  string
+	// initial is whether the issue was in the sprint before its first change.
+	initial bool
+}
+
+// newSprintMembership replays the sprint changes of issue. member is whether the
+// issue is in the sprint now, which decides for issues without sprint changes.
+func newSprintMembership(issue jira.Issue, sprintID int, member bool) sprintMembership {
+	m := sprintMembership{changes: sprintChanges(issue), sprint: strconv.Itoa(sprintID), initial: member}
+	if len(m.changes) > 0 {
+		m.initial = containsString(m.changes[0].from, m.sprint)
+	}
+	return m
+}
+
+// at reports whether the issue was in the sprint at t.
+func (m sprintMembership) at(t time.Time) bool {
+	in := m.initial
+	for _, c := range m.changes {
+		if c.at.After(t) {
+			break
+		}
+		in = containsString(c.to, m.sprint)
+	}
+	return in
+}
+
+// addedAt returns the first time after start the issue was added to the sprint.
+func (m sprintMembership) addedAt(start time.Time) (time.Time, bool) {
+	for _, c := range m.changes {
+		if c.at.After(start) && containsString(c.to, m.sprint) && !containsString(c.from, m.sprint) {
+			return c.at, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// removedAt returns the last time until end the issue was removed from the sprint.
+func (m sprintMembership) removedAt(end time.Time) (time.Time, bool) {
+	var removed time.Time
+	for _, c := range m.changes {
+		if c.at.After(end) {
+			break
+		}
+		if containsString(c.from, m.sprint) && !containsString(c.to, m.sprint) {
+			removed = c.at
+		}
+	}
+	return removed, !removed.IsZero()
+}
+
+// fetchSprint looks up the started sprint given as qm.Sprint (id or name) with
+// the agile API and fetches the issues currently in it. Failed requests to Jira
+// are returned as a *jiraRequestError.
+func fetchSprint(ctx context.Context, client *jira.Client, issues []jira.Issue, qm queryModel) (*jira.Sprint, []jira.Issue, error) {
+	if strings.TrimSpace(qm.Sprint) == "" {
+		return nil, nil, fmt.Errorf("%s needs a sprint", qm.Metric)
+	}
+	sprintID, err := resolveSprintID(strings.TrimSpace(qm.Sprint), issues)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sprint, err := client.GetSprint(ctx, sprintID)
+	if err != nil {
+		return nil, nil, &jiraRequestError{prefix: "jira sprint fetch failed", err: err}
+	}
+	if sprint.StartDate == "" {
+		return nil, nil, fmt.Errorf("sprint %s has not started yet", sprint.Name)
+	}
+
+	members, _, err := client.SearchChangelogs(ctx, fmt.Sprintf("sprint = %d", sprintID), searchOptions(qm))
+	if err != nil {
+		return nil, nil, &jiraRequestError{prefix: "jira sprint search failed", err: err}
+	}
+	return sprint, members, nil
+}
+
+// sprintIssues returns the issues of the JQL and the members of the sprint
+// without duplicates, and which of them are in the sprint now.
+func sprintIssues(issues, members []jira.Issue) ([]jira.Issue, map[string]bool) {
+	isMember := map[string]bool{}
+	for _, issue := range members {
+		isMember[issue.Key] = true
+	}
+	seen := map[string]bool{}
+	var all []jira.Issue
+	for _, list := range [][]jira.Issue{issues, members} {
+		for _, issue := range list {
+			if !seen[issue.Key] {
+				seen[issue.Key] = true
+				all = append(all, issue)
+			}
+		}
+	}
+	return all, isMember
+}
+
+// getSprintChurnData classifies the issues of the sprint given as qm.Sprint (id
+// or name). The sprint's start and completion dates come from the agile API, and
+// the issues currently in the sprint are fetched in addition to those of the JQL,
+// which has to cover the issues that were removed from the sprint.
+func (d *Datasource) getSprintChurnData(ctx context.Context, client *jira.Client, issues []jira.Issue, qm queryModel) backend.DataResponse {
+	sprint, members, err := fetchSprint(ctx, client, issues, qm)
+	if err != nil {
+		return queryErrorResponse(err)
+	}
+	return sprintChurnFrames(issues, members, sprint, qm, time.Now())
+}
+
+// sprintChurnFrames classifies every issue that was in the sprint after it
+// started as committed (in the sprint at its start) or added later, and flags
+// the ones that were removed before it was completed (or now, while it is
+// active). members are the issues currently in the sprint. A "summary" frame
+// has the counts and story points of each class.
+func sprintChurnFrames(issues, members []jira.Issue, sprint *jira.Sprint, qm queryModel, now time.Time) backend.DataResponse {
+	var response backend.DataResponse
+
+	start, err := time.Parse(time.RFC3339, sprint.StartDate)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusInternal, fmt.Sprintf("invalid sprint start date: %s", sprint.StartDate))
+	}
+	end := now
+	if completed, err := time.Parse(time.RFC3339, sprint.CompleteDate); err == nil {
+		end = completed
+	}
+
+	all, isMember := sprintIssues(issues, members)
+
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("Summary", nil, []*string{}),
+		data.NewField("IssueType", nil, []*string{}),
+		data.NewField("Scope", nil, []string{}),
+		data.NewField("AddedAt", nil, []*time.Time{}),
+		data.NewField("Removed", nil, []bool{}),
+		data.NewField("RemovedAt", nil, []*time.Time{}),
+		data.NewField("StoryPoints", nil, []*float64{}),
+	)
+
+	var committed, added, removed int64
+	var committedPoints, addedPoints, removedPoints float64
+	for _, issue := range all {
+		m := newSprintMembership(issue, sprint.ID, isMember[issue.Key])
+
+		createdRaw, _ := issue.Fields["created"].(string)
+		created, err := parseJiraTime(createdRaw)
+		createdLater := err == nil && created.After(start)
+
+		scope := scopeCommitted
+		var addedAt *time.Time
+		if createdLater || !m.at(start) {
+			at, ok := m.addedAt(start)
+			switch {
+			case ok:
+			case createdLater && m.initial:
+				// Created right into the sprint.
+				at = created
+			default:
+				// Never in the sprint while it ran.
+				continue
+			}
+			scope = scopeAdded
+			addedAt = &at
+		}
+
+		var removedAt *time.Time
+		if !m.at(end) {
+			if at, ok := m.removedAt(end); ok {
+				removedAt = &at
+			}
+		}
+
+		var points *float64
+		if sp, ok := issue.Fields[qm.storyPointsField].(float64); ok && qm.storyPointsField != "" {
+			points = &sp
+		}
+		pointValue := 0.0
+		if points != nil {
+			pointValue = *points
+		}
+		if scope == scopeCommitted {
+			committed++
+			committedPoints += pointValue
+		} else {
+			added++
+			addedPoints += pointValue
+		}
+		if removedAt != nil {
+			removed++
+			removedPoints += pointValue
+		}
+
+		summary, _ := issue.Fields["summary"].(string)
+		frame.AppendRow(
+			issue.Key,
+			qm.text(summary),
+			optionalString(issueTypeName(issue)),
+			scope,
+			addedAt,
+			removedAt != nil,
+			removedAt,
+			points,
+		)
+	}
+	setCustomMeta(frame, "sprint", sprint.Name)
+
+	summary := data.NewFrame("summary",
+		data.NewField("Committed", nil, []int64{committed}),
+		data.NewField("Added", nil, []int64{added}),
+		data.NewField("Removed", nil, []int64{removed}),
+		data.NewField("CommittedPoints", nil, []float64{committedPoints}),
+		data.NewField("AddedPoints", nil, []float64{addedPoints}),
+		data.NewField("RemovedPoints", nil, []float64{removedPoints}),
+	)
+
+	response.Frames = append(response.Frames, frame, summary)
+	return response
+}
@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// newTestSprintIssue returns an issue with sprint changes given as
+// (created, from ids, to ids).
+func newTestSprintIssue(key, created string, points float64, changes ...[3]string) jira.Issue {
+	issue := newTestIssue(key, "Story")
+	issue.Fields["created"] = created
+	issue.Fields["customfield_10016"] = points
+	for _, c := range changes {
+		issue.Changelog.Histories = append(issue.Changelog.Histories, jira.History{
+			Created: c[0],
+			Items:   []jira.Item{{Field: sprintField, From: c[1], To: c[2]}},
+		})
+	}
+	return issue
+}
+
+func TestSprintChurn(t *testing.T) {
+	sprint := &jira.Sprint{ID: 7, Name: "Sprint 7", StartDate: "2024-01-08T09:00:00.000Z", CompleteDate: "2024-01-19T17:00:00.000Z"}
+	created := "2024-01-01T10:00:00.000+0000"
+	issues := []jira.Issue{
+		// Planned into the sprint before it started.
+		newTestSprintIssue("T-1", created, 3, [3]string{"2024-01-05T10:00:00.000+0000", "6", "6, 7"}),
+		// Added during the sprint.
+		newTestSprintIssue("T-2", created, 2, [3]string{"2024-01-10T10:00:00.000+0000", "", "7"}),
+		// Committed, then moved out.
+		newTestSprintIssue("T-3", created, 5,
+			[3]string{"2024-01-05T10:00:00.000+0000", "", "7"},
+			[3]string{"2024-01-12T10:00:00.000+0000", "7", "8"},
+		),
+		// Never in the sprint.
+		newTestSprintIssue("T-4", created, 1, [3]string{"2024-01-10T10:00:00.000+0000", "", "8"}),
+	}
+	members := []jira.Issue{
+		issues[0],
+		// Created right into the sprint, so there is no sprint change.
+		newTestSprintIssue("T-5", "2024-01-15T10:00:00.000+0000", 1),
+	}
+
+	res := sprintChurnFrames(issues, members, sprint, queryModel{storyPointsField: "customfield_10016"}, time.Now())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+	want := map[string][2]interface{}{
+		"T-1": {scopeCommitted, false},
+		"T-2": {scopeAdded, false},
+		"T-3": {scopeCommitted, true},
+		"T-5": {scopeAdded, false},
+	}
+	if frame.Rows() != len(want) {
+		t.Fatalf("expected %d issues, got %d", len(want), frame.Rows())
+	}
+	for i := 0; i < frame.Rows(); i++ {
+		key := frame.Fields[0].At(i).(string)
+		if got := [2]interface{}{frame.Fields[3].At(i), frame.Fields[5].At(i)}; got != want[key] {
+			t.Errorf("%s: expected scope/removed %v, got %v", key, want[key], got)
+		}
+	}
+
+	summary := res.Frames[1]
+	if added, points := summary.At(1, 0), summary.At(4, 0); added != int64(2) || points != 3.0 {
+		t.Errorf("expected 2 added issues with 3 points, got %v with %v", added, points)
+	}
+	if removed, points := summary.At(2, 0), summary.At(5, 0); removed != int64(1) || points != 5.0 {
+		t.Errorf("expected 1 removed issue with 5 points, got %v with %v", removed, points)
+	}
+}
+
+func TestResolveSprintID(t *testing.T) {
+	issue := newTestIssue("T-1", "Story")
+	issue.Changelog.Histories = append(issue.Changelog.Histories, jira.History{
+		Created: "2024-01-05T10:00:00.000+0000",
+		Items:   []jira.Item{{Field: sprintField, From: "6", FromString: "Sprint 6", To: "6, 7", ToString: "Sprint 6, Sprint 7"}},
+	})
+
+	if id, err := resolveSprintID("sprint 7", []jira.Issue{issue}); err != nil || id != 7 {
+		t.Errorf("expected sprint 7 to resolve to 7, got %d (%v)", id, err)
+	}
+	if id, err := resolveSprintID("42", nil); err != nil || id != 42 {
+		t.Errorf("expected an id to be used as is, got %d (%v)", id, err)
+	}
+	if _, err := resolveSprintID("Sprint 9", []jira.Issue{issue}); err == nil {
+		t.Error("expected an unknown sprint name to fail")
+	}
+}
+
+func TestFetchSprintErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/agile/1.0/sprint/7":
+			w.Write([]byte(`{"id":7,"name":"Sprint 7"}`))
+		default:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	client := jira.NewClient(server.URL, "user", "token", "")
+
+	tests := map[string]backend.Status{
+		"":         backend.StatusBadRequest, // no sprint
+		"Sprint 9": backend.StatusBadRequest, // unknown name
+		"7":        backend.StatusBadRequest, // not started
+		"8":        backend.StatusInternal,   // failed fetch
+	}
+	for sprint, want := range tests {
+		qm := queryModel{Metric: "sprintChurn", Sprint: sprint}
+		for _, res := range []backend.DataResponse{
+			(&Datasource{}).getSprintChurnData(context.Background(), client, nil, qm),
+			(&Datasource{}).getBurndownData(context.Background(), client, nil, queryModel{Metric: "burndown", Sprint: sprint, BurndownUnit: burndownIssueCount}),
+		} {
+			if res.Status != want {
+				t.Errorf("sprint %q: expected status %d, got %d (%v)", sprint, want, res.Status, res.Error)
+			}
+		}
+	}
+}
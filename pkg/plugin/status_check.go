@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// maxStatusDistance is the largest edit distance at which a known status is
+// suggested for an unknown one.
+const maxStatusDistance = 2
+
+// queryStatusNames returns the status names of the start and end statuses and
+// segments of the query, without "!" prefixes and wildcards.
+func queryStatusNames(qm queryModel) []string {
+	raws := []string{qm.StartStatus, qm.EndStatus}
+	for _, s := range qm.Segments {
+		raws = append(raws, s.StartStatuses, s.EndStatuses)
+	}
+	var names []string
+	for _, raw := range raws {
+		for _, status := range parseList(raw) {
+			status = strings.TrimSpace(strings.TrimPrefix(status, "!"))
+			if status == "" || status == statusWildcard || status == allValues || containsString(names, status) {
+				continue
+			}
+			names = append(names, status)
+		}
+	}
+	return names
+}
+
+// editDistance is the Levenshtein distance of a and b in runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// closeStatuses returns the known statuses within maxStatusDistance of name,
+// compared case-insensitively, closest first.
+func closeStatuses(name string, known []string) []string {
+	distances := map[string]int{}
+	var matches []string
+	for _, k := range known {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(k)); d <= maxStatusDistance {
+			distances[k] = d
+			matches = append(matches, k)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return distances[matches[i]] < distances[matches[j]] })
+	return matches
+}
+
+// unknownStatusNotice returns a warning listing the names that aren't among the
+// known statuses, with close matches, or nil if all of them are known.
+func unknownStatusNotice(names []string, statuses []jira.NamedValue) *data.Notice {
+	var known []string
+	for _, s := range statuses {
+		if !containsString(known, s.Name) {
+			known = append(known, s.Name)
+		}
+	}
+
+	var unknown []string
+	for _, name := range names {
+		if containsString(known, name) {
+			continue
+		}
+		text := fmt.Sprintf("%q", name)
+		if matches := closeStatuses(name, known); len(matches) > 0 {
+			quoted := make([]string, len(matches))
+			for i, c := range matches {
+				quoted[i] = fmt.Sprintf("%q", c)
+			}
+			text += " (did you mean " + strings.Join(quoted, " or ") + "?)"
+		}
+		unknown = append(unknown, text)
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	return &data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     "Unknown statuses in the query, which never match: " + strings.Join(unknown, ", ") + ".",
+	}
+}
+
+// checkStatusNames warns about start and end statuses that don't exist in Jira,
+// e.g. typos that would silently produce empty frames. The statuses are loaded
+// like other metadata, from the snapshots if they are enabled and shared by
+// concurrent queries otherwise. The check never fails the query.
+func (d *Datasource) checkStatusNames(ctx context.Context, client *jira.Client, qm queryModel) *data.Notice {
+	names := queryStatusNames(qm)
+	if len(names) == 0 {
+		return nil
+	}
+	statuses, err := d.statuses(ctx, client)
+	if err != nil || len(statuses) == 0 {
+		return nil
+	}
+	return unknownStatusNotice(names, statuses)
+}
@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestUnknownStatusNotice(t *testing.T) {
+	statuses := []jira.NamedValue{{ID: "1", Name: "To Do"}, {ID: "3", Name: "In Progress"}, {ID: "4", Name: "Done"}, {ID: "5", Name: "Done"}}
+	qm := queryModel{
+		StartStatus: "{!To Do,In Progess}",
+		EndStatus:   "done, Closed",
+		Segments:    []segment{{Name: "Queue", StartStatuses: "*", EndStatuses: "In Progress"}},
+	}
+
+	names := queryStatusNames(qm)
+	if len(names) != 5 {
+		t.Fatalf("expected 5 distinct names without prefixes and wildcards, got %q", names)
+	}
+
+	notice := unknownStatusNotice(names, statuses)
+	if notice == nil {
+		t.Fatal("expected a notice")
+	}
+	for _, want := range []string{`"In Progess" (did you mean "In Progress"?)`, `"done" (did you mean "Done"?)`, `"Closed"`} {
+		if !strings.Contains(notice.Text, want) {
+			t.Errorf("expected the notice to contain %s, got %q", want, notice.Text)
+		}
+	}
+	if strings.Contains(notice.Text, "To Do") || strings.Contains(notice.Text, "Closed\" (") {
+		t.Errorf("expected only unknown names, without far-off suggestions, got %q", notice.Text)
+	}
+
+	if notice := unknownStatusNotice([]string{"Done", "In Progress"}, statuses); notice != nil {
+		t.Errorf("expected no notice for known statuses, got %q", notice.Text)
+	}
+}
+
+func TestCheckStatusNamesLive(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/rest/api/3/status" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[{"id":"3","name":"In Progress"},{"id":"4","name":"Done"}]`)
+	}))
+	defer server.Close()
+
+	// Without metadata snapshots, the statuses are fetched from Jira.
+	ds := &Datasource{}
+	client := jira.NewClient(server.URL, "user", "token", "")
+	notice := ds.checkStatusNames(context.Background(), client, queryModel{StartStatus: "In Progess", EndStatus: "Done"})
+	if notice == nil || !strings.Contains(notice.Text, `"In Progess" (did you mean "In Progress"?)`) {
+		t.Errorf("expected a notice about the typo, got %v", notice)
+	}
+	if requests != 1 {
+		t.Errorf("expected one status lookup, got %d", requests)
+	}
+
+	// Queries without statuses don't need them.
+	if notice := ds.checkStatusNames(context.Background(), client, queryModel{}); notice != nil || requests != 1 {
+		t.Errorf("expected no lookup without statuses, got %d requests", requests)
+	}
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// getStatusFlowData counts, per bucket, the transitions into (EnteredCount) and
+// out of (ExitedCount) the flow status, e.g. how many items enter and leave code
+// review per day. Buckets without transitions are zero. With several flow
+// statuses, one frame labelled with the status is emitted per status.
+func (d *Datasource) getStatusFlowData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	statuses := parseList(qm.FlowStatus)
+	if len(statuses) == 0 {
+		return backend.ErrDataResponse(backend.StatusBadRequest, "statusFlow needs a flowStatus")
+	}
+	if containsString(statuses, allValues) {
+		return backend.ErrDataResponse(backend.StatusBadRequest, "statusFlow needs the flow statuses listed, not "+allValues)
+	}
+
+	size, err := bucketSize(qm, timeRange)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	buckets := bucketStarts(timeRange, size, qm.loc())
+
+	entered := map[string][]int64{}
+	exited := map[string][]int64{}
+	for _, status := range statuses {
+		entered[status] = make([]int64, len(buckets))
+		exited[status] = make([]int64, len(buckets))
+	}
+
+	for _, issue := range issues {
+		for _, change := range statusChanges(issue) {
+			if change.from == change.to {
+				continue
+			}
+			i, ok := bucketIndex(timeRange, buckets, change.at)
+			if !ok {
+				continue
+			}
+			if counts, ok := entered[change.to]; ok {
+				counts[i]++
+			}
+			if counts, ok := exited[change.from]; ok {
+				counts[i]++
+			}
+		}
+	}
+
+	for _, status := range statuses {
+		name := "response"
+		var labels data.Labels
+		if len(statuses) > 1 {
+			name = status
+			labels = data.Labels{"status": status}
+		}
+
+		frame := data.NewFrame(name,
+			data.NewField("Time", nil, buckets),
+			data.NewField("EnteredCount", labels, entered[status]),
+			data.NewField("ExitedCount", labels, exited[status]),
+		)
+		// Two value fields make it a wide frame; multi frames have exactly one.
+		frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesWide})
+		setBucketInterval(frame, size)
+		response.Frames = append(response.Frames, frame)
+	}
+
+	return response
+}
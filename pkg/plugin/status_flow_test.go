@@ -0,0 +1,45 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestStatusFlow(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-01T10:00:00.000+0000", "In Progress", "Review"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "Review", "Done"},
+		),
+		newTestIssue("T-2", "Story",
+			[3]string{"2024-01-01T12:00:00.000+0000", "In Progress", "Review"},
+		),
+	}
+
+	res := ds.getStatusFlowData(issues, queryModel{FlowStatus: "Review", Interval: "1d"}, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if len(res.Frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(res.Frames))
+	}
+	entered, exited := res.Frames[0].Fields[1], res.Frames[0].Fields[2]
+	if entered.Len() != 31 {
+		t.Fatalf("expected a bucket for every day, got %d", entered.Len())
+	}
+	for i, want := range [][2]int64{{2, 0}, {0, 0}, {0, 1}} {
+		if got := [2]int64{entered.At(i).(int64), exited.At(i).(int64)}; got != want {
+			t.Errorf("bucket %d: expected entered/exited %v, got %v", i, want, got)
+		}
+	}
+
+	res = ds.getStatusFlowData(issues, queryModel{FlowStatus: "Review, Done", Interval: "1d"}, testTimeRange())
+	if len(res.Frames) != 2 || res.Frames[1].Fields[1].Labels["status"] != "Done" {
+		t.Fatalf("expected one labelled frame per status, got %d frames", len(res.Frames))
+	}
+	if got := res.Frames[1].Fields[1].At(2).(int64); got != 1 {
+		t.Errorf("expected 1 issue to enter Done on the 3rd, got %d", got)
+	}
+}
@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// statusMapping is the start and end statuses of one project, for projects
+// that name the same stages differently, e.g. "In Dev" instead of "In
+// Progress". An empty list falls back to the startStatus or endStatus of the
+// query.
+type statusMapping struct {
+	Start []string `json:"start"`
+	End   []string `json:"end"`
+}
+
+// validateStatusMappings applies the checks of startStatus and endStatus to the
+// statuses of every project.
+func validateStatusMappings(mappings map[string]statusMapping) error {
+	for project, mapping := range mappings {
+		name := "statusMappings." + project
+		for _, status := range append(append([]string{}, mapping.Start...), mapping.End...) {
+			if strings.ContainsAny(status, "\r\n") {
+				return fmt.Errorf("%s must not contain line breaks", name)
+			}
+		}
+		for part, statuses := range map[string][]string{"start": mapping.Start, "end": mapping.End} {
+			if err := validateStatusSet(name+"."+part, strings.Join(statuses, ",")); err != nil {
+				return err
+			}
+		}
+		if err := validateCycleStatuses(name, strings.Join(mapping.Start, ","), strings.Join(mapping.End, ",")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cycleStatuses are the start and end statuses a cycle of an issue is measured
+// between, as configured and as matched.
+type cycleStatuses struct {
+	start, end   string
+	starts, ends statusSet
+}
+
+// cycleStatusesOf returns the statuses of the mapping of the issue's project,
+// matched by name, or those of the query for unmapped projects and statuses.
+func (qm queryModel) cycleStatusesOf(issue jira.Issue, global cycleStatuses) cycleStatuses {
+	mapping, ok := qm.StatusMappings[projectKey(issue)]
+	if !ok {
+		return global
+	}
+	statuses := global
+	if len(mapping.Start) > 0 {
+		statuses.start = strings.Join(mapping.Start, ",")
+		statuses.starts = newStatusSet(mapping.Start)
+	}
+	if len(mapping.End) > 0 {
+		statuses.end = strings.Join(mapping.End, ",")
+		statuses.ends = newStatusSet(mapping.End)
+	}
+	return statuses
+}
@@ -0,0 +1,55 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestStatusMappings(t *testing.T) {
+	inProject := func(key, project, start string) jira.Issue {
+		issue := newTestIssue(key, "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", start},
+			[3]string{"2024-01-05T10:00:00.000+0000", start, "Done"},
+		)
+		issue.Fields["project"] = map[string]interface{}{"key": project}
+		return issue
+	}
+	issues := []jira.Issue{
+		inProject("PLAT-1", "PLAT", "In Dev"),
+		inProject("WEB-1", "WEB", "In Progress"),
+		// Unmapped projects don't know "In Dev".
+		inProject("API-1", "API", "In Dev"),
+	}
+
+	var qm queryModel
+	raw := `{"metric":"cycletime","startStatus":"In Progress","endStatus":"Done","quantile":85,
+		"statusMappings":{"PLAT":{"start":["In Dev","Dev Review"]}}}`
+	if err := json.Unmarshal([]byte(raw), &qm); err != nil {
+		t.Fatal(err)
+	}
+	if err := qm.validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := (&Datasource{}).getCycletimeData(issues, qm, testTimeRange()).Frames[0]
+	got := map[string]string{}
+	for i := 0; i < frame.Rows(); i++ {
+		got[frame.Fields[0].At(i).(string)] = frame.Fields[3].At(i).(string) + " -> " + frame.Fields[4].At(i).(string)
+	}
+	want := map[string]string{"PLAT-1": "In Dev,Dev Review -> Done", "WEB-1": "In Progress -> Done"}
+	if len(got) != len(want) || got["PLAT-1"] != want["PLAT-1"] || got["WEB-1"] != want["WEB-1"] {
+		t.Errorf("expected the statuses of each cycle %v, got %v", want, got)
+	}
+
+	for _, mappings := range []map[string]statusMapping{
+		{"PLAT": {Start: []string{"In Dev", "!In Dev"}}},
+		{"PLAT": {Start: []string{"*"}, End: []string{"*"}}},
+		{"PLAT": {End: []string{"Done\nDONE"}}},
+	} {
+		if err := (queryModel{StatusMappings: mappings}).validate(); err == nil {
+			t.Errorf("expected %v to be rejected", mappings)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// missingStatusNames reports whether a status change of the issues carries only
+// status ids, as some Jira Data Center versions send after a status was renamed.
+func missingStatusNames(issues []jira.Issue) bool {
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+		for _, history := range issue.Changelog.Histories {
+			for _, item := range history.Items {
+				if item.Field == "status" && ((item.FromString == "" && item.From != "") || (item.ToString == "" && item.To != "")) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// resolveStatusNames fills in the names of status changes that only carry ids,
+// looking them up once with statuses. Ids that aren't known, or all of them if
+// the lookup fails, are used as the name.
+func resolveStatusNames(statuses func() ([]jira.NamedValue, error), issues []jira.Issue) error {
+	if !missingStatusNames(issues) {
+		return nil
+	}
+
+	names := map[string]string{}
+	known, err := statuses()
+	for _, status := range known {
+		names[status.ID] = status.Name
+	}
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return id
+	}
+
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+		for _, history := range issue.Changelog.Histories {
+			for i := range history.Items {
+				item := &history.Items[i]
+				if item.Field != "status" {
+					continue
+				}
+				if item.FromString == "" && item.From != "" {
+					item.FromString = name(item.From)
+				}
+				if item.ToString == "" && item.To != "" {
+					item.ToString = name(item.To)
+				}
+			}
+		}
+	}
+	return err
+}
+
+// unnamedStatuses reports whether a status of the query never appears by name
+// in the status changes of the issues while they carry status ids, as with the
+// localized names of changelogs of Jira instances in other languages.
+func unnamedStatuses(names []string, issues []jira.Issue) bool {
+	seen := map[string]bool{}
+	ids := false
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+		for _, history := range issue.Changelog.Histories {
+			for _, item := range history.Items {
+				if item.Field != "status" {
+					continue
+				}
+				seen[item.FromString], seen[item.ToString] = true, true
+				ids = ids || item.From != "" || item.To != ""
+			}
+		}
+	}
+	if !ids {
+		return false
+	}
+	for _, name := range names {
+		if !seen[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// matchStatusIDs makes the query match its statuses by id with the statuses of
+// Jira if the changelog doesn't name them, see unnamedStatuses. The changelog
+// keeps its names. Queries scoped to a project already match by id.
+func (d *Datasource) matchStatusIDs(ctx context.Context, client *jira.Client, qm *queryModel, issues []jira.Issue) *data.Notice {
+	if qm.statusIDs != nil || !unnamedStatuses(queryStatusNames(*qm), issues) {
+		return nil
+	}
+	statuses, err := d.statuses(ctx, client)
+	if err != nil {
+		return &data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Statuses are matched by name, Jira's statuses couldn't be looked up: %v", err),
+		}
+	}
+	ids := map[string][]string{}
+	for _, status := range statuses {
+		ids[status.Name] = append(ids[status.Name], status.ID)
+	}
+	qm.statusIDs = ids
+	return nil
+}
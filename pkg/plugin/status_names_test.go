@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestResolveStatusNames(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/rest/api/3/status" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `[{"id":"1","name":"To Do"},{"id":"3","name":"In Progress"}]`)
+	}))
+	defer server.Close()
+
+	issue := newTestIssue("A-1", "Story", [3]string{"2024-01-02T10:00:00.000+0000", "", ""})
+	item := &issue.Changelog.Histories[0].Items[0]
+	item.From, item.To = "1", "3"
+	other := newTestIssue("A-2", "Story", [3]string{"2024-01-03T10:00:00.000+0000", "", ""})
+	other.Changelog.Histories[0].Items[0].From = "3"
+	other.Changelog.Histories[0].Items[0].To = "99"
+
+	if err := resolveStatusNames(statusesOf(jira.NewClient(server.URL, "user", "token", "")), []jira.Issue{issue, other}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("expected one status lookup, got %d", requests)
+	}
+	if item.FromString != "To Do" || item.ToString != "In Progress" {
+		t.Errorf("expected the names to be filled in, got %q -> %q", item.FromString, item.ToString)
+	}
+	if got := other.Changelog.Histories[0].Items[0]; got.FromString != "In Progress" || got.ToString != "99" {
+		t.Errorf("expected the unknown id as the name, got %q -> %q", got.FromString, got.ToString)
+	}
+
+	// Changes with names don't need the lookup.
+	named := newTestIssue("A-3", "Story", [3]string{"2024-01-02T10:00:00.000+0000", "To Do", "Done"})
+	if err := resolveStatusNames(statusesOf(jira.NewClient("http://127.0.0.1:0", "user", "token", "")), []jira.Issue{named}); err != nil {
+		t.Errorf("expected no lookup, got %v", err)
+	}
+}
+
+func TestMatchStatusIDs(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if got := r.Header.Get("Accept-Language"); got != "en" {
+			t.Errorf("expected the statuses to be asked for in English, got %q", got)
+		}
+		fmt.Fprint(w, `[{"id":"1","name":"To Do"},{"id":"3","name":"In Progress"},{"id":"4","name":"Done"}]`)
+	}))
+	defer server.Close()
+	client := (&Datasource{}).newClient(&models.PluginSettings{URL: server.URL, Secrets: &models.SecretPluginSettings{}}, backend.PluginContext{})
+
+	// Changelogs of a German instance carry the names in the language of the
+	// user who made the change.
+	issue := newTestIssue("A-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "Zu erledigen", "In Arbeit"},
+		[3]string{"2024-01-04T10:00:00.000+0000", "In Arbeit", "Fertig"},
+	)
+	histories := issue.Changelog.Histories
+	histories[0].Items[0].From, histories[0].Items[0].To = "1", "3"
+	histories[1].Items[0].From, histories[1].Items[0].To = "3", "4"
+	issues := []jira.Issue{issue}
+
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done"}
+	if notice := (&Datasource{}).matchStatusIDs(context.Background(), client, &qm, issues); notice != nil {
+		t.Fatalf("expected the statuses to be matched by id, got %s", notice.Text)
+	}
+	cycles := collectCycles(issues, qm, testTimeRange())
+	if len(cycles) != 1 || cycles[0].start.Day() != 2 || cycles[0].end.Day() != 4 {
+		t.Errorf("expected the cycle from the 2nd to the 4th, got %v", cycles)
+	}
+	var got []string
+	for _, change := range statusChanges(issue) {
+		got = append(got, change.from+" -> "+change.to)
+	}
+	if strings.Join(got, ", ") != "Zu erledigen -> In Arbeit, In Arbeit -> Fertig" {
+		t.Errorf("expected the changelog names to be kept, got %v", got)
+	}
+
+	// Statuses named in the changelog don't need the lookup.
+	named := newTestIssue("A-2", "Story", [3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"})
+	named.Changelog.Histories[0].Items[0].From, named.Changelog.Histories[0].Items[0].To = "1", "3"
+	qm = queryModel{StartStatus: "In Progress", EndStatus: "To Do"}
+	requests = 0
+	if notice := (&Datasource{}).matchStatusIDs(context.Background(), client, &qm, []jira.Issue{named}); notice != nil || requests != 0 || qm.statusIDs != nil {
+		t.Errorf("expected names to be matched without a lookup, got %d requests", requests)
+	}
+
+	// A failed lookup falls back to names with a warning.
+	qm = queryModel{StartStatus: "In Progress", EndStatus: "Done"}
+	if notice := (&Datasource{}).matchStatusIDs(context.Background(), jira.NewClient("http://127.0.0.1:0", "user", "token", ""), &qm, issues); notice == nil || qm.statusIDs != nil {
+		t.Error("expected a notice about the failed lookup")
+	}
+}
+
+// statusesOf returns the status lookup of resolveStatusNames for client.
+func statusesOf(client *jira.Client) func() ([]jira.NamedValue, error) {
+	return func() ([]jira.NamedValue, error) { return client.GetStatuses(context.Background()) }
+}
@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// projectClause matches the project clauses of a JQL query.
+var projectClause = regexp.MustCompile(`(?i)\bproject\s*(!=|=|~|not\s+in\b|in\b)\s*("[^"]*"|'[^']*'|[^\s()]+)`)
+
+// orOperator matches the OR operator of a JQL query.
+var orOperator = regexp.MustCompile(`(?i)\bOR\b`)
+
+// jqlProject returns the project of a JQL query limited to one project by a
+// single "project = X" clause, "" otherwise.
+func jqlProject(jql string) string {
+	clauses := projectClause.FindAllStringSubmatch(jql, -1)
+	if len(clauses) != 1 || clauses[0][1] != "=" || orOperator.MatchString(jql) {
+		return ""
+	}
+	return strings.Trim(clauses[0][2], `"'`)
+}
+
+// ambiguousStatusNames returns the status names that appear with different ids
+// in the status changes of the issues, e.g. of team-managed projects.
+func ambiguousStatusNames(issues []jira.Issue) []string {
+	ids := map[string]map[string]bool{}
+	add := func(name, id string) {
+		if name == "" || id == "" {
+			return
+		}
+		if ids[name] == nil {
+			ids[name] = map[string]bool{}
+		}
+		ids[name][id] = true
+	}
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+		for _, history := range issue.Changelog.Histories {
+			for _, item := range history.Items {
+				if item.Field == "status" {
+					add(item.FromString, item.From)
+					add(item.ToString, item.To)
+				}
+			}
+		}
+	}
+
+	var names []string
+	for name, set := range ids {
+		if len(set) > 1 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// projectStatusIDs returns the ids of the statuses of the project by name.
+func (d *Datasource) projectStatusIDs(ctx context.Context, client *jira.Client, project string) (map[string][]string, error) {
+	projects, err := d.projects(ctx, client, false)
+	if err != nil {
+		return nil, err
+	}
+	projectID := ""
+	for _, p := range projects {
+		if strings.EqualFold(p.Key, project) || strings.EqualFold(p.Name, project) || p.ID == project {
+			projectID = p.ID
+			break
+		}
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("project %s not found", project)
+	}
+
+	statuses, err := loadMetadata(ctx, d, metadataStatuses+":"+projectID, func(ctx context.Context) ([]jira.NamedValue, error) {
+		return client.GetProjectStatuses(ctx, projectID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string][]string{}
+	for _, status := range statuses {
+		ids[status.Name] = append(ids[status.Name], status.ID)
+	}
+	return ids, nil
+}
+
+// scopeStatuses makes the query match statuses by id within its project, given
+// as qm.Project or by the JQL. Without a project, statuses are matched by name,
+// and a notice warns if names stand for different statuses in the issues.
+func (d *Datasource) scopeStatuses(ctx context.Context, client *jira.Client, qm *queryModel, issues []jira.Issue) *data.Notice {
+	project := strings.TrimSpace(qm.Project)
+	if project == "" {
+		project = jqlProject(qm.JQLQuery)
+	}
+	if project == "" {
+		names := ambiguousStatusNames(issues)
+		if len(names) == 0 {
+			return nil
+		}
+		return &data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("The statuses %s are different statuses in different projects but are matched by name. Set a project to match them within it.", strings.Join(names, ", ")),
+		}
+	}
+
+	ids, err := d.projectStatusIDs(ctx, client, project)
+	if err != nil {
+		return &data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Statuses are matched by name, the statuses of project %s couldn't be looked up: %v", project, err),
+		}
+	}
+	qm.statusIDs = ids
+	return nil
+}
@@ -0,0 +1,73 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestJQLProject(t *testing.T) {
+	tests := map[string]string{
+		`project = PLAT AND type = Story`:  "PLAT",
+		`project = "Team Alpha"`:           "Team Alpha",
+		`project in (PLAT, WEB)`:           "",
+		`project = PLAT OR project = WEB`:  "",
+		`project = PLAT OR assignee = me`:  "",
+		`assignee = currentUser()`:         "",
+		`project != PLAT ORDER BY created`: "",
+	}
+	for jql, want := range tests {
+		if got := jqlProject(jql); got != want {
+			t.Errorf("%s: expected %q, got %q", jql, want, got)
+		}
+	}
+}
+
+func TestScopeStatuses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/api/3/project/search":
+			fmt.Fprint(w, `{"isLast":true,"values":[{"id":"10000","key":"TEAM","name":"Team"}]}`)
+		case "/rest/api/3/statuses/search":
+			if r.URL.Query().Get("projectId") != "10000" {
+				t.Errorf("expected the statuses of project 10000, got %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, `{"isLast":true,"values":[{"id":"20","name":"In Progress"},{"id":"21","name":"Done"}]}`)
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	// A company-managed "In Progress" (3) and the team-managed one (20).
+	issue := newTestIssue("TEAM-1", "Story",
+		[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+		[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "In Progress"},
+		[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+	)
+	for i, ids := range [][2]string{{"1", "3"}, {"3", "20"}, {"20", "21"}} {
+		item := &issue.Changelog.Histories[i].Items[0]
+		item.From, item.To = ids[0], ids[1]
+	}
+	issues := []jira.Issue{issue}
+
+	qm := queryModel{JQLQuery: "project = TEAM", StartStatus: "In Progress", EndStatus: "Done"}
+	if notice := (&Datasource{}).scopeStatuses(context.Background(), jira.NewClient(server.URL, "user", "token", ""), &qm, issues); notice != nil {
+		t.Fatalf("expected the statuses to be scoped, got %s", notice.Text)
+	}
+	cycles := collectCycles(issues, qm, testTimeRange())
+	if len(cycles) != 1 || cycles[0].start.Day() != 3 {
+		t.Errorf("expected the cycle to start with the team's In Progress on the 3rd, got %v", cycles)
+	}
+
+	// Without a project, names are matched and the ambiguity is reported.
+	unscoped := queryModel{JQLQuery: "assignee = currentUser()"}
+	notice := (&Datasource{}).scopeStatuses(context.Background(), jira.NewClient("http://127.0.0.1:0", "user", "token", ""), &unscoped, issues)
+	if notice == nil || unscoped.statusIDs != nil {
+		t.Error("expected a notice about the ambiguous status names")
+	}
+}
@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// formatStatusTimestamps flattens changelogRaw into one row per issue.
+const formatStatusTimestamps = "statusTimestamps"
+
+// statusTimes is when an issue first entered and last left a status.
+type statusTimes struct {
+	firstEntered *time.Time
+	lastLeft     *time.Time
+}
+
+// getStatusTimestampsData emits one row per issue with a "First:<Status>" and a
+// "Last:<Status>" column for every status seen in the result set: when the issue
+// first entered the status and when it last left it. Statuses an issue never
+// entered or never left are null. The columns are ordered by when the status was
+// first entered by any issue, which roughly follows the workflow.
+func (d *Datasource) getStatusTimestampsData(issues []jira.Issue, qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	type issueRow struct {
+		issue jira.Issue
+		times map[string]*statusTimes
+	}
+
+	var rows []issueRow
+	// firstSeen also counts the statuses in the order they were seen, so that
+	// the status an issue left sorts before the one it entered at the same time.
+	type seenAt struct {
+		at  time.Time
+		seq int
+	}
+	firstSeen := map[string]seenAt{}
+	see := func(status string, at time.Time) {
+		if seen, ok := firstSeen[status]; !ok || at.Before(seen.at) {
+			firstSeen[status] = seenAt{at: at, seq: len(firstSeen)}
+		}
+	}
+
+	for _, issue := range issues {
+		changes := statusChanges(issue)
+		if len(changes) == 0 {
+			continue
+		}
+
+		times := map[string]*statusTimes{}
+		get := func(status string) *statusTimes {
+			if times[status] == nil {
+				times[status] = &statusTimes{}
+			}
+			return times[status]
+		}
+		for _, change := range changes {
+			at := change.at
+			if to := get(change.to); to.firstEntered == nil {
+				to.firstEntered = &at
+			}
+			get(change.from).lastLeft = &at
+			see(change.from, at)
+			see(change.to, at)
+		}
+		rows = append(rows, issueRow{issue: issue, times: times})
+	}
+
+	statuses := make([]string, 0, len(firstSeen))
+	for status := range firstSeen {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		a, b := firstSeen[statuses[i]], firstSeen[statuses[j]]
+		if !a.at.Equal(b.at) {
+			return a.at.Before(b.at)
+		}
+		return a.seq < b.seq
+	})
+
+	frame := data.NewFrame("response",
+		data.NewField("IssueKey", nil, []string{}),
+		data.NewField("IssueType", nil, []*string{}),
+	)
+	for _, status := range statuses {
+		frame.Fields = append(frame.Fields,
+			data.NewField("First:"+status, nil, []*time.Time{}),
+			data.NewField("Last:"+status, nil, []*time.Time{}),
+		)
+	}
+
+	for _, r := range rows {
+		row := []interface{}{r.issue.Key, optionalString(issueTypeName(r.issue))}
+		for _, status := range statuses {
+			t := r.times[status]
+			if t == nil {
+				t = &statusTimes{}
+			}
+			row = append(row, t.firstEntered, t.lastLeft)
+		}
+		frame.AppendRow(row...)
+	}
+
+	limit := rowLimit(qm)
+	if err := sortFrame(frame, qm, "", "", "IssueKey"); err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+	addTruncationNotice(frame, truncateFrame(frame, limit), limit)
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestStatusTimestampsData(t *testing.T) {
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Review"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "Review", "In Progress"},
+			[3]string{"2024-01-05T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		newTestIssue("T-2", "Bug",
+			[3]string{"2024-01-06T10:00:00.000+0000", "To Do", "Blocked"},
+		),
+	}
+
+	res := (&Datasource{}).getStatusTimestampsData(issues, queryModel{})
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	frame := res.Frames[0]
+
+	var names []string
+	for _, field := range frame.Fields {
+		names = append(names, field.Name)
+	}
+	expected := []string{"IssueKey", "IssueType",
+		"First:To Do", "Last:To Do", "First:In Progress", "Last:In Progress",
+		"First:Review", "Last:Review", "First:Done", "Last:Done", "First:Blocked", "Last:Blocked"}
+	if len(names) != len(expected) {
+		t.Fatalf("expected columns %v, got %v", expected, names)
+	}
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Fatalf("expected columns %v, got %v", expected, names)
+		}
+	}
+
+	if frame.Rows() != 2 {
+		t.Fatalf("expected one row per issue, got %d", frame.Rows())
+	}
+
+	field := func(name string) int {
+		_, idx := frame.FieldByName(name)
+		return idx
+	}
+	if first, _ := frame.ConcreteAt(field("First:In Progress"), 0); first.(time.Time).Day() != 2 {
+		t.Errorf("expected T-1 to first enter In Progress on the 2nd, got %v", first)
+	}
+	if last, _ := frame.ConcreteAt(field("Last:In Progress"), 0); last.(time.Time).Day() != 5 {
+		t.Errorf("expected T-1 to last leave In Progress on the 5th, got %v", last)
+	}
+	if _, ok := frame.ConcreteAt(field("Last:Done"), 0); ok {
+		t.Errorf("expected Last:Done to be null for T-1, it never left Done")
+	}
+	if _, ok := frame.ConcreteAt(field("First:Review"), 1); ok {
+		t.Errorf("expected First:Review to be null for T-2, it never entered Review")
+	}
+}
@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+const (
+	// subtaskSearchBatch is the number of parent keys per `parent in (...)` search.
+	subtaskSearchBatch = 50
+	// maxSubtaskParents caps how many parents get their subtasks fetched.
+	maxSubtaskParents = 500
+)
+
+// subtaskProgress counts the subtask references of an issue and how many of them
+// are in the done status category.
+func subtaskProgress(issue jira.Issue) (count, done int64) {
+	subtasks, _ := issue.Fields["subtasks"].([]interface{})
+	for _, s := range subtasks {
+		subtask, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		count++
+		if fields, ok := subtask["fields"].(map[string]interface{}); ok && statusCategoryKey(fields["status"]) == "done" {
+			done++
+		}
+	}
+	return count, done
+}
+
+// subtaskPoints fetches the subtasks of the given issues with secondary
+// `parent in (...)` searches and sums their story points per parent key. Only the
+// first maxSubtaskParents parents with subtasks are looked up; the returned notice
+// says so when the cap was hit. Failed searches are returned as a
+// *jiraRequestError.
+func subtaskPoints(ctx context.Context, client *jira.Client, issues []jira.Issue, storyPointsField string) (map[string]float64, *data.Notice, error) {
+	if storyPointsField == "" {
+		return nil, nil, fmt.Errorf("summing subtask story points requires the story points field in the datasource settings")
+	}
+
+	var parents []string
+	for _, issue := range issues {
+		if count, _ := subtaskProgress(issue); count > 0 {
+			parents = append(parents, issue.Key)
+		}
+	}
+
+	var notice *data.Notice
+	if len(parents) > maxSubtaskParents {
+		notice = &data.Notice{
+			Severity: data.NoticeSeverityWarning,
+			Text:     fmt.Sprintf("Subtask story points were only fetched for the first %d of %d parent issues.", maxSubtaskParents, len(parents)),
+		}
+		parents = parents[:maxSubtaskParents]
+	}
+
+	points := map[string]float64{}
+	for start := 0; start < len(parents); start += subtaskSearchBatch {
+		end := min(start+subtaskSearchBatch, len(parents))
+		jql := fmt.Sprintf("parent in (%s)", jira.QuoteJQLList(parents[start:end]))
+
+		subtasks, _, err := client.SearchChangelogs(ctx, jql, jira.SearchOptions{
+			Fields:        []string{"parent", storyPointsField},
+			SkipChangelog: true,
+		})
+		if err != nil {
+			return nil, nil, &jiraRequestError{prefix: "jira subtask search failed", err: err}
+		}
+
+		for _, subtask := range subtasks {
+			parent, _ := subtask.Fields["parent"].(map[string]interface{})
+			parentKey, _ := parent["key"].(string)
+			if sp, ok := subtask.Fields[storyPointsField].(float64); ok && parentKey != "" {
+				points[parentKey] += sp
+			}
+		}
+	}
+
+	return points, notice, nil
+}
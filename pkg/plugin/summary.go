@@ -0,0 +1,46 @@
+package plugin
+
+import (
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// summaryFrame describes the distribution of values in a single row: Count,
+// Mean, Median, P85, P95, Min and Max, with percentiles computed by method.
+// Without values all but Count are null.
+func summaryFrame(values []float64, method string) *data.Frame {
+	frame := data.NewFrame("summary",
+		data.NewField("Count", nil, []int64{}),
+		data.NewField("Mean", nil, []*float64{}),
+		data.NewField("Median", nil, []*float64{}),
+		data.NewField("P85", nil, []*float64{}),
+		data.NewField("P95", nil, []*float64{}),
+		data.NewField("Min", nil, []*float64{}),
+		data.NewField("Max", nil, []*float64{}),
+	)
+	if len(values) == 0 {
+		frame.AppendRow(int64(0), nil, nil, nil, nil, nil, nil)
+		return frame
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	stat := func(v float64) *float64 { return &v }
+
+	frame.AppendRow(
+		int64(len(sorted)),
+		stat(sum/float64(len(sorted))),
+		stat(quantileSorted(sorted, 50, method)),
+		stat(quantileSorted(sorted, 85, method)),
+		stat(quantileSorted(sorted, 95, method)),
+		stat(sorted[0]),
+		stat(sorted[len(sorted)-1]),
+	)
+	return frame
+}
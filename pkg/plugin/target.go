@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// validateTarget rejects targetDays that no cycle can meet and hideQuantile
+// without a target to show instead.
+func validateTarget(qm queryModel) error {
+	if qm.TargetDays != nil && *qm.TargetDays <= 0 {
+		return fmt.Errorf("targetDays must be positive")
+	}
+	if qm.HideQuantile && qm.TargetDays == nil {
+		return fmt.Errorf("hideQuantile requires targetDays")
+	}
+	return nil
+}
+
+// withinTarget reports whether a cycle of days meets targetDays.
+func withinTarget(days, targetDays float64) bool {
+	return days <= targetDays
+}
+
+// targetFrame has one row with how many of the cycles of days meet targetDays,
+// for service level objectives like "85% of stories done within 10 days". days
+// are the cycles the quantile is computed over, i.e. without the ones excluded
+// by the outlier options. PercentWithinTarget is null without cycles.
+func targetFrame(days []float64, targetDays float64) *data.Frame {
+	frame := data.NewFrame("target",
+		data.NewField("TargetDays", nil, []float64{}),
+		data.NewField("Count", nil, []int64{}),
+		data.NewField("WithinTarget", nil, []int64{}),
+		data.NewField("PercentWithinTarget", nil, []*float64{}),
+	)
+	var within int64
+	for _, d := range days {
+		if withinTarget(d, targetDays) {
+			within++
+		}
+	}
+	var percent *float64
+	if len(days) > 0 {
+		p := float64(within) / float64(len(days)) * 100
+		percent = &p
+	}
+	frame.AppendRow(targetDays, int64(len(days)), within, percent)
+	return frame
+}
@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestCycletimeTarget(t *testing.T) {
+	cycle := func(key, end string) jira.Issue {
+		return newTestIssue(key, "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{end, "In Progress", "Done"},
+		)
+	}
+	issues := []jira.Issue{
+		cycle("T-1", "2024-01-04T10:00:00.000+0000"), // 3 days
+		cycle("T-2", "2024-01-11T10:00:00.000+0000"), // 10 days, on target
+		cycle("T-3", "2024-01-16T10:00:00.000+0000"), // 15 days
+		cycle("T-4", "2024-01-31T10:00:00.000+0000"), // 30 days, excluded
+	}
+	target := 10.0
+	qm := queryModel{
+		Metric: "cycletime", StartStatus: "In Progress", EndStatus: "Done", Quantile: 85,
+		MaxCycleDays: 20, ListExcluded: true, TargetDays: &target,
+	}
+
+	res := (&Datasource{}).buildFrames(context.Background(), nil, &models.PluginSettings{}, qm, testTimeRange(), issues)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if len(res.Frames) != 2 || res.Frames[1].Name != "target" {
+		t.Fatalf("expected the table and a target frame, got %d frames", len(res.Frames))
+	}
+	table := res.Frames[0]
+	within, _ := table.FieldByName("WithinTarget")
+	if within == nil {
+		t.Fatal("expected a WithinTarget column")
+	}
+	if _, idx := table.FieldByName("Quantile"); idx < 0 {
+		t.Error("expected the Quantile column to stay without hideQuantile")
+	}
+	for i := 0; i < table.Rows(); i++ {
+		key := table.Fields[0].At(i).(string)
+		if got, want := within.At(i).(bool), key == "T-1" || key == "T-2"; got != want {
+			t.Errorf("%s: expected WithinTarget %v, got %v", key, want, got)
+		}
+	}
+
+	// The excluded 30 day cycle doesn't count, like for the quantile.
+	summary := res.Frames[1]
+	count, _ := summary.FieldByName("Count")
+	percent, _ := summary.FieldByName("PercentWithinTarget")
+	if got := count.At(0).(int64); got != 3 {
+		t.Errorf("expected 3 cycles, got %d", got)
+	}
+	if got := *percent.At(0).(*float64); got < 66.6 || got > 66.7 {
+		t.Errorf("expected 2 of 3 cycles within target, got %v%%", got)
+	}
+
+	qm.HideQuantile = true
+	res = (&Datasource{}).buildFrames(context.Background(), nil, &models.PluginSettings{}, qm, testTimeRange(), issues)
+	if _, idx := res.Frames[0].FieldByName("Quantile"); idx >= 0 {
+		t.Error("expected hideQuantile to leave out the Quantile column")
+	}
+
+	if frame := targetFrame(nil, target); frame.Fields[3].At(0).(*float64) != nil {
+		t.Error("expected no percentage without cycles")
+	}
+	zero := 0.0
+	for _, qm := range []queryModel{{TargetDays: &zero}, {HideQuantile: true}} {
+		if err := qm.validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", qm)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package plugin
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+const defaultDescriptionLength = 1000
+
+var (
+	htmlBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>|</h[1-6]>`)
+	htmlTagRe   = regexp.MustCompile(`<[^>]*>`)
+	blankRunRe  = regexp.MustCompile(`\n{3,}`)
+)
+
+// truncateText cuts value to at most maxLength characters (runes, not bytes) and
+// appends an ellipsis when anything was cut. A maxLength of 0 disables truncation.
+func truncateText(value string, maxLength int) string {
+	if maxLength <= 0 {
+		return value
+	}
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[:maxLength]) + "…"
+}
+
+// text returns a free-text field such as the summary for a nullable column,
+// trimmed to the textLength of the query.
+func (qm queryModel) text(value string) *string {
+	return optionalString(truncateText(value, qm.textLength))
+}
+
+// htmlToText extracts the plain text of rendered Jira HTML, keeping line breaks
+// between paragraphs and list items.
+func htmlToText(value string) string {
+	value = htmlBreakRe.ReplaceAllString(value, "\n")
+	value = htmlTagRe.ReplaceAllString(value, "")
+	value = html.UnescapeString(value)
+	value = blankRunRe.ReplaceAllString(value, "\n\n")
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,70 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		value    string
+		max      int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"this is too long", 7, "this is…"},
+		{"größer als", 4, "größ…"},
+		{"unlimited", 0, "unlimited"},
+	}
+	for _, tt := range tests {
+		if got := truncateText(tt.value, tt.max); got != tt.expected {
+			t.Errorf("truncateText(%q, %d) = %q, expected %q", tt.value, tt.max, got, tt.expected)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	got := htmlToText(`<p>Steps &amp; notes</p><ul><li>one</li><li>two</li></ul>`)
+	if expected := "Steps & notes\none\ntwo"; got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestJQLDataTrimsText(t *testing.T) {
+	ds := &Datasource{}
+	issue := newTestIssue("A-1", "Story")
+	issue.Fields["summary"] = "Ünïcödé summary"
+	issue.RenderedFields = map[string]interface{}{"description": "a long description"}
+	issues := []jira.Issue{issue}
+
+	res := ds.getJQLData(issues, queryModel{textLength: 7, IncludeDescription: true}, nil)
+	frame := res.Frames[0]
+	if got := *frame.Fields[1].At(0).(*string); got != "Ünïcödé…" {
+		t.Errorf("expected the summary to be trimmed, got %q", got)
+	}
+	if got := *frame.Fields[7].At(0).(*string); got != "a long description" {
+		t.Errorf("expected the description to keep its own limit, got %q", got)
+	}
+
+	noTrim := 0
+	res = ds.getJQLData(issues, queryModel{MaxTextLength: &noTrim, IncludeDescription: true, DescriptionFormat: "text"}, nil)
+	if got := *res.Frames[0].Fields[1].At(0).(*string); got != "Ünïcödé summary" {
+		t.Errorf("expected no trimming, got %q", got)
+	}
+
+	four := 4
+	res = ds.getJQLData(issues, queryModel{MaxTextLength: &four, textLength: four, IncludeDescription: true}, nil)
+	if got := *res.Frames[0].Fields[7].At(0).(*string); got != "a lo…" {
+		t.Errorf("expected the query's maxTextLength to trim the description, got %q", got)
+	}
+
+	if got := (&models.PluginSettings{}).TextLength(); got != 200 {
+		t.Errorf("expected a default of 200 characters, got %d", got)
+	}
+	if got := (&models.PluginSettings{MaxTextLength: -1}).TextLength(); got != 0 {
+		t.Errorf("expected -1 to disable trimming, got %d", got)
+	}
+}
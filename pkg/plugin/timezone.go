@@ -0,0 +1,41 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// Embedded so that IANA zone names resolve on hosts without a zoneinfo database.
+	_ "time/tzdata"
+
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+)
+
+// queryLocation resolves the time zone of a query: the dashboard time zone sent
+// by the frontend, or the datasource default when the dashboard uses the browser
+// time zone (which the backend can't know) or doesn't send one.
+func queryLocation(qm queryModel, config *models.PluginSettings) (*time.Location, error) {
+	name := qm.Timezone
+	if name == "" || strings.EqualFold(name, "browser") {
+		name = ""
+		if config != nil {
+			name = config.DefaultTimezone
+		}
+	}
+	if name == "" || strings.EqualFold(name, "utc") {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone: %s", name)
+	}
+	return loc, nil
+}
+
+// loc returns the time zone of the query, UTC unless query() resolved another one.
+func (qm queryModel) loc() *time.Location {
+	if qm.location == nil {
+		return time.UTC
+	}
+	return qm.location
+}
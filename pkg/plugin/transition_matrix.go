@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+type transitionPair struct {
+	from string
+	to   string
+}
+
+// getTransitionMatrixData tallies every status change in the time range into
+// (FromStatus, ToStatus, Count) rows, which pairs well with a heatmap panel.
+// When qm.Normalize is set, a Percent column holds each count as a share of all
+// transitions leaving the same FromStatus.
+func (d *Datasource) getTransitionMatrixData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	issueTypes := parseList(qm.IssueTypeFilter)
+
+	counts := map[transitionPair]int64{}
+	totals := map[string]int64{}
+
+	for _, issue := range issues {
+		if issue.Changelog == nil {
+			continue
+		}
+		if len(issueTypes) > 0 && !listHas(issueTypes, issueTypeName(issue)) {
+			continue
+		}
+
+		for _, history := range issue.Changelog.Histories {
+			createdTime, err := parseJiraTime(history.Created)
+			if err != nil {
+				continue
+			}
+			if createdTime.Before(timeRange.From) || createdTime.After(timeRange.To) {
+				continue
+			}
+
+			for _, item := range history.Items {
+				if item.Field != "status" {
+					continue
+				}
+				counts[transitionPair{from: item.FromString, to: item.ToString}]++
+				totals[item.FromString]++
+			}
+		}
+	}
+
+	pairs := make([]transitionPair, 0, len(counts))
+	for pair := range counts {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].from != pairs[j].from {
+			return pairs[i].from < pairs[j].from
+		}
+		return pairs[i].to < pairs[j].to
+	})
+
+	frame := data.NewFrame("response",
+		data.NewField("FromStatus", nil, []string{}),
+		data.NewField("ToStatus", nil, []string{}),
+		data.NewField("Count", nil, []int64{}),
+	)
+	if qm.Normalize {
+		frame.Fields = append(frame.Fields, data.NewField("Percent", nil, []float64{}))
+	}
+
+	for _, pair := range pairs {
+		count := counts[pair]
+		if qm.Normalize {
+			frame.AppendRow(pair.from, pair.to, count, float64(count)*100/float64(totals[pair.from]))
+		} else {
+			frame.AppendRow(pair.from, pair.to, count)
+		}
+	}
+
+	response.Frames = append(response.Frames, frame)
+	return response
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestTransitionMatrix(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		newTestIssue("T-1", "Story",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		newTestIssue("T-2", "Bug",
+			[3]string{"2024-01-02T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "In Progress", "To Do"},
+		),
+		// Outside of the time range, must not be counted.
+		newTestIssue("T-3", "Story",
+			[3]string{"2023-12-02T10:00:00.000+0000", "To Do", "Done"},
+		),
+	}
+
+	res := ds.getTransitionMatrixData(issues, queryModel{Normalize: true}, testTimeRange())
+	frame := res.Frames[0]
+	if frame.Rows() != 3 {
+		t.Fatalf("expected 3 rows, got %d", frame.Rows())
+	}
+
+	// Rows are sorted by FromStatus, ToStatus.
+	if from, to := frame.Fields[0].At(0), frame.Fields[1].At(0); from != "In Progress" || to != "Done" {
+		t.Errorf("unexpected first pair %v -> %v", from, to)
+	}
+	if pct := frame.Fields[3].At(0).(float64); pct != 50 {
+		t.Errorf("expected 50%% for In Progress -> Done, got %v", pct)
+	}
+	if count := frame.Fields[2].At(2).(int64); count != 2 {
+		t.Errorf("expected 2 To Do -> In Progress transitions, got %d", count)
+	}
+
+	res = ds.getTransitionMatrixData(issues, queryModel{IssueTypeFilter: "Bug"}, testTimeRange())
+	if rows := res.Frames[0].Rows(); rows != 2 {
+		t.Errorf("expected 2 rows for Bug filter, got %d", rows)
+	}
+	if len(res.Frames[0].Fields) != 3 {
+		t.Errorf("expected no Percent column without normalize")
+	}
+}
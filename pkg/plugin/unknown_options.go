@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// maxOptionDistance is the largest edit distance at which a query option is
+// suggested for an unknown one.
+const maxOptionDistance = 2
+
+// grafanaQueryKeys are the keys Grafana adds to every query next to the options
+// of the query editor.
+var grafanaQueryKeys = []string{"refId", "datasource", "datasourceId", "hide", "key", "queryType", "intervalMs", "maxDataPoints", "resultAssertions", "timeRange"}
+
+// queryOptions returns the JSON names of the options of queryModel.
+var queryOptions = sync.OnceValue(func() []string {
+	var options []string
+	t := reflect.TypeOf(queryModel{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			options = append(options, name)
+		}
+	}
+	return options
+})
+
+// unknownOptions returns the keys of the query JSON that are neither an option of
+// the query model nor added by Grafana, sorted. Like the decoding of the query
+// model, keys match ignoring case.
+func unknownOptions(raw []byte) []string {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil
+	}
+	known := append(append([]string{}, queryOptions()...), grafanaQueryKeys...)
+	var unknown []string
+	for key := range keys {
+		if !containsFold(known, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// containsFold reports whether values contains value, ignoring case.
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// closestOption returns the query option closest to key within
+// maxOptionDistance, compared case-insensitively, "" if there is none.
+func closestOption(key string) string {
+	closest, distance := "", maxOptionDistance+1
+	for _, option := range queryOptions() {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(option)); d < distance {
+			closest, distance = option, d
+		}
+	}
+	return closest
+}
+
+// unknownOptionsNotice returns an info notice listing the unknown options of the
+// query with the option that was probably meant, e.g. for typos in hand-edited
+// panel JSON that would otherwise silently fall back to the default, or nil
+// without unknown options. Unknown options never fail the query.
+func unknownOptionsNotice(unknown []string) *data.Notice {
+	if len(unknown) == 0 {
+		return nil
+	}
+	texts := make([]string, len(unknown))
+	for i, key := range unknown {
+		texts[i] = fmt.Sprintf("query option '%s' is not recognized", key)
+		if option := closestOption(key); option != "" {
+			texts[i] += fmt.Sprintf(" — did you mean '%s'?", option)
+		}
+	}
+	return &data.Notice{Severity: data.NoticeSeverityInfo, Text: strings.Join(texts, "; ")}
+}
@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/achan/grafana-jira-datasource/pkg/models"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestUnknownOptions(t *testing.T) {
+	tests := []struct {
+		json string
+		want string
+	}{
+		// What Grafana adds and options in another case are fine.
+		{`{"refId":"A","datasource":{"type":"jira","uid":"x"},"hide":false,"intervalMs":60000,"maxDataPoints":500,"metric":"cycletime","StartStatus":"In Progress"}`, ""},
+		{`{"metric":"cycletime","qunatile":90}`, "query option 'qunatile' is not recognized — did you mean 'quantile'?"},
+		{`{"metric":"cycletime","startStaus":"In Progress","endStatuses":"Done"}`,
+			"query option 'endStatuses' is not recognized — did you mean 'endStatus'?; query option 'startStaus' is not recognized — did you mean 'startStatus'?"},
+		{`{"metric":"jql","maxrow":10}`, "query option 'maxrow' is not recognized — did you mean 'maxRows'?"},
+		{`{"metric":"jql","colour":"red"}`, "query option 'colour' is not recognized"},
+	}
+	for _, tt := range tests {
+		var got string
+		if notice := unknownOptionsNotice(unknownOptions([]byte(tt.json))); notice != nil {
+			if notice.Severity != data.NoticeSeverityInfo {
+				t.Errorf("%s: expected an info notice, got %v", tt.json, notice.Severity)
+			}
+			got = notice.Text
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.json, tt.want, got)
+		}
+	}
+}
+
+func TestQueryNoticesUnknownOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"issues":[{"key":"A-1","fields":{}}],"isLast":true}`)
+	}))
+	defer server.Close()
+
+	query := backend.DataQuery{
+		RefID:     "A",
+		JSON:      []byte(`{"refId":"A","metric":"jql","jqlQuery":"project = A","maxRos":1}`),
+		TimeRange: testTimeRange(),
+	}
+	config := &models.PluginSettings{Secrets: &models.SecretPluginSettings{}}
+	res := (&Datasource{}).query(context.Background(), jira.NewClient(server.URL, "user", "token", ""), config, query)
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	var notices []string
+	for _, notice := range res.Frames[0].Meta.Notices {
+		notices = append(notices, notice.Text)
+	}
+	if !strings.Contains(strings.Join(notices, "\n"), "query option 'maxRos' is not recognized — did you mean 'maxRows'?") {
+		t.Errorf("expected a notice about maxRos, got %v", notices)
+	}
+}
@@ -0,0 +1,53 @@
+package plugin
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// userResponse is the shape /users returns for both Cloud and Server users.
+type userResponse struct {
+	// ID is the accountId on Cloud and the username (or key) on Server.
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+}
+
+func toUserResponse(u jira.User) userResponse {
+	id := u.AccountID
+	if id == "" {
+		id = u.Name
+	}
+	if id == "" {
+		id = u.Key
+	}
+	return userResponse{ID: id, DisplayName: u.DisplayName}
+}
+
+// handleUsers lists the users assignable in ?project, optionally filtered by ?query.
+func (d *Datasource) handleUsers(w http.ResponseWriter, r *http.Request) {
+	project := r.URL.Query().Get("project")
+	if project == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("missing project parameter"))
+		return
+	}
+
+	client, _, err := d.clientFromRequest(r)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	users, err := client.SearchUsers(r.Context(), r.URL.Query().Get("query"), project)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	res := make([]userResponse, 0, len(users))
+	for _, u := range users {
+		res = append(res, toUserResponse(u))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
@@ -0,0 +1,119 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// allValues is the value of a Grafana multi-value variable with "All" selected
+// and no custom all value. List options containing it match everything.
+const allValues = "$__all"
+
+// listHas reports whether value is one of the values of a list option, which
+// match every value if they have allValues.
+func listHas(values []string, value string) bool {
+	return containsString(values, allValues) || containsString(values, value)
+}
+
+// parseList splits a list-typed query option such as startStatus into trimmed
+// values. Besides plain comma-separated values it accepts the multi-value
+// formats Grafana interpolates variables with:
+//
+//	csv          a,b
+//	glob, raw    {a,b}
+//	json         ["a","b"]
+//	pipe         a|b
+//	regex        (a|b), with special characters escaped
+//	lucene       ("a" OR "b")
+//	doublequote  "a","b"
+//	singlequote  'a','b', also sqlstring with '' for a quote
+func parseList(raw string) []string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+
+	var values []string
+	switch {
+	case strings.HasPrefix(raw, "[") && json.Unmarshal([]byte(raw), &values) == nil:
+		return trimValues(values)
+	case isWrapped(raw, "(", ")") && strings.Contains(raw, " OR "):
+		values = strings.Split(raw[1:len(raw)-1], " OR ")
+	case isWrapped(raw, "(", ")") && strings.Contains(raw, "|"):
+		for _, v := range splitUnescaped(raw[1:len(raw)-1], '|') {
+			values = append(values, unescapeRegex(v))
+		}
+	default:
+		raw = strings.TrimSpace(strings.Trim(raw, "{}"))
+		if raw == "" {
+			return nil
+		}
+		sep := ","
+		if !strings.Contains(raw, ",") && strings.Contains(raw, "|") {
+			sep = "|"
+		}
+		values = strings.Split(raw, sep)
+	}
+
+	for i, v := range values {
+		values[i] = unquote(strings.TrimSpace(v))
+	}
+	return values
+}
+
+// trimValues trims the values of a decoded json variable.
+func trimValues(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	for i, v := range values {
+		values[i] = strings.TrimSpace(v)
+	}
+	return values
+}
+
+// isWrapped reports whether s starts with prefix and ends with suffix.
+func isWrapped(s, prefix, suffix string) bool {
+	return len(s) >= len(prefix)+len(suffix) && strings.HasPrefix(s, prefix) && strings.HasSuffix(s, suffix)
+}
+
+// unquote strips the double or single quotes around a value, unescaping the
+// doubled single quotes of Grafana's sqlstring format.
+func unquote(v string) string {
+	switch {
+	case isWrapped(v, `"`, `"`):
+		return v[1 : len(v)-1]
+	case isWrapped(v, "'", "'"):
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+	}
+	return v
+}
+
+// splitUnescaped splits s at every sep not preceded by a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unescapeRegex removes the backslashes Grafana's regex format puts before
+// special characters.
+func unescapeRegex(v string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+		}
+		b.WriteByte(v[i])
+	}
+	return b.String()
+}
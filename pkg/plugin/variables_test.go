@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestParseList(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		raw    string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{"single", "In Progress", []string{"In Progress"}},
+		{"csv", "In Progress, Done", []string{"In Progress", "Done"}},
+		{"glob", "{In Progress,Done}", []string{"In Progress", "Done"}},
+		{"empty glob", "{}", nil},
+		{"json", `["In Progress","Done"]`, []string{"In Progress", "Done"}},
+		{"empty json", `[]`, nil},
+		{"pipe", "In Progress|Done", []string{"In Progress", "Done"}},
+		{"regex", `(In Progress|Done\.|A\|B)`, []string{"In Progress", "Done.", "A|B"}},
+		{"lucene", `("In Progress" OR "Done")`, []string{"In Progress", "Done"}},
+		{"doublequote", `"In Progress","Done"`, []string{"In Progress", "Done"}},
+		{"quoted glob", `{"In Progress","Done"}`, []string{"In Progress", "Done"}},
+		{"singlequote", `'In Progress','Done'`, []string{"In Progress", "Done"}},
+		{"sqlstring", `'Won''t Do','Done'`, []string{"Won't Do", "Done"}},
+		{"negated", "{!Backlog,In Progress}", []string{"!Backlog", "In Progress"}},
+		{"all", "$__all", []string{allValues}},
+		{"all glob", "{$__all}", []string{allValues}},
+	} {
+		if got := parseList(tt.raw); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s %q: expected %q, got %q", tt.format, tt.raw, tt.want, got)
+		}
+	}
+}
+
+func TestStatusSetAllValues(t *testing.T) {
+	qm := queryModel{statusIDs: map[string][]string{"Done": {"3"}}}
+	s := qm.statusSet("$__all")
+	if !s.matches(jira.Item{Field: "status", From: "1", FromString: "To Do", To: "2", ToString: "In Progress"}) {
+		t.Error("expected $__all to match any status")
+	}
+}
+
+func TestListHasAllValues(t *testing.T) {
+	if !listHas([]string{allValues}, "Bug") || listHas([]string{"Story"}, "Bug") {
+		t.Error("expected $__all, and only $__all, to match every value")
+	}
+	// Lists other than list options are compared exactly.
+	if containsString([]string{allValues}, "Bug") {
+		t.Error("expected containsString not to treat $__all as a wildcard")
+	}
+	if err := validateStatusSet("startStatus", "*,!Backlog"); err != nil {
+		t.Errorf("expected any transition out of Backlog to be valid, got %v", err)
+	}
+	if res := (&Datasource{}).getReviewLatencyData(nil, queryModel{ReviewStatus: "$__all"}, testTimeRange()); res.Error == nil {
+		t.Error("expected reviewLatency to reject $__all")
+	}
+	issues := []jira.Issue{newTestIssue("T-1", "Story", [3]string{"2024-01-02T10:00:00.000+0000", "To Do", "UAT"})}
+	if kept := filterByCurrentStatus(issues, []string{allValues}); len(kept) != 1 {
+		t.Errorf("expected $__all to keep every issue, got %d", len(kept))
+	}
+}
@@ -0,0 +1,87 @@
+package plugin
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/build/buildinfo"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// gitCommit and buildDate are injected at build time by the Magefile:
+//
+//	-ldflags "-X github.com/achan/grafana-jira-datasource/pkg/plugin.gitCommit=<sha> -X github.com/achan/grafana-jira-datasource/pkg/plugin.buildDate=<RFC 3339>"
+//
+// Without them, the VCS stamp of the Go toolchain is used if there is one.
+var (
+	gitCommit string
+	buildDate string
+)
+
+// versionResponse is the build of the running plugin and, if Jira could be
+// reached, the Jira instance it talks to.
+type versionResponse struct {
+	Version   string           `json:"version"`
+	Commit    string           `json:"commit"`
+	BuildDate string           `json:"buildDate"`
+	GoVersion string           `json:"goVersion"`
+	Jira      *jira.ServerInfo `json:"jira,omitempty"`
+	// JiraError says why the Jira deployment isn't known.
+	JiraError string `json:"jiraError,omitempty"`
+}
+
+// pluginVersion returns the version of the plugin build, falling back to the
+// version Grafana loaded and "dev".
+func pluginVersion(pluginContext backend.PluginContext) string {
+	version := pluginContext.PluginVersion
+	if info, err := buildinfo.GetBuildInfo(); err == nil && info.Version != "" {
+		version = info.Version
+	}
+	if version == "" {
+		version = "dev"
+	}
+	return version
+}
+
+// buildVersion returns the version, commit and build date of the running build.
+func buildVersion(pluginContext backend.PluginContext) versionResponse {
+	res := versionResponse{
+		Version:   pluginVersion(pluginContext),
+		Commit:    gitCommit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+	if res.BuildDate == "" {
+		if info, err := buildinfo.GetBuildInfo(); err == nil && info.Time > 0 {
+			res.BuildDate = time.UnixMilli(info.Time).UTC().Format(time.RFC3339)
+		}
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && res.Commit == "":
+				res.Commit = setting.Value
+			case setting.Key == "vcs.time" && res.BuildDate == "":
+				res.BuildDate = setting.Value
+			}
+		}
+	}
+	return res
+}
+
+// handleVersion reports the running plugin build for support requests, with the
+// Jira version and deployment type when the datasource can reach Jira.
+func (d *Datasource) handleVersion(w http.ResponseWriter, r *http.Request) {
+	res := buildVersion(backend.PluginConfigFromContext(r.Context()))
+	client, _, err := d.clientFromRequest(r)
+	if err == nil {
+		res.Jira, err = client.ServerInfo(r.Context())
+	}
+	if err != nil {
+		res.JiraError = err.Error()
+	}
+	writeJSON(w, http.StatusOK, res)
+}
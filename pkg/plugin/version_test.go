@@ -0,0 +1,61 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	gitCommit, buildDate = "0123abc", "2024-05-01T12:00:00Z"
+	defer func() { gitCommit, buildDate = "", "" }()
+
+	jiraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/api/3/serverInfo" {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"baseUrl":"https://example.atlassian.net","version":"1001.0.0","deploymentType":"Cloud","buildNumber":100250}`)
+	}))
+	defer jiraServer.Close()
+
+	rec := httptest.NewRecorder()
+	(&Datasource{}).newResourceMux().ServeHTTP(rec, newResourceRequest(t, jiraServer.URL, "/version"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if got := strings.Join(keys, " "); got != "buildDate commit goVersion jira version" {
+		t.Errorf("unexpected keys %s", got)
+	}
+	if body["commit"] != "0123abc" || body["buildDate"] != "2024-05-01T12:00:00Z" || body["version"] == "" {
+		t.Errorf("unexpected build %v", body)
+	}
+	if jira, _ := body["jira"].(map[string]interface{}); jira["deploymentType"] != "Cloud" || jira["version"] != "1001.0.0" {
+		t.Errorf("expected the Jira deployment, got %v", body["jira"])
+	}
+
+	// Without Jira, the build is still reported.
+	jiraServer.Close()
+	rec = httptest.NewRecorder()
+	(&Datasource{}).newResourceMux().ServeHTTP(rec, newResourceRequest(t, jiraServer.URL, "/version"))
+	var res versionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || res.Commit != "0123abc" || res.Jira != nil || res.JiraError == "" {
+		t.Errorf("expected the build and why Jira is unknown, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
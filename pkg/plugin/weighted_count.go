@@ -0,0 +1,95 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// defaultPriorityWeight is the weight of priorities missing from priorityWeights.
+const defaultPriorityWeight = 1.0
+
+// validatePriorityWeights rejects negative weights, which would let issues pay
+// off debt.
+func validatePriorityWeights(weights map[string]float64) error {
+	for priority, weight := range weights {
+		if weight < 0 {
+			return fmt.Errorf("priorityWeights: the weight of %s must not be negative", priority)
+		}
+	}
+	return nil
+}
+
+// priorityWeight returns the weight of priority in weights, compared ignoring
+// case, and whether it is configured.
+func priorityWeight(weights map[string]float64, priority string) (float64, bool) {
+	if weight, ok := weights[priority]; ok {
+		return weight, true
+	}
+	for name, weight := range weights {
+		if strings.EqualFold(name, priority) {
+			return weight, true
+		}
+	}
+	return defaultPriorityWeight, false
+}
+
+// getWeightedCountData sums the priorityWeights of the issues currently
+// matching the JQL into a single Score, e.g. bug debt with Blocker=8 and
+// Critical=5, next to the number of issues. Priorities without a weight, and
+// issues without a priority, weigh 1; they are listed in the custom meta under
+// "unknownPriorities" so that typos in the weights don't go unnoticed. With
+// priorityBreakdown, a "priorities" frame has the count, weight and score of
+// each priority, the most urgent first.
+func (d *Datasource) getWeightedCountData(issues []jira.Issue, qm queryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	counts := map[string]int64{}
+	ranks := map[string]int{}
+	for _, issue := range issues {
+		priority, rank := issuePriority(issue)
+		counts[priority]++
+		ranks[priority] = rank
+	}
+	priorities := make([]string, 0, len(counts))
+	for priority := range counts {
+		priorities = append(priorities, priority)
+	}
+	sortPriorities(priorities, ranks)
+
+	breakdown := data.NewFrame("priorities",
+		data.NewField("Priority", nil, []string{}),
+		data.NewField("Count", nil, []int64{}),
+		data.NewField("Weight", nil, []float64{}),
+		data.NewField("Score", nil, []float64{}),
+	)
+	var score float64
+	unknown := []string{}
+	for _, priority := range priorities {
+		weight, ok := priorityWeight(qm.PriorityWeights, priority)
+		if !ok {
+			unknown = append(unknown, priority)
+		}
+		score += weight * float64(counts[priority])
+		breakdown.AppendRow(priority, counts[priority], weight, weight*float64(counts[priority]))
+	}
+	sort.Strings(unknown)
+
+	frame := data.NewFrame("response",
+		data.NewField("Score", nil, []float64{score}),
+		data.NewField("Count", nil, []int64{int64(len(issues))}),
+	)
+	if len(unknown) > 0 {
+		setCustomMeta(frame, "unknownPriorities", unknown)
+	}
+
+	response.Frames = append(response.Frames, frame)
+	if qm.PriorityBreakdown {
+		response.Frames = append(response.Frames, breakdown)
+	}
+	return response
+}
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestWeightedCount(t *testing.T) {
+	var issues []jira.Issue
+	for i, priority := range []string{"Blocker", "Critical", "Critical", "Cosmetic", ""} {
+		issue := newTestIssue(fmt.Sprintf("B-%d", i+1), "Bug")
+		if priority != "" {
+			issue.Fields["priority"] = map[string]interface{}{"id": fmt.Sprint(i + 1), "name": priority}
+		}
+		issues = append(issues, issue)
+	}
+	qm := queryModel{
+		Metric:            "weightedCount",
+		PriorityWeights:   map[string]float64{"blocker": 8, "Critical": 5},
+		PriorityBreakdown: true,
+	}
+
+	res := (&Datasource{}).getWeightedCountData(issues, qm)
+	if len(res.Frames) != 2 {
+		t.Fatalf("expected a score and a breakdown frame, got %d", len(res.Frames))
+	}
+	// 8 + 2*5, plus 1 each for the unknown priority and the issue without one.
+	frame := res.Frames[0]
+	if score, count := frame.Fields[0].At(0).(float64), frame.Fields[1].At(0).(int64); score != 20 || count != 5 {
+		t.Errorf("expected a score of 20 from 5 issues, got %v from %d", score, count)
+	}
+	unknown, _ := frame.Meta.Custom.(map[string]interface{})["unknownPriorities"].([]string)
+	if strings.Join(unknown, ",") != "Cosmetic,"+noPriority {
+		t.Errorf("expected the unweighted priorities in the meta, got %v", unknown)
+	}
+
+	breakdown := res.Frames[1]
+	var rows []string
+	for i := 0; i < breakdown.Rows(); i++ {
+		rows = append(rows, fmt.Sprintf("%s=%v", breakdown.Fields[0].At(i), breakdown.Fields[3].At(i)))
+	}
+	if got := strings.Join(rows, " "); got != "Blocker=8 Critical=10 Cosmetic=1 None=1" {
+		t.Errorf("unexpected breakdown %s", got)
+	}
+
+	// The score covers what matches now, not only what changed in the time range.
+	qm.JQLQuery = "type = Bug AND resolution = Unresolved"
+	if jql, _ := finalJQL(qm, testTimeRange()); strings.Contains(jql, "updated") {
+		t.Errorf("expected no time range filter, got %s", jql)
+	}
+	if err := (queryModel{PriorityWeights: map[string]float64{"Minor": -1}}).validate(); err == nil {
+		t.Error("expected a negative weight to be rejected")
+	}
+}
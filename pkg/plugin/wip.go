@@ -0,0 +1,126 @@
+package plugin
+
+import (
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+// wipPeriod is a span of time during which an issue was work in progress.
+// A zero exit means the issue is still in progress.
+type wipPeriod struct {
+	enter time.Time
+	exit  time.Time
+}
+
+// wipPeriods replays the full changelog of the issue: entering a start status puts
+// it in progress, and reaching an end status, or moving back to the status it was
+// in before it was started (e.g. back to the backlog), takes it out again.
+func wipPeriods(issue jira.Issue, startStatuses, endStatuses []string) []wipPeriod {
+	var periods []wipPeriod
+	var current *wipPeriod
+	var preStart []string
+
+	for _, change := range statusChanges(issue) {
+		if current == nil {
+			if listHas(startStatuses, change.to) {
+				current = &wipPeriod{enter: change.at}
+				preStart = []string{change.from}
+			}
+			continue
+		}
+
+		if listHas(endStatuses, change.to) || containsString(preStart, change.to) {
+			current.exit = change.at
+			periods = append(periods, *current)
+			current = nil
+		}
+	}
+
+	if current != nil {
+		periods = append(periods, *current)
+	}
+	return periods
+}
+
+// inProgressAt reports whether any of the periods covers t.
+func inProgressAt(periods []wipPeriod, t time.Time) bool {
+	for _, p := range periods {
+		if !t.Before(p.enter) && (p.exit.IsZero() || t.Before(p.exit)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getWIPData returns, for the start of every bucket in the time range, how many
+// issues were between the start and end statuses at that moment. With
+// qm.SplitByIssueType a separate series labelled with the issue type is emitted
+// per issue type.
+func (d *Datasource) getWIPData(issues []jira.Issue, qm queryModel, timeRange backend.TimeRange) backend.DataResponse {
+	var response backend.DataResponse
+
+	size, err := bucketSize(qm, timeRange)
+	if err != nil {
+		return backend.ErrDataResponse(backend.StatusBadRequest, err.Error())
+	}
+
+	startStatuses := parseList(qm.StartStatus)
+	endStatuses := parseList(qm.EndStatus)
+	buckets := bucketStarts(timeRange, size, qm.loc())
+
+	counts := map[string][]int64{}
+	for _, issue := range issues {
+		periods := wipPeriods(issue, startStatuses, endStatuses)
+		if len(periods) == 0 {
+			continue
+		}
+
+		group := ""
+		if qm.SplitByIssueType {
+			group = issueTypeName(issue)
+		}
+		if _, ok := counts[group]; !ok {
+			counts[group] = make([]int64, len(buckets))
+		}
+		for i, t := range buckets {
+			if inProgressAt(periods, t) {
+				counts[group][i]++
+			}
+		}
+	}
+
+	// Without any issue in progress we still want a flat zero series, unlabelled
+	// even when split by issue type.
+	if len(counts) == 0 {
+		counts[""] = make([]int64, len(buckets))
+	}
+
+	groups := make([]string, 0, len(counts))
+	for group := range counts {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		name := "response"
+		var labels data.Labels
+		if qm.SplitByIssueType && group != "" {
+			name = group
+			labels = data.Labels{"issueType": group}
+		}
+
+		frame := data.NewFrame(name,
+			data.NewField("Time", nil, buckets),
+			data.NewField("WIP", labels, counts[group]),
+		)
+		frame.SetMeta(&data.FrameMeta{Type: data.FrameTypeTimeSeriesMulti})
+		setBucketInterval(frame, size)
+		response.Frames = append(response.Frames, frame)
+	}
+
+	return response
+}
@@ -0,0 +1,50 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/achan/grafana-jira-datasource/pkg/jira"
+)
+
+func TestWIP(t *testing.T) {
+	ds := &Datasource{}
+	issues := []jira.Issue{
+		// Started before the window, finished on the 3rd.
+		newTestIssue("T-1", "Story",
+			[3]string{"2023-12-20T10:00:00.000+0000", "To Do", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Done"},
+		),
+		// Started on the 2nd, moved back to the backlog on the 4th.
+		newTestIssue("T-2", "Bug",
+			[3]string{"2024-01-02T10:00:00.000+0000", "Backlog", "In Progress"},
+			[3]string{"2024-01-03T10:00:00.000+0000", "In Progress", "Review"},
+			[3]string{"2024-01-04T10:00:00.000+0000", "Review", "Backlog"},
+		),
+	}
+	qm := queryModel{StartStatus: "In Progress", EndStatus: "Done", Interval: "1d"}
+
+	res := ds.getWIPData(issues, qm, testTimeRange())
+	if res.Error != nil {
+		t.Fatal(res.Error)
+	}
+	if len(res.Frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(res.Frames))
+	}
+
+	wip := res.Frames[0].Fields[1]
+	expected := []int64{1, 1, 2, 1, 0}
+	for i, v := range expected {
+		if got := wip.At(i).(int64); got != v {
+			t.Errorf("bucket %d: expected %d, got %d", i, v, got)
+		}
+	}
+
+	qm.SplitByIssueType = true
+	res = ds.getWIPData(issues, qm, testTimeRange())
+	if len(res.Frames) != 2 {
+		t.Fatalf("expected one frame per issue type, got %d", len(res.Frames))
+	}
+	if res.Frames[0].Fields[1].Labels["issueType"] != "Bug" {
+		t.Errorf("expected labelled Bug series first, got %v", res.Frames[0].Fields[1].Labels)
+	}
+}
@@ -32,6 +32,14 @@ export function ConfigEditor(props: Props) {
     onOptionsChange({ ...options, jsonData });
   };
 
+  const onStoryPointsFieldChange = (event: ChangeEvent<HTMLInputElement>) => {
+    const jsonData = {
+      ...options.jsonData,
+      storyPointsField: event.target.value,
+    };
+    onOptionsChange({ ...options, jsonData });
+  };
+
   const onResetToken = () => {
     onOptionsChange({
       ...options,
@@ -56,7 +64,7 @@ export function ConfigEditor(props: Props) {
         <Input
           id="config-url"
           onChange={onUrlChange}
-          value={jsonData.url || ''}
+          value={jsonData.url || jsonData.path || ''}
           placeholder="url for your jira instance"
           width={40}
         />
@@ -81,6 +89,15 @@ export function ConfigEditor(props: Props) {
           onChange={onTokenChange}
         />
       </InlineField>
+      <InlineField label="Story Points" labelWidth={12} htmlFor="config-story-points" tooltip="The custom field id holding story points, for example customfield_10016">
+        <Input
+          id="config-story-points"
+          onChange={onStoryPointsFieldChange}
+          value={jsonData.storyPointsField || ''}
+          placeholder="customfield_10016"
+          width={40}
+        />
+      </InlineField>
     </div>
   );
 }
@@ -1,8 +1,15 @@
 import { QueryEditorHelpProps } from '@grafana/data';
-import {JiraQuery, METRICS} from "../../types";
-import React from "react";
+import {JiraQuery, METRICS, VersionResponse} from "../../types";
+import {DataSource} from "../../datasource";
+import React, {useEffect, useState} from "react";
 
 export default function QueryEditorHelp(props: QueryEditorHelpProps<JiraQuery>): any {
+    const [version, setVersion] = useState<VersionResponse>();
+    const datasource = props.datasource as unknown as DataSource;
+    useEffect(() => {
+        datasource?.getVersion().then(setVersion).catch(() => undefined);
+    }, [datasource]);
+
     const examples = [
         {
             title: 'cycle time scatterplot',
@@ -22,6 +29,12 @@ export default function QueryEditorHelp(props: QueryEditorHelpProps<JiraQuery>):
                     <div className="cheat-sheet-item__label">{item.label}</div>
                 </div>
             ))}
+            {version &&
+                <div className="cheat-sheet-item__label" title={`built ${version.buildDate || 'unknown'} with ${version.goVersion}`}>
+                    Plugin {version.version} ({version.commit ? version.commit.slice(0, 7) : 'unknown commit'})
+                    {version.jira && `, Jira ${version.jira.deploymentType} ${version.jira.version}`}
+                </div>
+            }
         </div>
     );
 };
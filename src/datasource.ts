@@ -1,34 +1,71 @@
 import {
+    DataQueryRequest,
     DataSourceInstanceSettings,
     ScopedVars,
 } from '@grafana/data';
 import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
 
-import { JiraQuery, MyDataSourceOptions, DEFAULT_QUERY, METRICS, QueryTypesResponse } from './types';
+import { JiraQuery, MyDataSourceOptions, DEFAULT_QUERY, METRICS, QueryTypesResponse, VersionResponse } from './types';
 
 export class DataSource extends DataSourceWithBackend<JiraQuery, MyDataSourceOptions> {
     constructor(instanceSettings: DataSourceInstanceSettings<MyDataSourceOptions>) {
         super(instanceSettings);
+        // Annotation queries use the regular query editor with the annotations metric.
+        this.annotations = {};
     }
 
     getDefaultQuery(_: any): Partial<JiraQuery> {
         return DEFAULT_QUERY;
     }
 
+    query(request: DataQueryRequest<JiraQuery>) {
+        // The backend buckets and formats times in the dashboard time zone.
+        return super.query({
+            ...request,
+            targets: request.targets.map((target) => ({...target, timezone: target.timezone ?? request.timezone})),
+        });
+    }
+
     applyTemplateVariables(query: JiraQuery, scopedVars: ScopedVars): JiraQuery {
         return {
             ...query,
             jqlQuery: getTemplateSrv().replace(query.jqlQuery, scopedVars),
             startStatus: getTemplateSrv().replace(query.startStatus, scopedVars),
             endStatus: getTemplateSrv().replace(query.endStatus, scopedVars),
+            flowStatus: query.flowStatus && getTemplateSrv().replace(query.flowStatus, scopedVars),
+            // A multi-value variable expands to one key per value.
+            issueKeys: query.issueKeys?.flatMap((key) => getTemplateSrv().replace(key, scopedVars, 'csv').split(',')),
         };
     }
 
+    // getVersion returns the running plugin build and the Jira deployment, for support requests.
+    getVersion(): Promise<VersionResponse> {
+        return this.getResource('version');
+    }
+
     getAvailableMetricTypes(): Promise<QueryTypesResponse> {
         const metrics = [
             {value: METRICS.CYCLE_TIME, label: 'cycle time'},
             {value: METRICS.CHANGELOG_RAW, label: 'change log - raw data'},
             {value: METRICS.JQL, label: 'JQL (Raw Issue Data)'},
+            {value: METRICS.TRANSITION_MATRIX, label: 'transition matrix'},
+            {value: METRICS.TIME_TO_FIRST_TRANSITION, label: 'time to first transition'},
+            {value: METRICS.HANDOVERS, label: 'assignee handovers'},
+            {value: METRICS.CYCLE_TIME_TREND, label: 'cycle time trend'},
+            {value: METRICS.WIP, label: 'WIP over time'},
+            {value: METRICS.AGING_WIP, label: 'aging WIP'},
+            {value: METRICS.STATUS_FLOW, label: 'status flow'},
+            {value: METRICS.DEFECT_RATIO, label: 'defect ratio'},
+            {value: METRICS.FIRST_TIME_RIGHT, label: 'first time right'},
+            {value: METRICS.MTTR, label: 'mean time to resolve'},
+            {value: METRICS.WEIGHTED_COUNT, label: 'priority-weighted count'},
+            {value: METRICS.SPRINT_CHURN, label: 'sprint churn'},
+            {value: METRICS.BURNDOWN, label: 'sprint burndown'},
+            {value: METRICS.REVIEW_LATENCY, label: 'review latency'},
+            {value: METRICS.BACKLOG_GROWTH, label: 'backlog growth'},
+            {value: METRICS.LINKS, label: 'issue links'},
+            {value: METRICS.PROJECTS, label: 'projects'},
+            {value: METRICS.ANNOTATIONS, label: 'status annotations'},
             {value: METRICS.NONE, label: 'None'},
         ]
 
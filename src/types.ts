@@ -7,6 +7,35 @@ export interface JiraQuery extends DataQuery {
   startStatus: string;
   endStatus: string;
   metric: string;
+  flowStatus?: string;
+  timezone?: string;
+  issueKeys?: string[];
+  currentStatusFilter?: string[];
+  dryRun?: boolean;
+  project?: string;
+  excludeSubtasks?: boolean;
+  endCondition?: string;
+  maxTextLength?: number;
+  includeUnresolved?: boolean;
+  includeURL?: boolean;
+  createdAfter?: string;
+  createdBefore?: string;
+  authorFilter?: string;
+  authorMatch?: string;
+  pageToken?: string;
+  pageSize?: number;
+  targetDays?: number;
+  hideQuantile?: boolean;
+  statusOrder?: string;
+  boardId?: number;
+  includeBugFields?: boolean;
+  includeStatusSince?: boolean;
+  compactColumns?: boolean;
+  splitByProject?: boolean;
+  changelogDepth?: 'truncated' | 'full';
+  priorityWeights?: Record<string, number>;
+  priorityBreakdown?: boolean;
+  statusMappings?: Record<string, {start?: string[]; end?: string[]}>;
 }
 
 export const METRICS = {
@@ -14,6 +43,25 @@ export const METRICS = {
   NONE : 'none',
   CHANGELOG_RAW: 'changelogRaw',
   JQL: 'jql',
+  TRANSITION_MATRIX: 'transitionMatrix',
+  TIME_TO_FIRST_TRANSITION: 'timeToFirstTransition',
+  HANDOVERS: 'handovers',
+  CYCLE_TIME_TREND: 'cycletimeTrend',
+  WIP: 'wip',
+  BACKLOG_GROWTH: 'backlogGrowth',
+  LINKS: 'links',
+  PROJECTS: 'projects',
+  ANNOTATIONS: 'annotations',
+  AGING_WIP: 'agingWip',
+  STATUS_FLOW: 'statusFlow',
+  DEFECT_RATIO: 'defectRatio',
+  FIRST_TIME_RIGHT: 'firstTimeRight',
+  WEIGHTED_COUNT: 'weightedCount',
+  MTTR: 'mttr',
+  SPRINT_CHURN: 'sprintChurn',
+  BURNDOWN: 'burndown',
+  REVIEW_LATENCY: 'reviewLatency',
+  COHORT: 'cohort',
 }
 
 export const DEFAULT_QUERY: Partial<JiraQuery> = {
@@ -26,7 +74,23 @@ export const DEFAULT_QUERY: Partial<JiraQuery> = {
  */
 export interface MyDataSourceOptions extends DataSourceJsonData {
   url?: string;
+  // The URL as early versions of the plugin stored it.
+  path?: string;
   username?: string;
+  storyPointsField?: string;
+  cacheTTLSeconds?: number;
+  defaultTimezone?: string;
+  holidays?: string[];
+  workingHours?: string;
+  defectTypes?: string[];
+  anonymizeUsers?: boolean;
+  maxTimeRangeDays?: number;
+  metadataSnapshots?: boolean;
+  splitSearchThreshold?: number;
+  maxSearchSplits?: number;
+  maxTextLength?: number;
+  language?: string;
+  changelogDepth?: 'truncated' | 'full';
 }
 
 /**
@@ -35,6 +99,7 @@ export interface MyDataSourceOptions extends DataSourceJsonData {
 export interface MySecureJsonData {
   token?: string;
   basicAuth?: string;
+  anonymizeSalt?: string;
 }
 
 export type QueryTypesResponse = {
@@ -42,6 +107,15 @@ export type QueryTypesResponse = {
 };
 
 
+export type VersionResponse = {
+  version: string;
+  commit: string;
+  buildDate: string;
+  goVersion: string;
+  jira?: {baseUrl: string; version: string; deploymentType: string; buildNumber: number};
+  jiraError?: string;
+};
+
 export type StatusTypesResponse = {
   statusTypes: Array<SelectableValue<string>>;
 };